@@ -0,0 +1,172 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/billyb2/tracking_server/auth"
+	"github.com/billyb2/tracking_server/db"
+	"github.com/billyb2/tracking_server/trackings"
+	"github.com/gin-gonic/gin"
+)
+
+// keyGroup returns the group the request's API key is restricted to, and false if it isn't restricted
+func keyGroup(c *gin.Context) (string, bool) {
+	key := auth.APIKeyFromGinContext(c)
+	if key == nil || key.GroupID == nil {
+		return "", false
+	}
+	if key.GroupName == nil {
+		return "", true
+	}
+
+	return *key.GroupName, true
+}
+
+// restrictToGroup puts what groupName names into the API key's group, if it's restricted to one. Leaving it empty picks
+// the key's group, and naming another group is an error.
+func restrictToGroup(c *gin.Context, groupName *string) error {
+	group, ok := keyGroup(c)
+	if !ok {
+		return nil
+	}
+	if *groupName != "" && *groupName != group {
+		return fmt.Errorf("%w: it can only use %q", auth.GroupRestricted, group)
+	}
+	*groupName = group
+
+	return nil
+}
+
+// checkKeyGroup makes numbers outside the API key's group look like they aren't tracked, if it's restricted to one
+func checkKeyGroup(c *gin.Context, trackingNumber string) error {
+	group, ok := keyGroup(c)
+	if !ok {
+		return nil
+	}
+
+	tracking, err := trackings.Get(db.FromGinContext(c), currentOrgID(c), trackingNumber)
+	if err != nil {
+		return err
+	}
+	if tracking.GroupName != group {
+		return trackings.NotFound
+	}
+
+	return nil
+}
+
+type apiKeyResp struct {
+	*auth.APIKey
+	// The key itself, which is only ever shown when it's created
+	Key string `json:"key"`
+}
+
+// CreateAPIKey godoc
+//
+//	@Summary		Creates an API key for a machine client
+//	@Description	Keys act in the organization they're created in, with no more than the user's role in it. Scopes are read, write:trackings and manage:webhooks. Keys restricted to a group only see and change the numbers in it, and can't use endpoints that work across groups. The key is only shown in this response.
+//	@ID				create-api-key
+//	@Accept			json
+//	@Produce		json
+//	@Param			Authorization		header		string			true	"Bearer token"
+//	@Param			X-Organization-ID	header		int				false	"Organization, defaulting to the user's first"
+//	@Param			newAPIKey			body		auth.NewAPIKey	true	"API key"
+//	@Success		201					{object}	apiKeyResp
+//	@Failure		400					{object}	errorEnvelope
+//	@Failure		401					{object}	errorEnvelope
+//	@Failure		403					{object}	errorEnvelope
+//	@Failure		500					{object}	errorEnvelope
+//	@Router			/v1/api_keys [post]
+func CreateAPIKey(c *gin.Context) {
+	userID := currentUserID(c)
+	orgID := currentOrgID(c)
+
+	newKey := auth.NewAPIKey{}
+	if err := c.ShouldBindJSON(&newKey); err != nil {
+		abortWithError(c, http.StatusBadRequest, codeInvalidRequest, fmt.Errorf("error parsing CreateAPIKey: %w", err))
+		return
+	}
+
+	key, secret, err := auth.CreateAPIKey(db.FromGinContext(c), userID, orgID, &newKey)
+	if err != nil {
+		switch {
+		case errors.Is(err, auth.InvalidNewAPIKey):
+			abortWithError(c, http.StatusBadRequest, codeInvalidRequest, err)
+		default:
+			abortWithError(c, http.StatusInternalServerError, codeInternal, err)
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, apiKeyResp{
+		APIKey: key,
+		Key:    secret,
+	})
+}
+
+type apiKeysResp struct {
+	APIKeys []auth.APIKey `json:"api_keys"`
+}
+
+// ListAPIKeys godoc
+//
+//	@Summary	Lists the user's API keys, without the keys themselves
+//	@ID			list-api-keys
+//	@Produce	json
+//	@Param		Authorization	header		string	true	"Bearer token"
+//	@Success	200				{object}	apiKeysResp
+//	@Failure	401				{object}	errorEnvelope
+//	@Failure	403				{object}	errorEnvelope
+//	@Failure	500				{object}	errorEnvelope
+//	@Router		/v1/api_keys [get]
+func ListAPIKeys(c *gin.Context) {
+	userID := currentUserID(c)
+
+	keys, err := auth.ListAPIKeys(db.FromGinContext(c), userID)
+	if err != nil {
+		abortWithError(c, http.StatusInternalServerError, codeInternal, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, apiKeysResp{
+		APIKeys: keys,
+	})
+}
+
+// RevokeAPIKey godoc
+//
+//	@Summary	Revokes an API key
+//	@ID			revoke-api-key
+//	@Param		Authorization	header	string	true	"Bearer token"
+//	@Param		id				path	int		true	"API key id"
+//	@Success	204
+//	@Failure	400	{object}	errorEnvelope
+//	@Failure	401	{object}	errorEnvelope
+//	@Failure	403	{object}	errorEnvelope
+//	@Failure	404	{object}	errorEnvelope
+//	@Failure	500	{object}	errorEnvelope
+//	@Router		/v1/api_keys/{id} [delete]
+func RevokeAPIKey(c *gin.Context) {
+	userID := currentUserID(c)
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		abortWithError(c, http.StatusBadRequest, codeInvalidRequest, fmt.Errorf("invalid api key id %q", c.Param("id")))
+		return
+	}
+
+	if err := auth.RevokeAPIKey(db.FromGinContext(c), userID, id); err != nil {
+		switch {
+		case errors.Is(err, auth.APIKeyNotFound):
+			abortWithError(c, http.StatusNotFound, codeNotFound, err)
+		default:
+			abortWithError(c, http.StatusInternalServerError, codeInternal, err)
+		}
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -1,45 +1,67 @@
 package api
 
 import (
-	"crypto/rand"
 	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/billyb2/tracking_server/auth"
 	"github.com/billyb2/tracking_server/db"
+	"github.com/billyb2/tracking_server/orgs"
 	"github.com/gin-gonic/gin"
-	"golang.org/x/crypto/argon2"
 )
 
 type registrationInfo struct {
 	Username string `json:"username"`
 	Password string `json:"password"`
-	Company  string `json:"company"`
+	// Names the organization the user gets, defaulting to their username
+	Company string `json:"company"`
+	// Joins the organization that emailed this invite token instead
+	InviteToken string `json:"invite_token,omitempty"`
+}
+
+// sessionTokens are what registering or logging in gives back
+type sessionTokens struct {
+	Token        string `json:"token,omitempty"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	// When the token expires, before which the refresh token should be swapped for new ones with /refresh
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+func newSessionTokens(tokens *auth.Tokens) sessionTokens {
+	return sessionTokens{
+		Token:        tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		ExpiresAt:    &tokens.ExpiresAt,
+	}
 }
 
 type registerResponse struct {
-	Token string `json:"token,omitempty"`
+	sessionTokens
 	Error string `json:"error,omitempty"`
 }
 
 // Register godoc
 //
-//	@Summary	Registers a new user
-//	@ID			register-user
-//	@Accept		json
-//	@Produce	json
-//	@Param		registrationInfo	body		registrationInfo	true	"Registration Info"
-//	@Success	201					{object}	registerResponse
-//	@Failure	400					{object}	registerResponse
-//	@Failure	500					{object}	registerResponse
-//	@Router		/register [post]
+//	@Summary		Registers a new user
+//	@Description	Users join the organization their invite_token is for, or get their own named after company.
+//	@ID				register-user
+//	@Accept			json
+//	@Produce		json
+//	@Param			registrationInfo	body		registrationInfo	true	"Registration Info"
+//	@Success		201					{object}	registerResponse
+//	@Failure		400					{object}	registerResponse
+//	@Failure		500					{object}	registerResponse
+//	@Router			/register [post]
 func Register(c *gin.Context) {
 	authInfo := registrationInfo{}
 	err := c.BindJSON(&authInfo)
 	if err != nil {
-		err = fmt.Errorf("error parsing RegistrationInfo: %w")
+		err = fmt.Errorf("error parsing RegistrationInfo: %w", err)
 		c.JSON(http.StatusBadRequest, registerResponse{
 			Error: err.Error(),
 		})
@@ -49,17 +71,17 @@ func Register(c *gin.Context) {
 	statusCode := http.StatusCreated
 	resp := registerResponse{}
 
-	token, err := registerUser(c, &authInfo)
+	tokens, err := registerUser(c, &authInfo)
 
 	switch {
-	case errors.Is(err, duplicateUserError):
+	case errors.Is(err, duplicateUserError), errors.Is(err, auth.InvalidPassword), errors.Is(err, orgs.InvalidName), errors.Is(err, orgs.InvalidInvitation):
 		statusCode = http.StatusBadRequest
 		resp.Error = err.Error()
 	case err != nil:
 		statusCode = http.StatusInternalServerError
 		resp.Error = err.Error()
 	default:
-		resp.Token = token
+		resp.sessionTokens = newSessionTokens(tokens)
 	}
 
 	c.JSON(statusCode, resp)
@@ -68,50 +90,64 @@ func Register(c *gin.Context) {
 
 var duplicateUserError error = fmt.Errorf("a user with that username already exists")
 
-func registerUser(c *gin.Context, authInfo *registrationInfo) (string, error) {
-	salt := make([]byte, 32)
-	_, err := rand.Read(salt)
+func registerUser(c *gin.Context, authInfo *registrationInfo) (*auth.Tokens, error) {
+	if err := auth.ValidatePassword(authInfo.Password); err != nil {
+		return nil, err
+	}
+	passwordHash, err := auth.HashPassword(authInfo.Password)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	passwordHash := argon2.IDKey([]byte(authInfo.Password), salt, 1, 47104, 1, 32)
-
 	db := db.FromGinContext(c)
 	if db == nil {
-		return "", fmt.Errorf("db is nil")
+		return nil, fmt.Errorf("db is nil")
 	}
 
 	tx, err := db.Begin()
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	defer tx.Commit()
+	defer tx.Rollback()
 
-	row := tx.QueryRow("insert into users (username, password_hash, salt, company) values (?, ?, ?, ?) returning id", authInfo.Username, passwordHash, salt, authInfo.Company)
+	row := tx.QueryRow("insert into users (username, password_hash) values (?, ?) returning id", authInfo.Username, passwordHash)
 	if row.Err() != nil {
-		return "", err
+		return nil, err
 	}
 	var userID int32
 	if err := row.Scan(&userID); err != nil {
 		switch {
 		case strings.Contains(err.Error(), "UNIQUE constraint failed: users.username"):
-			return "", duplicateUserError
+			return nil, duplicateUserError
 		default:
-			return "", err
+			return nil, err
 		}
 	}
 
-	token, err := auth.CreateToken(tx, userID)
+	if authInfo.InviteToken != "" {
+		if _, err := orgs.AcceptInvitation(tx, orgs.SignerFromGinContext(c), authInfo.InviteToken, userID); err != nil {
+			return nil, err
+		}
+	} else {
+		orgName := authInfo.Company
+		if strings.TrimSpace(orgName) == "" {
+			orgName = authInfo.Username
+		}
+		if _, err := orgs.Create(tx, orgName, userID); err != nil {
+			return nil, err
+		}
+	}
+
+	tokens, err := auth.CreateToken(tx, userID)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	if err := tx.Commit(); err != nil {
-		return "", err
+		return nil, err
 
 	}
 
-	return token, err
+	return tokens, err
 
 }
 
@@ -121,7 +157,7 @@ type loginInfo struct {
 }
 
 type loginResponse struct {
-	Token string `json:"token,omitempty"`
+	sessionTokens
 	Error string `json:"error,omitempty"`
 }
 
@@ -140,18 +176,18 @@ func Login(c *gin.Context) {
 	authInfo := loginInfo{}
 	err := c.BindJSON(&authInfo)
 	if err != nil {
-		err = fmt.Errorf("error parsing loginResponse: %w")
+		err = fmt.Errorf("error parsing loginResponse: %w", err)
 		c.JSON(http.StatusBadRequest, loginResponse{
 			Error: err.Error(),
 		})
 		return
 	}
 
-	token, err := auth.CheckUserCreds(c, authInfo.Username, authInfo.Password)
+	tokens, err := auth.CheckUserCreds(c, authInfo.Username, authInfo.Password)
 	switch {
 	case err == nil:
 		c.JSON(http.StatusOK, loginResponse{
-			Token: token,
+			sessionTokens: newSessionTokens(tokens),
 		})
 
 	case errors.Is(err, auth.BadUsernameOrPassword):
@@ -165,3 +201,340 @@ func Login(c *gin.Context) {
 		})
 	}
 }
+
+type refresh struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// Refresh godoc
+//
+//	@Summary		Swaps a refresh token for a new token and refresh token
+//	@Description	Each refresh token can only be used once. Using one again logs its session out, since it means someone else has a copy of it.
+//	@ID				refresh
+//	@Accept			json
+//	@Produce		json
+//	@Param			refresh	body		refresh	true	"Refresh token"
+//	@Success		200		{object}	loginResponse
+//	@Failure		400		{object}	loginResponse
+//	@Failure		401		{object}	loginResponse
+//	@Failure		500		{object}	loginResponse
+//	@Router			/refresh [post]
+func Refresh(c *gin.Context) {
+	refresh := refresh{}
+	if err := c.BindJSON(&refresh); err != nil {
+		err = fmt.Errorf("error parsing Refresh: %w", err)
+		c.JSON(http.StatusBadRequest, loginResponse{
+			Error: err.Error(),
+		})
+		return
+	}
+
+	tokens, err := auth.Refresh(db.FromGinContext(c), refresh.RefreshToken)
+	switch {
+	case err == nil:
+		c.JSON(http.StatusOK, loginResponse{
+			sessionTokens: newSessionTokens(tokens),
+		})
+
+	case errors.Is(err, auth.InvalidToken):
+		c.JSON(http.StatusUnauthorized, loginResponse{
+			Error: err.Error(),
+		})
+
+	default:
+		c.JSON(http.StatusInternalServerError, loginResponse{
+			Error: err.Error(),
+		})
+	}
+}
+
+type logout struct {
+	// Deprecated: send an Authorization: Bearer header instead
+	Token string `json:"token"`
+	// Log out every session rather than just this one
+	Everywhere bool `json:"everywhere"`
+}
+
+// Logout godoc
+//
+//	@Summary	Revokes the token and its refresh token, or every one of the user's tokens
+//	@ID			logout
+//	@Accept		json
+//	@Produce	json
+//	@Param		Authorization	header		string	false	"Bearer token"
+//	@Param		logout			body		logout	true	"Logout"
+//	@Success	200				{object}	errorResp
+//	@Failure	400				{object}	errorResp
+//	@Failure	401				{object}	errorResp
+//	@Failure	403				{object}	errorResp
+//	@Failure	500				{object}	errorResp
+//	@Router		/logout [post]
+func Logout(c *gin.Context) {
+	logout := logout{}
+	if err := c.BindJSON(&logout); err != nil {
+		err = fmt.Errorf("error parsing Logout: %w", err)
+		c.JSON(http.StatusBadRequest, errorResp{
+			Error: err.Error(),
+		})
+		return
+	}
+
+	userID, ok := authenticate(c, logout.Token)
+	if !ok {
+		return
+	}
+	token := logout.Token
+	if headerToken, ok := authorizationToken(c); ok {
+		token = headerToken
+	}
+
+	db := db.FromGinContext(c)
+	var err error
+	if logout.Everywhere {
+		err = auth.LogoutEverywhere(db, userID)
+	} else {
+		err = auth.Logout(db, token)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResp{
+			Error: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, errorResp{})
+}
+
+type errorResp struct {
+	Error string `json:"error,omitempty"`
+}
+
+// authenticate returns the user LegacyAuth authenticated the request as. Clients that don't send an Authorization header
+// yet can still send their token in the body, which is deprecated.
+func authenticate(c *gin.Context, token string) (int32, bool) {
+	if userID, ok := auth.UserIDFromGinContext(c); ok {
+		return userID, true
+	}
+
+	c.Header("Deprecation", "true")
+	return authenticateToken(c, token)
+}
+
+// authenticateQuery is authenticate for endpoints like the event stream, which browsers can only send the token to as
+// the token query parameter
+func authenticateQuery(c *gin.Context) (int32, bool) {
+	if userID, ok := auth.UserIDFromGinContext(c); ok {
+		return userID, true
+	}
+
+	return authenticateToken(c, c.Query("token"))
+}
+
+// authenticateToken looks up the user the token or API key belongs to, responding with an error and returning false if
+// that fails
+func authenticateToken(c *gin.Context, token string) (int32, bool) {
+	if err := resolveCredentials(c, token); err != nil {
+		err = fmt.Errorf("auth error: %w", err)
+		switch {
+		case errors.Is(err, auth.InvalidToken), errors.Is(err, auth.Forbidden):
+			c.JSON(http.StatusForbidden, errorResp{
+				Error: err.Error(),
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, errorResp{
+				Error: err.Error(),
+			})
+		}
+		return 0, false
+	}
+
+	return currentUserID(c), true
+}
+
+// resolveCredentials looks up the user a token or API key belongs to and the organization the request acts in, checking
+// that the user's role there and the API key allow the route, and puts them on the context
+func resolveCredentials(c *gin.Context, token string) error {
+	db := db.FromGinContext(c)
+
+	orgID, err := requestedOrgID(c)
+	if err != nil {
+		return err
+	}
+
+	var userID int32
+	var key *auth.APIKey
+	if !strings.HasPrefix(token, auth.APIKeyPrefix) {
+		userID, err = auth.UserIDFromToken(c, token)
+		if err != nil {
+			return err
+		}
+	} else {
+		key, err = auth.APIKeyFromToken(db, token)
+		if err != nil {
+			return err
+		}
+		// keys only act in the organization they were made in
+		if orgID != 0 && orgID != key.OrgID {
+			return fmt.Errorf("%w: the api key belongs to another organization", auth.Forbidden)
+		}
+		userID, orgID = key.UserID, key.OrgID
+	}
+
+	route, ok := routePermissions[c.Request.Method+" "+c.FullPath()]
+	if !ok {
+		return noRoutePermission
+	}
+	if key != nil {
+		if err := key.Allows(route.scope, route.groupScoped); err != nil {
+			return err
+		}
+	}
+
+	// users who aren't in any organization can still log out or join one
+	if route.role != "" {
+		membership, err := orgs.MembershipFor(db, userID, orgID)
+		if err != nil {
+			return err
+		}
+		if !orgs.RoleAtLeast(membership.Role, route.role) {
+			return fmt.Errorf("%w: it needs %s or above", orgs.InsufficientRole, route.role)
+		}
+		orgs.MembershipWithGinContext(c, membership)
+	}
+
+	auth.UserIDWithGinContext(c, userID)
+	if key != nil {
+		auth.APIKeyWithGinContext(c, key)
+	}
+	return nil
+}
+
+// requestedOrgID is the organization picked with the X-Organization-ID header, or 0 if there isn't one
+func requestedOrgID(c *gin.Context) (int64, error) {
+	header := c.GetHeader(orgs.Header)
+	if header == "" {
+		return 0, nil
+	}
+
+	orgID, err := strconv.ParseInt(header, 10, 64)
+	if err != nil || orgID <= 0 {
+		return 0, fmt.Errorf("%w: %s has to be an organization id, not %q", auth.Forbidden, orgs.Header, header)
+	}
+
+	return orgID, nil
+}
+
+// authorizationToken gets the token from an Authorization: Bearer header, returning false if there isn't one
+func authorizationToken(c *gin.Context) (string, bool) {
+	token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+	return strings.TrimSpace(token), ok
+}
+
+// Logger is gin's request logger, except that the token query parameter the event stream, WebSocket and email
+// verification links take is redacted, so that tokens don't end up in the logs
+func Logger() gin.HandlerFunc {
+	return gin.LoggerWithFormatter(func(param gin.LogFormatterParams) string {
+		var statusColor, methodColor, resetColor string
+		if param.IsOutputColor() {
+			statusColor = param.StatusCodeColor()
+			methodColor = param.MethodColor()
+			resetColor = param.ResetColor()
+		}
+
+		if param.Latency > time.Minute {
+			param.Latency = param.Latency.Truncate(time.Second)
+		}
+		return fmt.Sprintf("[GIN] %v |%s %3d %s| %13v | %15s |%s %-7s %s %#v\n%s",
+			param.TimeStamp.Format("2006/01/02 - 15:04:05"),
+			statusColor, param.StatusCode, resetColor,
+			param.Latency,
+			param.ClientIP,
+			methodColor, param.Method, resetColor,
+			redactToken(param.Path),
+			param.ErrorMessage,
+		)
+	})
+}
+
+// redactToken replaces the value of the token query parameter in path
+func redactToken(path string) string {
+	path, rawQuery, ok := strings.Cut(path, "?")
+	if !ok {
+		return path
+	}
+
+	query, err := url.ParseQuery(rawQuery)
+	if err != nil && strings.Contains(rawQuery, "token") {
+		return path + "?REDACTED"
+	}
+	if query.Has("token") {
+		query.Set("token", "REDACTED")
+		rawQuery = query.Encode()
+	}
+
+	return path + "?" + rawQuery
+}
+
+// LegacyAuth is middleware for the older endpoints, which authenticates requests with an Authorization: Bearer token or
+// API key and puts the user on the context. Requests without one are let through, so handlers can fall back to the
+// token in their body until clients have moved to the header.
+func LegacyAuth(c *gin.Context) {
+	token, ok := authorizationToken(c)
+	if !ok {
+		c.Next()
+		return
+	}
+
+	if err := resolveCredentials(c, token); err != nil {
+		err = fmt.Errorf("auth error: %w", err)
+		switch {
+		case errors.Is(err, auth.InvalidToken):
+			c.Header("WWW-Authenticate", "Bearer")
+			c.AbortWithStatusJSON(http.StatusUnauthorized, errorResp{
+				Error: err.Error(),
+			})
+		case errors.Is(err, auth.Forbidden):
+			c.AbortWithStatusJSON(http.StatusForbidden, errorResp{
+				Error: err.Error(),
+			})
+		default:
+			c.AbortWithStatusJSON(http.StatusInternalServerError, errorResp{
+				Error: err.Error(),
+			})
+		}
+		return
+	}
+
+	c.Next()
+}
+
+// RequireAuth is middleware for the /v1 endpoints, which rejects requests without a valid Authorization: Bearer token or
+// API key and puts the user on the context
+func RequireAuth(c *gin.Context) {
+	token, _ := authorizationToken(c)
+	if err := resolveCredentials(c, token); err != nil {
+		switch {
+		case errors.Is(err, auth.InvalidToken):
+			c.Header("WWW-Authenticate", "Bearer")
+			abortWithError(c, http.StatusUnauthorized, codeUnauthorized, err)
+		case errors.Is(err, auth.Forbidden):
+			abortWithError(c, http.StatusForbidden, codeForbidden, err)
+		default:
+			abortWithError(c, http.StatusInternalServerError, codeInternal, err)
+		}
+		return
+	}
+
+	c.Next()
+}
+
+// currentUserID is the user RequireAuth authenticated the request as
+func currentUserID(c *gin.Context) int32 {
+	userID, _ := auth.UserIDFromGinContext(c)
+	return userID
+}
+
+// currentOrgID is the organization the authenticated request acts in
+func currentOrgID(c *gin.Context) int64 {
+	return orgs.MembershipFromGinContext(c).OrgID
+}
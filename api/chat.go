@@ -0,0 +1,193 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/billyb2/tracking_server/db"
+	"github.com/billyb2/tracking_server/notify"
+	"github.com/gin-gonic/gin"
+)
+
+type createChatWebhook struct {
+	// Deprecated: send an Authorization: Bearer header instead
+	Token string `json:"token"`
+	// slack or teams
+	Kind string `json:"kind"`
+	URL  string `json:"url"`
+	// Only post about this group, empty means every group
+	GroupName string `json:"group_name,omitempty"`
+	// Canonical statuses to post about, empty means every status
+	Statuses []string `json:"statuses,omitempty"`
+}
+
+type chatWebhookResp struct {
+	ChatWebhook *notify.ChatWebhook `json:"chat_webhook,omitempty"`
+	Error       string              `json:"error,omitempty"`
+}
+
+// CreateChatWebhook godoc
+//
+//	@Summary	Registers a Slack or Microsoft Teams incoming webhook to post status changes to
+//	@ID			create-chat-webhook
+//	@Accept		json
+//	@Produce	json
+//	@Param		Authorization		header		string				false	"Bearer token"
+//	@Param		X-Organization-ID	header		int					false	"Organization, defaulting to the user's first"
+//	@Param		createChatWebhook	body		createChatWebhook	true	"Chat webhook"
+//	@Success	201					{object}	chatWebhookResp
+//	@Failure	400					{object}	chatWebhookResp
+//	@Failure	401					{object}	errorResp
+//	@Failure	403					{object}	errorResp
+//	@Failure	500					{object}	chatWebhookResp
+//	@Router		/create_chat_webhook [post]
+func CreateChatWebhook(c *gin.Context) {
+	createChatWebhook := createChatWebhook{}
+	if err := c.BindJSON(&createChatWebhook); err != nil {
+		err = fmt.Errorf("error parsing CreateChatWebhook: %w", err)
+		c.JSON(http.StatusBadRequest, chatWebhookResp{
+			Error: err.Error(),
+		})
+		return
+	}
+
+	userID, ok := authenticate(c, createChatWebhook.Token)
+	if !ok {
+		return
+	}
+
+	if err := checkStatuses(createChatWebhook.Statuses); err != nil {
+		c.JSON(http.StatusBadRequest, chatWebhookResp{
+			Error: err.Error(),
+		})
+		return
+	}
+
+	webhook := notify.ChatWebhook{
+		Kind:      createChatWebhook.Kind,
+		URL:       createChatWebhook.URL,
+		GroupName: createChatWebhook.GroupName,
+		Statuses:  createChatWebhook.Statuses,
+	}
+	err := notify.CreateChatWebhook(db.FromGinContext(c), userID, currentOrgID(c), &webhook)
+	switch {
+	case errors.Is(err, notify.InvalidChatWebhook):
+		c.JSON(http.StatusBadRequest, chatWebhookResp{
+			Error: err.Error(),
+		})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, chatWebhookResp{
+			Error: err.Error(),
+		})
+	default:
+		c.JSON(http.StatusCreated, chatWebhookResp{
+			ChatWebhook: &webhook,
+		})
+	}
+}
+
+type getChatWebhooks struct {
+	// Deprecated: send an Authorization: Bearer header instead
+	Token string `json:"token"`
+}
+
+type chatWebhooksResp struct {
+	ChatWebhooks []notify.ChatWebhook `json:"chat_webhooks"`
+	Error        string               `json:"error,omitempty"`
+}
+
+// GetChatWebhooks godoc
+//
+//	@Summary	Lists the user's Slack and Microsoft Teams webhooks
+//	@ID			get-chat-webhooks
+//	@Accept		json
+//	@Produce	json
+//	@Param		Authorization		header		string			false	"Bearer token"
+//	@Param		X-Organization-ID	header		int				false	"Organization, defaulting to the user's first"
+//	@Param		getChatWebhooks		body		getChatWebhooks	true	"Token"
+//	@Success	200					{object}	chatWebhooksResp
+//	@Failure	400					{object}	chatWebhooksResp
+//	@Failure	401					{object}	errorResp
+//	@Failure	403					{object}	errorResp
+//	@Failure	500					{object}	chatWebhooksResp
+//	@Router		/get_chat_webhooks [post]
+func GetChatWebhooks(c *gin.Context) {
+	getChatWebhooks := getChatWebhooks{}
+	if err := c.BindJSON(&getChatWebhooks); err != nil {
+		err = fmt.Errorf("error parsing GetChatWebhooks: %w", err)
+		c.JSON(http.StatusBadRequest, chatWebhooksResp{
+			Error: err.Error(),
+		})
+		return
+	}
+
+	userID, ok := authenticate(c, getChatWebhooks.Token)
+	if !ok {
+		return
+	}
+
+	webhooks, err := notify.ListChatWebhooks(db.FromGinContext(c), userID, currentOrgID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, chatWebhooksResp{
+			Error: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, chatWebhooksResp{
+		ChatWebhooks: webhooks,
+	})
+}
+
+type deleteChatWebhook struct {
+	// Deprecated: send an Authorization: Bearer header instead
+	Token         string `json:"token"`
+	ChatWebhookID int64  `json:"chat_webhook_id"`
+}
+
+// DeleteChatWebhook godoc
+//
+//	@Summary	Stops posting status changes to one of the user's Slack or Microsoft Teams webhooks
+//	@ID			delete-chat-webhook
+//	@Accept		json
+//	@Produce	json
+//	@Param		Authorization		header		string				false	"Bearer token"
+//	@Param		X-Organization-ID	header		int					false	"Organization, defaulting to the user's first"
+//	@Param		deleteChatWebhook	body		deleteChatWebhook	true	"Chat webhook"
+//	@Success	200					{object}	errorResp
+//	@Failure	400					{object}	errorResp
+//	@Failure	401					{object}	errorResp
+//	@Failure	403					{object}	errorResp
+//	@Failure	404					{object}	errorResp
+//	@Failure	500					{object}	errorResp
+//	@Router		/delete_chat_webhook [post]
+func DeleteChatWebhook(c *gin.Context) {
+	deleteChatWebhook := deleteChatWebhook{}
+	if err := c.BindJSON(&deleteChatWebhook); err != nil {
+		err = fmt.Errorf("error parsing DeleteChatWebhook: %w", err)
+		c.JSON(http.StatusBadRequest, errorResp{
+			Error: err.Error(),
+		})
+		return
+	}
+
+	userID, ok := authenticate(c, deleteChatWebhook.Token)
+	if !ok {
+		return
+	}
+
+	err := notify.DeleteChatWebhook(db.FromGinContext(c), userID, currentOrgID(c), deleteChatWebhook.ChatWebhookID)
+	switch {
+	case errors.Is(err, notify.ChatWebhookNotFound):
+		c.JSON(http.StatusNotFound, errorResp{
+			Error: err.Error(),
+		})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, errorResp{
+			Error: err.Error(),
+		})
+	default:
+		c.JSON(http.StatusOK, errorResp{})
+	}
+}
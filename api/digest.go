@@ -0,0 +1,240 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/billyb2/tracking_server/db"
+	"github.com/billyb2/tracking_server/digest"
+	"github.com/gin-gonic/gin"
+)
+
+type createDigestSubscription struct {
+	// Deprecated: send an Authorization: Bearer header instead
+	Token        string              `json:"token"`
+	Subscription digest.Subscription `json:"subscription"`
+}
+
+type digestSubscriptionResp struct {
+	Subscription *digest.Subscription `json:"subscription,omitempty"`
+	Error        string               `json:"error,omitempty"`
+}
+
+// CreateDigestSubscription godoc
+//
+//	@Summary	Subscribes the user to a daily or weekly digest of what was delivered, is in transit, is late, or hit exceptions in each group
+//	@ID			create-digest-subscription
+//	@Accept		json
+//	@Produce	json
+//	@Param		Authorization				header		string						false	"Bearer token"
+//	@Param		X-Organization-ID			header		int							false	"Organization, defaulting to the user's first"
+//	@Param		createDigestSubscription	body		createDigestSubscription	true	"Subscription"
+//	@Success	201							{object}	digestSubscriptionResp
+//	@Failure	400							{object}	digestSubscriptionResp
+//	@Failure	401							{object}	errorResp
+//	@Failure	403							{object}	errorResp
+//	@Failure	500							{object}	digestSubscriptionResp
+//	@Router		/create_digest_subscription [post]
+func CreateDigestSubscription(c *gin.Context) {
+	createSubscription := createDigestSubscription{}
+	if err := c.BindJSON(&createSubscription); err != nil {
+		err = fmt.Errorf("error parsing CreateDigestSubscription: %w", err)
+		c.JSON(http.StatusBadRequest, digestSubscriptionResp{
+			Error: err.Error(),
+		})
+		return
+	}
+
+	userID, ok := authenticate(c, createSubscription.Token)
+	if !ok {
+		return
+	}
+
+	err := digest.Subscribe(db.FromGinContext(c), userID, currentOrgID(c), &createSubscription.Subscription)
+	switch {
+	case errors.Is(err, digest.InvalidSubscription):
+		c.JSON(http.StatusBadRequest, digestSubscriptionResp{
+			Error: err.Error(),
+		})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, digestSubscriptionResp{
+			Error: err.Error(),
+		})
+	default:
+		c.JSON(http.StatusCreated, digestSubscriptionResp{
+			Subscription: &createSubscription.Subscription,
+		})
+	}
+}
+
+type getDigestSubscriptions struct {
+	// Deprecated: send an Authorization: Bearer header instead
+	Token string `json:"token"`
+}
+
+type digestSubscriptionsResp struct {
+	Subscriptions []digest.Subscription `json:"subscriptions"`
+	Error         string                `json:"error,omitempty"`
+}
+
+// GetDigestSubscriptions godoc
+//
+//	@Summary	Lists the user's digest subscriptions
+//	@ID			get-digest-subscriptions
+//	@Accept		json
+//	@Produce	json
+//	@Param		Authorization			header		string					false	"Bearer token"
+//	@Param		X-Organization-ID		header		int						false	"Organization, defaulting to the user's first"
+//	@Param		getDigestSubscriptions	body		getDigestSubscriptions	true	"Token"
+//	@Success	200						{object}	digestSubscriptionsResp
+//	@Failure	400						{object}	digestSubscriptionsResp
+//	@Failure	401						{object}	errorResp
+//	@Failure	403						{object}	errorResp
+//	@Failure	500						{object}	digestSubscriptionsResp
+//	@Router		/get_digest_subscriptions [post]
+func GetDigestSubscriptions(c *gin.Context) {
+	getSubscriptions := getDigestSubscriptions{}
+	if err := c.BindJSON(&getSubscriptions); err != nil {
+		err = fmt.Errorf("error parsing GetDigestSubscriptions: %w", err)
+		c.JSON(http.StatusBadRequest, digestSubscriptionsResp{
+			Error: err.Error(),
+		})
+		return
+	}
+
+	userID, ok := authenticate(c, getSubscriptions.Token)
+	if !ok {
+		return
+	}
+
+	subscriptions, err := digest.ListSubscriptions(db.FromGinContext(c), userID, currentOrgID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, digestSubscriptionsResp{
+			Error: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, digestSubscriptionsResp{
+		Subscriptions: subscriptions,
+	})
+}
+
+type deleteDigestSubscription struct {
+	// Deprecated: send an Authorization: Bearer header instead
+	Token          string `json:"token"`
+	SubscriptionID int64  `json:"subscription_id"`
+}
+
+// DeleteDigestSubscription godoc
+//
+//	@Summary	Unsubscribes the user from one of their digests
+//	@ID			delete-digest-subscription
+//	@Accept		json
+//	@Produce	json
+//	@Param		Authorization				header		string						false	"Bearer token"
+//	@Param		X-Organization-ID			header		int							false	"Organization, defaulting to the user's first"
+//	@Param		deleteDigestSubscription	body		deleteDigestSubscription	true	"Subscription"
+//	@Success	200							{object}	errorResp
+//	@Failure	400							{object}	errorResp
+//	@Failure	401							{object}	errorResp
+//	@Failure	403							{object}	errorResp
+//	@Failure	404							{object}	errorResp
+//	@Failure	500							{object}	errorResp
+//	@Router		/delete_digest_subscription [post]
+func DeleteDigestSubscription(c *gin.Context) {
+	deleteSubscription := deleteDigestSubscription{}
+	if err := c.BindJSON(&deleteSubscription); err != nil {
+		err = fmt.Errorf("error parsing DeleteDigestSubscription: %w", err)
+		c.JSON(http.StatusBadRequest, errorResp{
+			Error: err.Error(),
+		})
+		return
+	}
+
+	userID, ok := authenticate(c, deleteSubscription.Token)
+	if !ok {
+		return
+	}
+
+	err := digest.Unsubscribe(db.FromGinContext(c), userID, currentOrgID(c), deleteSubscription.SubscriptionID)
+	switch {
+	case errors.Is(err, digest.SubscriptionNotFound):
+		c.JSON(http.StatusNotFound, errorResp{
+			Error: err.Error(),
+		})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, errorResp{
+			Error: err.Error(),
+		})
+	default:
+		c.JSON(http.StatusOK, errorResp{})
+	}
+}
+
+type getDigest struct {
+	// Deprecated: send an Authorization: Bearer header instead
+	Token string `json:"token"`
+	// daily or weekly, defaults to daily
+	Frequency string `json:"frequency,omitempty"`
+}
+
+type digestResp struct {
+	Digest *digest.Digest `json:"digest,omitempty"`
+	Error  string         `json:"error,omitempty"`
+}
+
+// GetDigest godoc
+//
+//	@Summary	Builds the user's digest of the organization's packages for the last day or week, without sending it
+//	@ID			get-digest
+//	@Accept		json
+//	@Produce	json
+//	@Param		Authorization		header		string		false	"Bearer token"
+//	@Param		X-Organization-ID	header		int			false	"Organization, defaulting to the user's first"
+//	@Param		getDigest			body		getDigest	true	"Frequency"
+//	@Success	200					{object}	digestResp
+//	@Failure	400					{object}	digestResp
+//	@Failure	401					{object}	errorResp
+//	@Failure	403					{object}	errorResp
+//	@Failure	500					{object}	digestResp
+//	@Router		/get_digest [post]
+func GetDigest(c *gin.Context) {
+	getDigest := getDigest{}
+	if err := c.BindJSON(&getDigest); err != nil {
+		err = fmt.Errorf("error parsing GetDigest: %w", err)
+		c.JSON(http.StatusBadRequest, digestResp{
+			Error: err.Error(),
+		})
+		return
+	}
+
+	if _, ok := authenticate(c, getDigest.Token); !ok {
+		return
+	}
+
+	if getDigest.Frequency == "" {
+		getDigest.Frequency = digest.FrequencyDaily
+	}
+	if getDigest.Frequency != digest.FrequencyDaily && getDigest.Frequency != digest.FrequencyWeekly {
+		c.JSON(http.StatusBadRequest, digestResp{
+			Error: fmt.Sprintf("%s: frequency must be daily or weekly", digest.InvalidSubscription),
+		})
+		return
+	}
+
+	now := time.Now()
+	d, err := digest.Build(db.FromGinContext(c), currentOrgID(c), getDigest.Frequency, now.Add(-digest.Period(getDigest.Frequency)), now)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, digestResp{
+			Error: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, digestResp{
+		Digest: d,
+	})
+}
@@ -0,0 +1,333 @@
+package api
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"os"
+
+	"github.com/billyb2/tracking_server/db"
+	"github.com/billyb2/tracking_server/mailer"
+	"github.com/gin-gonic/gin"
+)
+
+type setEmail struct {
+	// Deprecated: send an Authorization: Bearer header instead
+	Token string `json:"token"`
+	Email string `json:"email"`
+}
+
+// SetEmail godoc
+//
+//	@Summary	Sets the user's email address and sends them a link to verify it. Notifications aren't emailed until it's verified
+//	@ID			set-email
+//	@Accept		json
+//	@Produce	json
+//	@Param		Authorization	header		string		false	"Bearer token"
+//	@Param		setEmail		body		setEmail	true	"Email"
+//	@Success	202				{object}	errorResp
+//	@Failure	400				{object}	errorResp
+//	@Failure	401				{object}	errorResp
+//	@Failure	403				{object}	errorResp
+//	@Failure	500				{object}	errorResp
+//	@Failure	503				{object}	errorResp
+//	@Router		/set_email [post]
+func SetEmail(c *gin.Context) {
+	setEmail := setEmail{}
+	if err := c.BindJSON(&setEmail); err != nil {
+		err = fmt.Errorf("error parsing SetEmail: %w", err)
+		c.JSON(http.StatusBadRequest, errorResp{
+			Error: err.Error(),
+		})
+		return
+	}
+
+	userID, ok := authenticate(c, setEmail.Token)
+	if !ok {
+		return
+	}
+
+	address, err := mail.ParseAddress(setEmail.Email)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errorResp{
+			Error: fmt.Sprintf("invalid email: %s", err),
+		})
+		return
+	}
+
+	m := mailer.FromGinContext(c)
+	if m == nil {
+		c.JSON(http.StatusServiceUnavailable, errorResp{
+			Error: mailer.NotConfigured.Error(),
+		})
+		return
+	}
+
+	verificationToken, err := startEmailVerification(db.FromGinContext(c), userID, address.Address)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResp{
+			Error: err.Error(),
+		})
+		return
+	}
+
+	link := verificationLink(verificationToken)
+	err = m.Send(&mailer.Message{
+		To:      []string{address.Address},
+		Subject: "Verify your email",
+		Text:    fmt.Sprintf("Open this link to start getting tracking notifications at this address:\n\n%s\n\nThe link expires in 24 hours.\n", link),
+		HTML:    fmt.Sprintf(`<p>Open <a href="%s">this link</a> to start getting tracking notifications at this address.</p><p>The link expires in 24 hours.</p>`, link),
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResp{
+			Error: fmt.Sprintf("error sending verification email: %s", err),
+		})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, errorResp{})
+}
+
+func startEmailVerification(db *sql.DB, userID int32, email string) (string, error) {
+	tokenBytes := make([]byte, 32)
+	if _, err := rand.Read(tokenBytes); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(tokenBytes)
+
+	tx, err := db.Begin()
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("update users set email = ?, email_verified_at = null where id = ?", email, userID); err != nil {
+		return "", err
+	}
+	// only the newest link works
+	if _, err := tx.Exec("delete from email_verifications where user_id = ?", userID); err != nil {
+		return "", err
+	}
+	if _, err := tx.Exec("insert into email_verifications (token, user_id, email, expires_at) values (?, ?, ?, datetime('now', '+1 day'))", token, userID, email); err != nil {
+		return "", err
+	}
+
+	return token, tx.Commit()
+}
+
+// verificationLink points at the server's public URL if PUBLIC_URL is set, otherwise it's relative
+func verificationLink(token string) string {
+	return os.Getenv("PUBLIC_URL") + "/api/verify_email?token=" + url.QueryEscape(token)
+}
+
+var invalidVerificationToken error = fmt.Errorf("invalid or expired verification link")
+
+// VerifyEmail godoc
+//
+//	@Summary	Verifies the user's email address using the link emailed to them
+//	@ID			verify-email
+//	@Produce	json
+//	@Param		token	query		string	true	"Verification token"
+//	@Success	200		{object}	errorResp
+//	@Failure	400		{object}	errorResp
+//	@Failure	500		{object}	errorResp
+//	@Router		/verify_email [get]
+func VerifyEmail(c *gin.Context) {
+	err := verifyEmail(db.FromGinContext(c), c.Query("token"))
+	switch {
+	case errors.Is(err, invalidVerificationToken):
+		c.JSON(http.StatusBadRequest, errorResp{
+			Error: err.Error(),
+		})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, errorResp{
+			Error: err.Error(),
+		})
+	default:
+		c.JSON(http.StatusOK, errorResp{})
+	}
+}
+
+func verifyEmail(db *sql.DB, token string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	row := tx.QueryRow("delete from email_verifications where token = ? and expires_at > datetime('now') returning user_id, email", token)
+	var userID int32
+	var email string
+	if err := row.Scan(&userID, &email); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return invalidVerificationToken
+		default:
+			return err
+		}
+	}
+
+	if _, err := tx.Exec("update users set email_verified_at = datetime('now') where id = ? and email = ?", userID, email); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+type setGroupEmailRecipients struct {
+	// Deprecated: send an Authorization: Bearer header instead
+	Token     string   `json:"token"`
+	GroupName string   `json:"group_name"`
+	Emails    []string `json:"emails"`
+}
+
+// SetGroupEmailRecipients godoc
+//
+//	@Summary	Replaces the list of extra people emailed about status changes in a group
+//	@ID			set-group-email-recipients
+//	@Accept		json
+//	@Produce	json
+//	@Param		Authorization			header		string					false	"Bearer token"
+//	@Param		X-Organization-ID		header		int						false	"Organization, defaulting to the user's first"
+//	@Param		setGroupEmailRecipients	body		setGroupEmailRecipients	true	"Recipients"
+//	@Success	200						{object}	errorResp
+//	@Failure	400						{object}	errorResp
+//	@Failure	401						{object}	errorResp
+//	@Failure	403						{object}	errorResp
+//	@Failure	500						{object}	errorResp
+//	@Router		/set_group_email_recipients [post]
+func SetGroupEmailRecipients(c *gin.Context) {
+	setRecipients := setGroupEmailRecipients{}
+	if err := c.BindJSON(&setRecipients); err != nil {
+		err = fmt.Errorf("error parsing SetGroupEmailRecipients: %w", err)
+		c.JSON(http.StatusBadRequest, errorResp{
+			Error: err.Error(),
+		})
+		return
+	}
+
+	userID, ok := authenticate(c, setRecipients.Token)
+	if !ok {
+		return
+	}
+
+	emails := []string{}
+	for _, email := range setRecipients.Emails {
+		address, err := mail.ParseAddress(email)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, errorResp{
+				Error: fmt.Sprintf("invalid email %q: %s", email, err),
+			})
+			return
+		}
+		emails = append(emails, address.Address)
+	}
+
+	if err := setGroupRecipients(db.FromGinContext(c), userID, currentOrgID(c), setRecipients.GroupName, emails); err != nil {
+		c.JSON(http.StatusInternalServerError, errorResp{
+			Error: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, errorResp{})
+}
+
+func setGroupRecipients(db *sql.DB, userID int32, orgID int64, groupName string, emails []string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("delete from group_email_recipients where org_id = ? and user_id = ? and group_name = ?", orgID, userID, groupName); err != nil {
+		return err
+	}
+
+	for _, email := range emails {
+		_, err := tx.Exec(
+			"insert into group_email_recipients (user_id, org_id, group_name, email) values (?, ?, ?, ?) on conflict do nothing",
+			userID, orgID, groupName, email,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+type getGroupEmailRecipients struct {
+	// Deprecated: send an Authorization: Bearer header instead
+	Token string `json:"token"`
+}
+
+type groupEmailRecipientsResp struct {
+	// Group name to the emails on its list
+	Recipients map[string][]string `json:"recipients"`
+	Error      string              `json:"error,omitempty"`
+}
+
+// GetGroupEmailRecipients godoc
+//
+//	@Summary	Gets the extra people emailed about status changes in each of the user's groups
+//	@ID			get-group-email-recipients
+//	@Accept		json
+//	@Produce	json
+//	@Param		Authorization			header		string					false	"Bearer token"
+//	@Param		X-Organization-ID		header		int						false	"Organization, defaulting to the user's first"
+//	@Param		getGroupEmailRecipients	body		getGroupEmailRecipients	true	"Token"
+//	@Success	200						{object}	groupEmailRecipientsResp
+//	@Failure	400						{object}	groupEmailRecipientsResp
+//	@Failure	401						{object}	errorResp
+//	@Failure	403						{object}	errorResp
+//	@Failure	500						{object}	groupEmailRecipientsResp
+//	@Router		/get_group_email_recipients [post]
+func GetGroupEmailRecipients(c *gin.Context) {
+	getRecipients := getGroupEmailRecipients{}
+	if err := c.BindJSON(&getRecipients); err != nil {
+		err = fmt.Errorf("error parsing GetGroupEmailRecipients: %w", err)
+		c.JSON(http.StatusBadRequest, groupEmailRecipientsResp{
+			Error: err.Error(),
+		})
+		return
+	}
+
+	userID, ok := authenticate(c, getRecipients.Token)
+	if !ok {
+		return
+	}
+
+	rows, err := db.FromGinContext(c).Query(
+		"select group_name, email from group_email_recipients where org_id = ? and user_id = ? order by group_name, email",
+		currentOrgID(c), userID,
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, groupEmailRecipientsResp{
+			Error: err.Error(),
+		})
+		return
+	}
+	defer rows.Close()
+
+	resp := groupEmailRecipientsResp{
+		Recipients: map[string][]string{},
+	}
+	for rows.Next() {
+		var groupName, email string
+		if err := rows.Scan(&groupName, &email); err != nil {
+			c.JSON(http.StatusInternalServerError, groupEmailRecipientsResp{
+				Error: err.Error(),
+			})
+			return
+		}
+		resp.Recipients[groupName] = append(resp.Recipients[groupName], email)
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,93 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/billyb2/tracking_server/db"
+	"github.com/billyb2/tracking_server/trackings"
+	"github.com/gin-gonic/gin"
+)
+
+var exportContentTypes = map[string]string{
+	trackings.ExportCSV:    "text/csv",
+	trackings.ExportNDJSON: "application/x-ndjson",
+	trackings.ExportXLSX:   "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+}
+
+type exportTrackings struct {
+	trackingFilters
+	// csv, ndjson or xlsx. Defaults to csv
+	Format string `form:"format"`
+}
+
+// ExportTrackings godoc
+//
+//	@Summary		Downloads the organization's tracking numbers as CSV, NDJSON or XLSX
+//	@Description	Each row has the number's status, ETA, when it was delivered, its carrier and how many status changes it's had. Archived numbers are included unless archived is false. The file is streamed as it's read, so an error partway through cuts the download short.
+//	@ID				export-trackings
+//	@Produce		text/csv
+//	@Produce		application/x-ndjson
+//	@Produce		application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+//	@Param			Authorization		header		string			true	"Bearer token"
+//	@Param			X-Organization-ID	header		int				false	"Organization, defaulting to the user's first"
+//	@Param			filters				query		exportTrackings	false	"Format and filters"
+//	@Success		200					{file}		file
+//	@Failure		400					{object}	errorEnvelope
+//	@Failure		401					{object}	errorEnvelope
+//	@Failure		403					{object}	errorEnvelope
+//	@Failure		500					{object}	errorEnvelope
+//	@Router			/v1/trackings/export [get]
+func ExportTrackings(c *gin.Context) {
+	orgID := currentOrgID(c)
+
+	exportTrackings := exportTrackings{}
+	if err := c.ShouldBindQuery(&exportTrackings); err != nil {
+		abortWithError(c, http.StatusBadRequest, codeInvalidRequest, fmt.Errorf("error parsing ExportTrackings: %w", err))
+		return
+	}
+	if exportTrackings.Format == "" {
+		exportTrackings.Format = trackings.ExportCSV
+	}
+
+	opts := exportTrackings.listOptions()
+	if err := restrictToGroup(c, &opts.GroupName); err != nil {
+		abortWithError(c, http.StatusForbidden, codeForbidden, err)
+		return
+	}
+
+	writer, err := trackings.NewExportWriter(exportTrackings.Format, c.Writer)
+	if err != nil {
+		abortWithTrackingError(c, err)
+		return
+	}
+
+	// the headers are only set once there's something to send, so errors before then can still be sent as json
+	setHeaders := func() {
+		if c.Writer.Written() || c.Writer.Header().Get("Content-Disposition") != "" {
+			return
+		}
+		c.Header("Content-Type", exportContentTypes[exportTrackings.Format])
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="trackings-%s.%s"`, time.Now().UTC().Format(time.DateOnly), exportTrackings.Format))
+	}
+
+	err = trackings.Export(db.FromGinContext(c), orgID, opts, func(row *trackings.ExportRow) error {
+		setHeaders()
+		return writer.Write(row)
+	})
+	if err == nil {
+		setHeaders()
+		err = writer.Close()
+	}
+	if err != nil {
+		if c.Writer.Written() {
+			fmt.Fprintln(os.Stderr, "error exporting tracking numbers", err)
+			c.Abort()
+			return
+		}
+		c.Writer.Header().Del("Content-Disposition")
+		abortWithTrackingError(c, err)
+	}
+}
@@ -0,0 +1,245 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/billyb2/tracking_server/db"
+	"github.com/billyb2/tracking_server/trackings"
+	"github.com/gin-gonic/gin"
+)
+
+// groupID parses the group id in the path, responding with an error and returning false if it isn't a number
+func groupID(c *gin.Context) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		abortWithError(c, http.StatusBadRequest, codeInvalidRequest, fmt.Errorf("invalid group id %q", c.Param("id")))
+		return 0, false
+	}
+
+	return id, true
+}
+
+type createGroup struct {
+	Name string `json:"name"`
+}
+
+// CreateGroup godoc
+//
+//	@Summary	Creates a group. Groups are also created when numbers are added to a group that doesn't exist yet
+//	@ID			create-group
+//	@Accept		json
+//	@Produce	json
+//	@Param		Authorization		header		string		true	"Bearer token"
+//	@Param		X-Organization-ID	header		int			false	"Organization, defaulting to the user's first"
+//	@Param		createGroup			body		createGroup	true	"Group"
+//	@Success	201					{object}	trackings.Group
+//	@Failure	400					{object}	errorEnvelope
+//	@Failure	401					{object}	errorEnvelope
+//	@Failure	403					{object}	errorEnvelope
+//	@Failure	409					{object}	errorEnvelope
+//	@Failure	500					{object}	errorEnvelope
+//	@Router		/v1/groups [post]
+func CreateGroup(c *gin.Context) {
+	orgID := currentOrgID(c)
+
+	createGroup := createGroup{}
+	if err := c.ShouldBindJSON(&createGroup); err != nil {
+		abortWithError(c, http.StatusBadRequest, codeInvalidRequest, fmt.Errorf("error parsing CreateGroup: %w", err))
+		return
+	}
+
+	group, err := trackings.CreateGroup(db.FromGinContext(c), orgID, createGroup.Name)
+	if err != nil {
+		abortWithTrackingError(c, err)
+		return
+	}
+
+	c.Header("Location", fmt.Sprintf("/api/v1/groups/%d", group.ID))
+	c.JSON(http.StatusCreated, group)
+}
+
+type groupsResp struct {
+	Groups []trackings.Group `json:"groups"`
+}
+
+// ListGroups godoc
+//
+//	@Summary	Lists the organization's groups with how many of their numbers are in each status
+//	@ID			list-groups
+//	@Produce	json
+//	@Param		Authorization		header		string	true	"Bearer token"
+//	@Param		X-Organization-ID	header		int		false	"Organization, defaulting to the user's first"
+//	@Param		include_archived	query		bool	false	"Include archived groups"
+//	@Success	200					{object}	groupsResp
+//	@Failure	400					{object}	errorEnvelope
+//	@Failure	401					{object}	errorEnvelope
+//	@Failure	403					{object}	errorEnvelope
+//	@Failure	500					{object}	errorEnvelope
+//	@Router		/v1/groups [get]
+func ListGroups(c *gin.Context) {
+	orgID := currentOrgID(c)
+
+	includeArchived := false
+	if include := c.Query("include_archived"); include != "" {
+		var err error
+		if includeArchived, err = strconv.ParseBool(include); err != nil {
+			abortWithError(c, http.StatusBadRequest, codeInvalidRequest, fmt.Errorf("invalid include_archived %q", include))
+			return
+		}
+	}
+
+	groups, err := trackings.ListGroups(db.FromGinContext(c), orgID, includeArchived)
+	if err != nil {
+		abortWithTrackingError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, groupsResp{
+		Groups: groups,
+	})
+}
+
+// GetGroup godoc
+//
+//	@Summary	Gets one of the organization's groups with how many of its numbers are in each status
+//	@ID			get-group
+//	@Produce	json
+//	@Param		Authorization		header		string	true	"Bearer token"
+//	@Param		X-Organization-ID	header		int		false	"Organization, defaulting to the user's first"
+//	@Param		id					path		int		true	"Group id"
+//	@Success	200					{object}	trackings.Group
+//	@Failure	400					{object}	errorEnvelope
+//	@Failure	401					{object}	errorEnvelope
+//	@Failure	403					{object}	errorEnvelope
+//	@Failure	404					{object}	errorEnvelope
+//	@Failure	500					{object}	errorEnvelope
+//	@Router		/v1/groups/{id} [get]
+func GetGroup(c *gin.Context) {
+	orgID := currentOrgID(c)
+	id, ok := groupID(c)
+	if !ok {
+		return
+	}
+
+	group, err := trackings.GetGroup(db.FromGinContext(c), orgID, id)
+	if err != nil {
+		abortWithTrackingError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, group)
+}
+
+type updateGroup struct {
+	// Renames the group everywhere it's used, like in notification rules and webhook filters
+	Name *string `json:"name,omitempty"`
+	// Archived groups are left out of the group list by default
+	Archived *bool `json:"archived,omitempty"`
+}
+
+// UpdateGroup godoc
+//
+//	@Summary	Renames, archives or unarchives a group
+//	@ID			update-group
+//	@Accept		json
+//	@Produce	json
+//	@Param		Authorization		header		string		true	"Bearer token"
+//	@Param		X-Organization-ID	header		int			false	"Organization, defaulting to the user's first"
+//	@Param		id					path		int			true	"Group id"
+//	@Param		updateGroup			body		updateGroup	true	"Fields to change"
+//	@Success	200					{object}	trackings.Group
+//	@Failure	400					{object}	errorEnvelope
+//	@Failure	401					{object}	errorEnvelope
+//	@Failure	403					{object}	errorEnvelope
+//	@Failure	404					{object}	errorEnvelope
+//	@Failure	409					{object}	errorEnvelope
+//	@Failure	500					{object}	errorEnvelope
+//	@Router		/v1/groups/{id} [patch]
+func UpdateGroup(c *gin.Context) {
+	orgID := currentOrgID(c)
+	id, ok := groupID(c)
+	if !ok {
+		return
+	}
+
+	updateGroup := updateGroup{}
+	if err := c.ShouldBindJSON(&updateGroup); err != nil {
+		abortWithError(c, http.StatusBadRequest, codeInvalidRequest, fmt.Errorf("error parsing UpdateGroup: %w", err))
+		return
+	}
+
+	db := db.FromGinContext(c)
+	if updateGroup.Name != nil {
+		if err := trackings.RenameGroup(db, orgID, id, *updateGroup.Name); err != nil {
+			abortWithTrackingError(c, err)
+			return
+		}
+	}
+	if updateGroup.Archived != nil {
+		if err := trackings.SetGroupArchived(db, orgID, id, *updateGroup.Archived); err != nil {
+			abortWithTrackingError(c, err)
+			return
+		}
+	}
+
+	group, err := trackings.GetGroup(db, orgID, id)
+	if err != nil {
+		abortWithTrackingError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, group)
+}
+
+type moveToGroup struct {
+	TrackingNumbers []string `json:"tracking_numbers,omitempty"`
+	// Also moves every number in this group, which merges it into the group being moved to
+	FromGroupID int64 `json:"from_group_id,omitempty"`
+}
+
+type moveToGroupResp struct {
+	Moved int64 `json:"moved"`
+}
+
+// MoveToGroup godoc
+//
+//	@Summary	Moves tracking numbers, or every number in another group, into a group
+//	@ID			move-to-group
+//	@Accept		json
+//	@Produce	json
+//	@Param		Authorization		header		string		true	"Bearer token"
+//	@Param		X-Organization-ID	header		int			false	"Organization, defaulting to the user's first"
+//	@Param		id					path		int			true	"Group id to move the numbers to"
+//	@Param		moveToGroup			body		moveToGroup	true	"Numbers to move"
+//	@Success	200					{object}	moveToGroupResp
+//	@Failure	400					{object}	errorEnvelope
+//	@Failure	401					{object}	errorEnvelope
+//	@Failure	403					{object}	errorEnvelope
+//	@Failure	404					{object}	errorEnvelope
+//	@Failure	500					{object}	errorEnvelope
+//	@Router		/v1/groups/{id}/trackings [post]
+func MoveToGroup(c *gin.Context) {
+	orgID := currentOrgID(c)
+	id, ok := groupID(c)
+	if !ok {
+		return
+	}
+
+	moveToGroup := moveToGroup{}
+	if err := c.ShouldBindJSON(&moveToGroup); err != nil {
+		abortWithError(c, http.StatusBadRequest, codeInvalidRequest, fmt.Errorf("error parsing MoveToGroup: %w", err))
+		return
+	}
+
+	moved, err := trackings.MoveToGroup(db.FromGinContext(c), orgID, id, moveToGroup.TrackingNumbers, moveToGroup.FromGroupID)
+	if err != nil {
+		abortWithTrackingError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, moveToGroupResp{
+		Moved: moved,
+	})
+}
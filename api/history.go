@@ -0,0 +1,83 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/billyb2/tracking_server/db"
+	"github.com/billyb2/tracking_server/history"
+	"github.com/gin-gonic/gin"
+)
+
+const defaultChangesLimit = 100
+const maxChangesLimit = 1000
+
+type getTrackingChanges struct {
+	// Deprecated: send an Authorization: Bearer header instead
+	Token string `json:"token"`
+	// Cursor returned by the previous call, or 0 to get every change
+	Cursor int64 `json:"cursor"`
+	Limit  int   `json:"limit,omitempty"`
+}
+
+type trackingChangesResp struct {
+	Changes []history.Change `json:"changes"`
+	// Pass this as the cursor on the next call to only get newer changes
+	Cursor int64  `json:"cursor"`
+	Error  string `json:"error,omitempty"`
+}
+
+// GetTrackingChanges godoc
+//
+//	@Summary	Gets every status change of the organization's tracking numbers since the given cursor
+//	@ID			get-tracking-changes
+//	@Accept		json
+//	@Produce	json
+//	@Param		Authorization		header		string				false	"Bearer token"
+//	@Param		X-Organization-ID	header		int					false	"Organization, defaulting to the user's first"
+//	@Param		getTrackingChanges	body		getTrackingChanges	true	"Cursor"
+//	@Success	200					{object}	trackingChangesResp
+//	@Failure	400					{object}	trackingChangesResp
+//	@Failure	401					{object}	errorResp
+//	@Failure	403					{object}	errorResp
+//	@Failure	500					{object}	trackingChangesResp
+//	@Router		/get_tracking_changes [post]
+func GetTrackingChanges(c *gin.Context) {
+	getTrackingChanges := getTrackingChanges{}
+	if err := c.BindJSON(&getTrackingChanges); err != nil {
+		err = fmt.Errorf("error parsing GetTrackingChanges: %w", err)
+		c.JSON(http.StatusBadRequest, trackingChangesResp{
+			Error: err.Error(),
+		})
+		return
+	}
+
+	userID, ok := authenticate(c, getTrackingChanges.Token)
+	if !ok {
+		return
+	}
+
+	limit := getTrackingChanges.Limit
+	if limit <= 0 {
+		limit = defaultChangesLimit
+	}
+	limit = min(limit, maxChangesLimit)
+
+	changes, err := history.Since(db.FromGinContext(c), userID, currentOrgID(c), getTrackingChanges.Cursor, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, trackingChangesResp{
+			Error: err.Error(),
+		})
+		return
+	}
+
+	cursor := getTrackingChanges.Cursor
+	if len(changes) > 0 {
+		cursor = changes[len(changes)-1].ID
+	}
+
+	c.JSON(http.StatusOK, trackingChangesResp{
+		Changes: changes,
+		Cursor:  cursor,
+	})
+}
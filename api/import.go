@@ -0,0 +1,65 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/billyb2/tracking_server/db"
+	"github.com/billyb2/tracking_server/trackings"
+	"github.com/gin-gonic/gin"
+)
+
+const maxImportSize = 20 << 20
+
+// ImportTrackings godoc
+//
+//	@Summary		Starts tracking every number in a CSV or XLSX file
+//	@Description	The first row names the columns: a tracking number column, and optionally carrier, group and notes (or reference) columns. Each row is imported on its own, and the report says what happened to every row, including rows the carrier couldn't be asked about.
+//	@ID				import-trackings
+//	@Accept			mpfd
+//	@Produce		json
+//	@Param			Authorization		header		string	true	"Bearer token"
+//	@Param			X-Organization-ID	header		int		false	"Organization, defaulting to the user's first"
+//	@Param			file				formData	file	true	"A .csv or .xlsx file"
+//	@Success		200					{object}	trackings.ImportReport
+//	@Failure		400					{object}	errorEnvelope
+//	@Failure		401					{object}	errorEnvelope
+//	@Failure		403					{object}	errorEnvelope
+//	@Failure		413					{object}	errorEnvelope
+//	@Failure		500					{object}	errorEnvelope
+//	@Router			/v1/trackings/import [post]
+func ImportTrackings(c *gin.Context) {
+	orgID := currentOrgID(c)
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportSize)
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		if c.Request.ContentLength > maxImportSize {
+			abortWithError(c, http.StatusRequestEntityTooLarge, codeInvalidRequest, fmt.Errorf("files can be at most %d MB", maxImportSize>>20))
+			return
+		}
+		abortWithError(c, http.StatusBadRequest, codeInvalidRequest, fmt.Errorf("error reading the uploaded file: %w", err))
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		abortWithError(c, http.StatusInternalServerError, codeInternal, err)
+		return
+	}
+	defer file.Close()
+
+	rows, err := trackings.ParseImport(fileHeader.Filename, file)
+	if err != nil {
+		abortWithTrackingError(c, err)
+		return
+	}
+
+	report, err := trackings.Import(db.FromGinContext(c), orgID, rows)
+	if err != nil {
+		abortWithTrackingError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
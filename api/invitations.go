@@ -0,0 +1,218 @@
+package api
+
+import (
+	"fmt"
+	"html"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/billyb2/tracking_server/db"
+	"github.com/billyb2/tracking_server/mailer"
+	"github.com/billyb2/tracking_server/orgs"
+	"github.com/gin-gonic/gin"
+)
+
+type createInvitation struct {
+	Email string `json:"email"`
+	// owner, admin, member or viewer
+	Role string `json:"role"`
+}
+
+// CreateInvitation godoc
+//
+//	@Summary		Emails someone an invitation to join the organization the request acts in
+//	@Description	Needs the admin role or above, and only owners can invite other owners. The email has an invite token that works for 7 days, and inviting the same email again replaces it.
+//	@ID				create-invitation
+//	@Accept			json
+//	@Produce		json
+//	@Param			Authorization		header		string				true	"Bearer token"
+//	@Param			X-Organization-ID	header		int					false	"Organization, defaulting to the user's first"
+//	@Param			createInvitation	body		createInvitation	true	"Invitation"
+//	@Success		201					{object}	orgs.Invitation
+//	@Failure		400					{object}	errorEnvelope
+//	@Failure		401					{object}	errorEnvelope
+//	@Failure		403					{object}	errorEnvelope
+//	@Failure		500					{object}	errorEnvelope
+//	@Failure		503					{object}	errorEnvelope
+//	@Router			/v1/organization/invitations [post]
+func CreateInvitation(c *gin.Context) {
+	membership := orgs.MembershipFromGinContext(c)
+
+	createInvitation := createInvitation{}
+	if err := c.ShouldBindJSON(&createInvitation); err != nil {
+		abortWithError(c, http.StatusBadRequest, codeInvalidRequest, fmt.Errorf("error parsing CreateInvitation: %w", err))
+		return
+	}
+
+	m := mailer.FromGinContext(c)
+	if m == nil {
+		abortWithError(c, http.StatusServiceUnavailable, codeUnavailable, mailer.NotConfigured)
+		return
+	}
+
+	invitation, token, err := orgs.Invite(db.FromGinContext(c), orgs.SignerFromGinContext(c), membership, createInvitation.Email, createInvitation.Role)
+	if err != nil {
+		abortWithOrgError(c, err)
+		return
+	}
+
+	expires := invitation.ExpiresAt.Format("January 2, 2006")
+	err = m.Send(&mailer.Message{
+		To:      []string{invitation.Email},
+		Subject: fmt.Sprintf("Join %s on Tracking Server", invitation.OrgName),
+		Text: fmt.Sprintf(
+			"%s invited you to join %s as a %s.\n\nRegister with this invite token, or log in and send it to /api/v1/invitations/accept:\n\n%s\n\nIt works until %s. Send it to /api/v1/invitations/decline to turn the invitation down.\n",
+			invitation.InvitedBy, invitation.OrgName, invitation.Role, token, expires,
+		),
+		HTML: fmt.Sprintf(
+			"<p>%s invited you to join %s as a %s.</p><p>Register with this invite token, or log in and send it to <code>/api/v1/invitations/accept</code>:</p><p><code>%s</code></p><p>It works until %s. Send it to <code>/api/v1/invitations/decline</code> to turn the invitation down.</p>",
+			html.EscapeString(invitation.InvitedBy), html.EscapeString(invitation.OrgName), invitation.Role, token, expires,
+		),
+	})
+	if err != nil {
+		// an invitation nobody got is no use
+		if err := orgs.RevokeInvitation(db.FromGinContext(c), invitation.OrgID, invitation.ID); err != nil {
+			fmt.Fprintln(os.Stderr, "error revoking unsent invitation", err)
+		}
+		abortWithError(c, http.StatusInternalServerError, codeInternal, fmt.Errorf("error sending invitation email: %w", err))
+		return
+	}
+
+	c.JSON(http.StatusCreated, invitation)
+}
+
+type invitationsResp struct {
+	Invitations []orgs.Invitation `json:"invitations"`
+}
+
+// ListInvitations godoc
+//
+//	@Summary	Lists the invitations to the organization the request acts in that haven't been answered or expired
+//	@ID			list-invitations
+//	@Produce	json
+//	@Param		Authorization		header		string	true	"Bearer token"
+//	@Param		X-Organization-ID	header		int		false	"Organization, defaulting to the user's first"
+//	@Success	200					{object}	invitationsResp
+//	@Failure	401					{object}	errorEnvelope
+//	@Failure	403					{object}	errorEnvelope
+//	@Failure	500					{object}	errorEnvelope
+//	@Router		/v1/organization/invitations [get]
+func ListInvitations(c *gin.Context) {
+	orgID := currentOrgID(c)
+
+	invitations, err := orgs.ListInvitations(db.FromGinContext(c), orgID)
+	if err != nil {
+		abortWithError(c, http.StatusInternalServerError, codeInternal, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, invitationsResp{
+		Invitations: invitations,
+	})
+}
+
+// RevokeInvitation godoc
+//
+//	@Summary	Revokes an invitation that hasn't been answered, so its invite token stops working
+//	@ID			revoke-invitation
+//	@Param		Authorization		header	string	true	"Bearer token"
+//	@Param		X-Organization-ID	header	int		false	"Organization, defaulting to the user's first"
+//	@Param		id					path	int		true	"Invitation id"
+//	@Success	204
+//	@Failure	400	{object}	errorEnvelope
+//	@Failure	401	{object}	errorEnvelope
+//	@Failure	403	{object}	errorEnvelope
+//	@Failure	404	{object}	errorEnvelope
+//	@Failure	500	{object}	errorEnvelope
+//	@Router		/v1/organization/invitations/{id} [delete]
+func RevokeInvitation(c *gin.Context) {
+	orgID := currentOrgID(c)
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		abortWithError(c, http.StatusBadRequest, codeInvalidRequest, fmt.Errorf("invalid invitation id %q", c.Param("id")))
+		return
+	}
+
+	if err := orgs.RevokeInvitation(db.FromGinContext(c), orgID, id); err != nil {
+		abortWithOrgError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+type answerInvitation struct {
+	// The invite token from the email
+	InviteToken string `json:"invite_token"`
+}
+
+// AcceptInvitation godoc
+//
+//	@Summary		Joins the organization an invite token is for
+//	@Description	Anyone logged in with the token can accept it, once. Send the organization's id in the X-Organization-ID header to act in it.
+//	@ID				accept-invitation
+//	@Accept			json
+//	@Produce		json
+//	@Param			Authorization		header		string				true	"Bearer token"
+//	@Param			answerInvitation	body		answerInvitation	true	"Invite token"
+//	@Success		200					{object}	orgs.Organization
+//	@Failure		400					{object}	errorEnvelope
+//	@Failure		401					{object}	errorEnvelope
+//	@Failure		403					{object}	errorEnvelope
+//	@Failure		409					{object}	errorEnvelope
+//	@Failure		500					{object}	errorEnvelope
+//	@Router			/v1/invitations/accept [post]
+func AcceptInvitation(c *gin.Context) {
+	userID := currentUserID(c)
+
+	answerInvitation := answerInvitation{}
+	if err := c.ShouldBindJSON(&answerInvitation); err != nil {
+		abortWithError(c, http.StatusBadRequest, codeInvalidRequest, fmt.Errorf("error parsing AcceptInvitation: %w", err))
+		return
+	}
+
+	tx, err := db.FromGinContext(c).Begin()
+	if err != nil {
+		abortWithError(c, http.StatusInternalServerError, codeInternal, err)
+		return
+	}
+	defer tx.Rollback()
+
+	organization, err := orgs.AcceptInvitation(tx, orgs.SignerFromGinContext(c), answerInvitation.InviteToken, userID)
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		abortWithOrgError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, organization)
+}
+
+// DeclineInvitation godoc
+//
+//	@Summary	Turns down the invitation an invite token is for. It doesn't need logging in
+//	@ID			decline-invitation
+//	@Accept		json
+//	@Param		answerInvitation	body	answerInvitation	true	"Invite token"
+//	@Success	204
+//	@Failure	400	{object}	errorEnvelope
+//	@Failure	500	{object}	errorEnvelope
+//	@Router		/v1/invitations/decline [post]
+func DeclineInvitation(c *gin.Context) {
+	answerInvitation := answerInvitation{}
+	if err := c.ShouldBindJSON(&answerInvitation); err != nil {
+		abortWithError(c, http.StatusBadRequest, codeInvalidRequest, fmt.Errorf("error parsing DeclineInvitation: %w", err))
+		return
+	}
+
+	if err := orgs.DeclineInvitation(db.FromGinContext(c), orgs.SignerFromGinContext(c), answerInvitation.InviteToken); err != nil {
+		abortWithOrgError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
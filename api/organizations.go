@@ -0,0 +1,175 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/billyb2/tracking_server/db"
+	"github.com/billyb2/tracking_server/orgs"
+	"github.com/gin-gonic/gin"
+)
+
+// memberID parses the user id in the path, responding with an error and returning false if it isn't a number
+func memberID(c *gin.Context) (int32, bool) {
+	id, err := strconv.ParseInt(c.Param("user_id"), 10, 32)
+	if err != nil {
+		abortWithError(c, http.StatusBadRequest, codeInvalidRequest, fmt.Errorf("invalid user id %q", c.Param("user_id")))
+		return 0, false
+	}
+
+	return int32(id), true
+}
+
+// abortWithOrgError picks the status code for an error from the orgs package
+func abortWithOrgError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, orgs.InvalidRole), errors.Is(err, orgs.InvalidNewInvitation), errors.Is(err, orgs.InvalidInvitation),
+		errors.Is(err, orgs.LastOwner):
+		abortWithError(c, http.StatusBadRequest, codeInvalidRequest, err)
+	case errors.Is(err, orgs.InsufficientRole):
+		abortWithError(c, http.StatusForbidden, codeForbidden, err)
+	case errors.Is(err, orgs.MemberNotFound), errors.Is(err, orgs.InvitationNotFound):
+		abortWithError(c, http.StatusNotFound, codeNotFound, err)
+	case errors.Is(err, orgs.AlreadyMember):
+		abortWithError(c, http.StatusConflict, codeAlreadyMember, err)
+	default:
+		abortWithError(c, http.StatusInternalServerError, codeInternal, err)
+	}
+}
+
+type organizationsResp struct {
+	Organizations []orgs.Organization `json:"organizations"`
+}
+
+// ListOrganizations godoc
+//
+//	@Summary		Lists the organizations the user is in, with their role in each
+//	@Description	Requests act in the first organization, unless another's id is sent in the X-Organization-ID header.
+//	@ID				list-organizations
+//	@Produce		json
+//	@Param			Authorization	header		string	true	"Bearer token"
+//	@Success		200				{object}	organizationsResp
+//	@Failure		401				{object}	errorEnvelope
+//	@Failure		403				{object}	errorEnvelope
+//	@Failure		500				{object}	errorEnvelope
+//	@Router			/v1/organizations [get]
+func ListOrganizations(c *gin.Context) {
+	userID := currentUserID(c)
+
+	organizations, err := orgs.List(db.FromGinContext(c), userID)
+	if err != nil {
+		abortWithError(c, http.StatusInternalServerError, codeInternal, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, organizationsResp{
+		Organizations: organizations,
+	})
+}
+
+type membersResp struct {
+	Members []orgs.Member `json:"members"`
+}
+
+// ListMembers godoc
+//
+//	@Summary	Lists the members of the organization the request acts in
+//	@ID			list-members
+//	@Produce	json
+//	@Param		Authorization		header		string	true	"Bearer token"
+//	@Param		X-Organization-ID	header		int		false	"Organization, defaulting to the user's first"
+//	@Success	200					{object}	membersResp
+//	@Failure	401					{object}	errorEnvelope
+//	@Failure	403					{object}	errorEnvelope
+//	@Failure	500					{object}	errorEnvelope
+//	@Router		/v1/organization/members [get]
+func ListMembers(c *gin.Context) {
+	orgID := currentOrgID(c)
+
+	members, err := orgs.Members(db.FromGinContext(c), orgID)
+	if err != nil {
+		abortWithError(c, http.StatusInternalServerError, codeInternal, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, membersResp{
+		Members: members,
+	})
+}
+
+type updateMember struct {
+	// owner, admin, member or viewer
+	Role string `json:"role"`
+}
+
+// UpdateMember godoc
+//
+//	@Summary		Changes a member's role in the organization the request acts in
+//	@Description	Needs the admin role or above. Only owners can change owners or make new ones, and the last owner can't stop being one.
+//	@ID				update-member
+//	@Accept			json
+//	@Produce		json
+//	@Param			Authorization		header		string			true	"Bearer token"
+//	@Param			X-Organization-ID	header		int				false	"Organization, defaulting to the user's first"
+//	@Param			user_id				path		int				true	"Member's user id"
+//	@Param			updateMember		body		updateMember	true	"Role"
+//	@Success		200					{object}	orgs.Member
+//	@Failure		400					{object}	errorEnvelope
+//	@Failure		401					{object}	errorEnvelope
+//	@Failure		403					{object}	errorEnvelope
+//	@Failure		404					{object}	errorEnvelope
+//	@Failure		500					{object}	errorEnvelope
+//	@Router			/v1/organization/members/{user_id} [patch]
+func UpdateMember(c *gin.Context) {
+	membership := orgs.MembershipFromGinContext(c)
+	userID, ok := memberID(c)
+	if !ok {
+		return
+	}
+
+	updateMember := updateMember{}
+	if err := c.ShouldBindJSON(&updateMember); err != nil {
+		abortWithError(c, http.StatusBadRequest, codeInvalidRequest, fmt.Errorf("error parsing UpdateMember: %w", err))
+		return
+	}
+
+	member, err := orgs.SetRole(db.FromGinContext(c), membership, userID, updateMember.Role)
+	if err != nil {
+		abortWithOrgError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, member)
+}
+
+// RemoveMember godoc
+//
+//	@Summary		Removes a member from the organization the request acts in
+//	@Description	Needs the admin role or above, and only owners can remove owners. The member's API keys for the organization are deleted and they're logged out everywhere.
+//	@ID				remove-member
+//	@Param			Authorization		header	string	true	"Bearer token"
+//	@Param			X-Organization-ID	header	int		false	"Organization, defaulting to the user's first"
+//	@Param			user_id				path	int		true	"Member's user id"
+//	@Success		204
+//	@Failure		400	{object}	errorEnvelope
+//	@Failure		401	{object}	errorEnvelope
+//	@Failure		403	{object}	errorEnvelope
+//	@Failure		404	{object}	errorEnvelope
+//	@Failure		500	{object}	errorEnvelope
+//	@Router			/v1/organization/members/{user_id} [delete]
+func RemoveMember(c *gin.Context) {
+	membership := orgs.MembershipFromGinContext(c)
+	userID, ok := memberID(c)
+	if !ok {
+		return
+	}
+
+	if err := orgs.RemoveMember(db.FromGinContext(c), membership, userID); err != nil {
+		abortWithOrgError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
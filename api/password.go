@@ -0,0 +1,154 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"net/http"
+	"net/mail"
+	"os"
+
+	"github.com/billyb2/tracking_server/auth"
+	"github.com/billyb2/tracking_server/db"
+	"github.com/billyb2/tracking_server/mailer"
+	"github.com/gin-gonic/gin"
+)
+
+type changePassword struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
+// ChangePassword godoc
+//
+//	@Summary		Changes the user's password
+//	@Description	Every other session is logged out, but the one making the request stays logged in. API keys keep working.
+//	@ID				change-password
+//	@Accept			json
+//	@Param			Authorization	header	string			true	"Bearer token"
+//	@Param			changePassword	body	changePassword	true	"Passwords"
+//	@Success		204
+//	@Failure		400	{object}	errorEnvelope
+//	@Failure		401	{object}	errorEnvelope
+//	@Failure		403	{object}	errorEnvelope
+//	@Failure		500	{object}	errorEnvelope
+//	@Router			/v1/password [put]
+func ChangePassword(c *gin.Context) {
+	userID := currentUserID(c)
+
+	changePassword := changePassword{}
+	if err := c.ShouldBindJSON(&changePassword); err != nil {
+		abortWithError(c, http.StatusBadRequest, codeInvalidRequest, fmt.Errorf("error parsing ChangePassword: %w", err))
+		return
+	}
+
+	token, _ := authorizationToken(c)
+	err := auth.ChangePassword(db.FromGinContext(c), userID, token, changePassword.CurrentPassword, changePassword.NewPassword)
+	if err != nil {
+		switch {
+		case errors.Is(err, auth.InvalidPassword):
+			abortWithError(c, http.StatusBadRequest, codeInvalidRequest, err)
+		case errors.Is(err, auth.WrongPassword):
+			abortWithError(c, http.StatusForbidden, codeForbidden, err)
+		default:
+			abortWithError(c, http.StatusInternalServerError, codeInternal, err)
+		}
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+type forgotPassword struct {
+	// The user's verified email
+	Email string `json:"email"`
+}
+
+// ForgotPassword godoc
+//
+//	@Summary		Emails a password reset token to the accounts with this verified email
+//	@Description	The response is the same whether or not any account has the email, and the token works once, for an hour.
+//	@ID				forgot-password
+//	@Accept			json
+//	@Param			forgotPassword	body	forgotPassword	true	"Email"
+//	@Success		202
+//	@Failure		400	{object}	errorEnvelope
+//	@Failure		500	{object}	errorEnvelope
+//	@Failure		503	{object}	errorEnvelope
+//	@Router			/v1/password/forgot [post]
+func ForgotPassword(c *gin.Context) {
+	forgotPassword := forgotPassword{}
+	if err := c.ShouldBindJSON(&forgotPassword); err != nil {
+		abortWithError(c, http.StatusBadRequest, codeInvalidRequest, fmt.Errorf("error parsing ForgotPassword: %w", err))
+		return
+	}
+
+	address, err := mail.ParseAddress(forgotPassword.Email)
+	if err != nil {
+		abortWithError(c, http.StatusBadRequest, codeInvalidRequest, fmt.Errorf("invalid email: %w", err))
+		return
+	}
+
+	m := mailer.FromGinContext(c)
+	if m == nil {
+		abortWithError(c, http.StatusServiceUnavailable, codeUnavailable, mailer.NotConfigured)
+		return
+	}
+
+	resets, err := auth.StartPasswordReset(db.FromGinContext(c), address.Address)
+	if err != nil {
+		abortWithError(c, http.StatusInternalServerError, codeInternal, err)
+		return
+	}
+
+	for _, reset := range resets {
+		err := m.Send(&mailer.Message{
+			To:      []string{reset.Email},
+			Subject: "Reset your password",
+			Text:    fmt.Sprintf("Send this token to /api/v1/password/reset with a new password for %s:\n\n%s\n\nIt works once, for an hour. If you didn't ask to reset your password, you can ignore this email.\n", reset.Username, reset.Token),
+			HTML:    fmt.Sprintf("<p>Send this token to <code>/api/v1/password/reset</code> with a new password for %s:</p><p><code>%s</code></p><p>It works once, for an hour. If you didn't ask to reset your password, you can ignore this email.</p>", html.EscapeString(reset.Username), reset.Token),
+		})
+		// the response can't say whether there was anyone to email
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "error sending password reset email", err)
+		}
+	}
+
+	c.Status(http.StatusAccepted)
+}
+
+type resetPassword struct {
+	// The token from the email
+	ResetToken  string `json:"reset_token"`
+	NewPassword string `json:"new_password"`
+}
+
+// ResetPassword godoc
+//
+//	@Summary	Sets a new password with a reset token from /v1/password/forgot, logging every session out and deleting every API key
+//	@ID			reset-password
+//	@Accept		json
+//	@Param		resetPassword	body	resetPassword	true	"Reset token and new password"
+//	@Success	204
+//	@Failure	400	{object}	errorEnvelope
+//	@Failure	500	{object}	errorEnvelope
+//	@Router		/v1/password/reset [post]
+func ResetPassword(c *gin.Context) {
+	resetPassword := resetPassword{}
+	if err := c.ShouldBindJSON(&resetPassword); err != nil {
+		abortWithError(c, http.StatusBadRequest, codeInvalidRequest, fmt.Errorf("error parsing ResetPassword: %w", err))
+		return
+	}
+
+	if err := auth.ResetPassword(db.FromGinContext(c), resetPassword.ResetToken, resetPassword.NewPassword); err != nil {
+		switch {
+		case errors.Is(err, auth.InvalidPassword), errors.Is(err, auth.InvalidResetToken):
+			abortWithError(c, http.StatusBadRequest, codeInvalidRequest, err)
+		default:
+			abortWithError(c, http.StatusInternalServerError, codeInternal, err)
+		}
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
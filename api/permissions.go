@@ -0,0 +1,79 @@
+package api
+
+import (
+	"fmt"
+
+	"github.com/billyb2/tracking_server/auth"
+	"github.com/billyb2/tracking_server/orgs"
+)
+
+type routePermission struct {
+	// The least role in the organization needed, or empty for routes that work without one
+	role string
+	// The scope API keys need, or empty if they can't be used
+	scope string
+	// Whether the handler enforces API keys' group restrictions
+	groupScoped bool
+}
+
+var noRoutePermission error = fmt.Errorf("%w: no permissions are set for this endpoint", auth.Forbidden)
+
+// routePermissions is what each authenticated route needs. Routes that aren't listed can't be used by anyone, so new
+// routes have to be added here. Webhooks, rules and the like belong to the user rather than the organization, so
+// viewers can manage their own.
+var routePermissions = map[string]routePermission{
+	"POST /api/logout":                             {"", "", false},
+	"POST /api/start_tracking":                     {orgs.RoleMember, auth.ScopeWriteTrackings, true},
+	"POST /api/get_tracking":                       {orgs.RoleViewer, auth.ScopeRead, true},
+	"POST /api/get_tracking_changes":               {orgs.RoleViewer, auth.ScopeRead, false},
+	"GET /api/trackings/stream":                    {orgs.RoleViewer, auth.ScopeRead, false},
+	"GET /api/trackings/ws":                        {orgs.RoleViewer, auth.ScopeRead, false},
+	"POST /api/create_webhook":                     {orgs.RoleViewer, auth.ScopeManageWebhooks, false},
+	"POST /api/get_webhooks":                       {orgs.RoleViewer, auth.ScopeManageWebhooks, false},
+	"POST /api/delete_webhook":                     {orgs.RoleViewer, auth.ScopeManageWebhooks, false},
+	"POST /api/get_webhook_deliveries":             {orgs.RoleViewer, auth.ScopeManageWebhooks, false},
+	"POST /api/redeliver_webhook":                  {orgs.RoleViewer, auth.ScopeManageWebhooks, false},
+	"POST /api/set_email":                          {orgs.RoleViewer, "", false},
+	"POST /api/set_group_email_recipients":         {orgs.RoleViewer, "", false},
+	"POST /api/get_group_email_recipients":         {orgs.RoleViewer, "", false},
+	"POST /api/create_chat_webhook":                {orgs.RoleViewer, auth.ScopeManageWebhooks, false},
+	"POST /api/get_chat_webhooks":                  {orgs.RoleViewer, auth.ScopeManageWebhooks, false},
+	"POST /api/delete_chat_webhook":                {orgs.RoleViewer, auth.ScopeManageWebhooks, false},
+	"POST /api/set_phone":                          {orgs.RoleViewer, "", false},
+	"POST /api/verify_phone":                       {orgs.RoleViewer, "", false},
+	"POST /api/create_notification_rule":           {orgs.RoleViewer, "", false},
+	"POST /api/get_notification_rules":             {orgs.RoleViewer, "", false},
+	"POST /api/delete_notification_rule":           {orgs.RoleViewer, "", false},
+	"POST /api/get_notification_log":               {orgs.RoleViewer, auth.ScopeRead, false},
+	"POST /api/create_digest_subscription":         {orgs.RoleViewer, "", false},
+	"POST /api/get_digest_subscriptions":           {orgs.RoleViewer, "", false},
+	"POST /api/delete_digest_subscription":         {orgs.RoleViewer, "", false},
+	"POST /api/get_digest":                         {orgs.RoleViewer, auth.ScopeRead, false},
+	"GET /api/v1/trackings":                        {orgs.RoleViewer, auth.ScopeRead, true},
+	"POST /api/v1/trackings":                       {orgs.RoleMember, auth.ScopeWriteTrackings, true},
+	"GET /api/v1/trackings/export":                 {orgs.RoleViewer, auth.ScopeRead, true},
+	"POST /api/v1/trackings/import":                {orgs.RoleMember, auth.ScopeWriteTrackings, false},
+	"POST /api/v1/trackings/archive":               {orgs.RoleMember, auth.ScopeWriteTrackings, false},
+	"POST /api/v1/trackings/unarchive":             {orgs.RoleMember, auth.ScopeWriteTrackings, false},
+	"POST /api/v1/trackings/delete":                {orgs.RoleMember, auth.ScopeWriteTrackings, false},
+	"GET /api/v1/trackings/:number":                {orgs.RoleViewer, auth.ScopeRead, true},
+	"PATCH /api/v1/trackings/:number":              {orgs.RoleMember, auth.ScopeWriteTrackings, true},
+	"DELETE /api/v1/trackings/:number":             {orgs.RoleMember, auth.ScopeWriteTrackings, true},
+	"GET /api/v1/groups":                           {orgs.RoleViewer, auth.ScopeRead, false},
+	"POST /api/v1/groups":                          {orgs.RoleMember, auth.ScopeWriteTrackings, false},
+	"GET /api/v1/groups/:id":                       {orgs.RoleViewer, auth.ScopeRead, false},
+	"PATCH /api/v1/groups/:id":                     {orgs.RoleMember, auth.ScopeWriteTrackings, false},
+	"POST /api/v1/groups/:id/trackings":            {orgs.RoleMember, auth.ScopeWriteTrackings, false},
+	"GET /api/v1/api_keys":                         {orgs.RoleViewer, "", false},
+	"POST /api/v1/api_keys":                        {orgs.RoleViewer, "", false},
+	"DELETE /api/v1/api_keys/:id":                  {orgs.RoleViewer, "", false},
+	"GET /api/v1/organizations":                    {"", "", false},
+	"GET /api/v1/organization/members":             {orgs.RoleViewer, auth.ScopeRead, false},
+	"PATCH /api/v1/organization/members/:user_id":  {orgs.RoleAdmin, "", false},
+	"DELETE /api/v1/organization/members/:user_id": {orgs.RoleAdmin, "", false},
+	"GET /api/v1/organization/invitations":         {orgs.RoleAdmin, "", false},
+	"POST /api/v1/organization/invitations":        {orgs.RoleAdmin, "", false},
+	"DELETE /api/v1/organization/invitations/:id":  {orgs.RoleAdmin, "", false},
+	"PUT /api/v1/password":                         {"", "", false},
+	"POST /api/v1/invitations/accept":              {"", "", false},
+}
@@ -0,0 +1,247 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/billyb2/tracking_server/db"
+	"github.com/billyb2/tracking_server/notify"
+	"github.com/gin-gonic/gin"
+)
+
+const defaultNotificationLogLimit = 100
+const maxNotificationLogLimit = 1000
+
+type createNotificationRule struct {
+	// Deprecated: send an Authorization: Bearer header instead
+	Token string      `json:"token"`
+	Rule  notify.Rule `json:"rule"`
+}
+
+type notificationRuleResp struct {
+	Rule  *notify.Rule `json:"rule,omitempty"`
+	Error string       `json:"error,omitempty"`
+}
+
+// CreateNotificationRule godoc
+//
+//	@Summary	Creates a rule picking which channels the user is notified on about which status changes. Once a user has any rules, only matching changes are sent
+//	@ID			create-notification-rule
+//	@Accept		json
+//	@Produce	json
+//	@Param		Authorization			header		string					false	"Bearer token"
+//	@Param		X-Organization-ID		header		int						false	"Organization, defaulting to the user's first"
+//	@Param		createNotificationRule	body		createNotificationRule	true	"Rule"
+//	@Success	201						{object}	notificationRuleResp
+//	@Failure	400						{object}	notificationRuleResp
+//	@Failure	401						{object}	errorResp
+//	@Failure	403						{object}	errorResp
+//	@Failure	500						{object}	notificationRuleResp
+//	@Router		/create_notification_rule [post]
+func CreateNotificationRule(c *gin.Context) {
+	createRule := createNotificationRule{}
+	if err := c.BindJSON(&createRule); err != nil {
+		err = fmt.Errorf("error parsing CreateNotificationRule: %w", err)
+		c.JSON(http.StatusBadRequest, notificationRuleResp{
+			Error: err.Error(),
+		})
+		return
+	}
+
+	userID, ok := authenticate(c, createRule.Token)
+	if !ok {
+		return
+	}
+
+	err := notify.CreateRule(db.FromGinContext(c), userID, currentOrgID(c), &createRule.Rule)
+	switch {
+	case errors.Is(err, notify.InvalidRule):
+		c.JSON(http.StatusBadRequest, notificationRuleResp{
+			Error: err.Error(),
+		})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, notificationRuleResp{
+			Error: err.Error(),
+		})
+	default:
+		c.JSON(http.StatusCreated, notificationRuleResp{
+			Rule: &createRule.Rule,
+		})
+	}
+}
+
+type getNotificationRules struct {
+	// Deprecated: send an Authorization: Bearer header instead
+	Token string `json:"token"`
+}
+
+type notificationRulesResp struct {
+	Rules []notify.Rule `json:"rules"`
+	Error string        `json:"error,omitempty"`
+}
+
+// GetNotificationRules godoc
+//
+//	@Summary	Lists the user's notification rules
+//	@ID			get-notification-rules
+//	@Accept		json
+//	@Produce	json
+//	@Param		Authorization			header		string					false	"Bearer token"
+//	@Param		X-Organization-ID		header		int						false	"Organization, defaulting to the user's first"
+//	@Param		getNotificationRules	body		getNotificationRules	true	"Token"
+//	@Success	200						{object}	notificationRulesResp
+//	@Failure	400						{object}	notificationRulesResp
+//	@Failure	401						{object}	errorResp
+//	@Failure	403						{object}	errorResp
+//	@Failure	500						{object}	notificationRulesResp
+//	@Router		/get_notification_rules [post]
+func GetNotificationRules(c *gin.Context) {
+	getRules := getNotificationRules{}
+	if err := c.BindJSON(&getRules); err != nil {
+		err = fmt.Errorf("error parsing GetNotificationRules: %w", err)
+		c.JSON(http.StatusBadRequest, notificationRulesResp{
+			Error: err.Error(),
+		})
+		return
+	}
+
+	userID, ok := authenticate(c, getRules.Token)
+	if !ok {
+		return
+	}
+
+	rules, err := notify.ListRules(db.FromGinContext(c), userID, currentOrgID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, notificationRulesResp{
+			Error: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, notificationRulesResp{
+		Rules: rules,
+	})
+}
+
+type deleteNotificationRule struct {
+	// Deprecated: send an Authorization: Bearer header instead
+	Token  string `json:"token"`
+	RuleID int64  `json:"rule_id"`
+}
+
+// DeleteNotificationRule godoc
+//
+//	@Summary	Deletes one of the user's notification rules
+//	@ID			delete-notification-rule
+//	@Accept		json
+//	@Produce	json
+//	@Param		Authorization			header		string					false	"Bearer token"
+//	@Param		X-Organization-ID		header		int						false	"Organization, defaulting to the user's first"
+//	@Param		deleteNotificationRule	body		deleteNotificationRule	true	"Rule"
+//	@Success	200						{object}	errorResp
+//	@Failure	400						{object}	errorResp
+//	@Failure	401						{object}	errorResp
+//	@Failure	403						{object}	errorResp
+//	@Failure	404						{object}	errorResp
+//	@Failure	500						{object}	errorResp
+//	@Router		/delete_notification_rule [post]
+func DeleteNotificationRule(c *gin.Context) {
+	deleteRule := deleteNotificationRule{}
+	if err := c.BindJSON(&deleteRule); err != nil {
+		err = fmt.Errorf("error parsing DeleteNotificationRule: %w", err)
+		c.JSON(http.StatusBadRequest, errorResp{
+			Error: err.Error(),
+		})
+		return
+	}
+
+	userID, ok := authenticate(c, deleteRule.Token)
+	if !ok {
+		return
+	}
+
+	err := notify.DeleteRule(db.FromGinContext(c), userID, currentOrgID(c), deleteRule.RuleID)
+	switch {
+	case errors.Is(err, notify.RuleNotFound):
+		c.JSON(http.StatusNotFound, errorResp{
+			Error: err.Error(),
+		})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, errorResp{
+			Error: err.Error(),
+		})
+	default:
+		c.JSON(http.StatusOK, errorResp{})
+	}
+}
+
+type getNotificationLog struct {
+	// Deprecated: send an Authorization: Bearer header instead
+	Token string `json:"token"`
+	// Cursor returned by the previous call, or 0 to start from the beginning
+	Cursor int64 `json:"cursor"`
+	Limit  int   `json:"limit,omitempty"`
+}
+
+type notificationLogResp struct {
+	Entries []notify.LogEntry `json:"entries"`
+	// Pass this as the cursor on the next call to only get newer entries
+	Cursor int64  `json:"cursor"`
+	Error  string `json:"error,omitempty"`
+}
+
+// GetNotificationLog godoc
+//
+//	@Summary	Gets what was sent, or why it wasn't, for each of the user's status changes
+//	@ID			get-notification-log
+//	@Accept		json
+//	@Produce	json
+//	@Param		Authorization		header		string				false	"Bearer token"
+//	@Param		X-Organization-ID	header		int					false	"Organization, defaulting to the user's first"
+//	@Param		getNotificationLog	body		getNotificationLog	true	"Cursor"
+//	@Success	200					{object}	notificationLogResp
+//	@Failure	400					{object}	notificationLogResp
+//	@Failure	401					{object}	errorResp
+//	@Failure	403					{object}	errorResp
+//	@Failure	500					{object}	notificationLogResp
+//	@Router		/get_notification_log [post]
+func GetNotificationLog(c *gin.Context) {
+	getLog := getNotificationLog{}
+	if err := c.BindJSON(&getLog); err != nil {
+		err = fmt.Errorf("error parsing GetNotificationLog: %w", err)
+		c.JSON(http.StatusBadRequest, notificationLogResp{
+			Error: err.Error(),
+		})
+		return
+	}
+
+	userID, ok := authenticate(c, getLog.Token)
+	if !ok {
+		return
+	}
+
+	limit := getLog.Limit
+	if limit <= 0 {
+		limit = defaultNotificationLogLimit
+	}
+	limit = min(limit, maxNotificationLogLimit)
+
+	entries, err := notify.Log(db.FromGinContext(c), userID, currentOrgID(c), getLog.Cursor, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, notificationLogResp{
+			Error: err.Error(),
+		})
+		return
+	}
+
+	cursor := getLog.Cursor
+	if len(entries) > 0 {
+		cursor = entries[len(entries)-1].ID
+	}
+
+	c.JSON(http.StatusOK, notificationLogResp{
+		Entries: entries,
+		Cursor:  cursor,
+	})
+}
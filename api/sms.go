@@ -0,0 +1,346 @@
+package api
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"os"
+
+	"github.com/billyb2/tracking_server/db"
+	"github.com/billyb2/tracking_server/notify"
+	"github.com/gin-gonic/gin"
+)
+
+type setPhone struct {
+	// Deprecated: send an Authorization: Bearer header instead
+	Token string `json:"token"`
+	// E.164 format, like +15555550123. Empty removes the user's phone number
+	Phone    string `json:"phone"`
+	SMSOptIn bool   `json:"sms_opt_in"`
+}
+
+// SetPhone godoc
+//
+//	@Summary		Sets the phone number the user is texted at when their packages are out for delivery, and whether they want texts at all
+//	@Description	Opting a new number in texts it a code, and nothing else is texted to it until the code is sent to verify_phone. Numbers that replied STOP can't be opted back in until they reply START.
+//	@ID				set-phone
+//	@Accept			json
+//	@Produce		json
+//	@Param			Authorization	header		string		false	"Bearer token"
+//	@Param			setPhone		body		setPhone	true	"Phone"
+//	@Success		200				{object}	errorResp
+//	@Success		202				{object}	errorResp
+//	@Failure		400				{object}	errorResp
+//	@Failure		401				{object}	errorResp
+//	@Failure		403				{object}	errorResp
+//	@Failure		409				{object}	errorResp
+//	@Failure		429				{object}	errorResp
+//	@Failure		500				{object}	errorResp
+//	@Failure		503				{object}	errorResp
+//	@Router			/set_phone [post]
+func SetPhone(c *gin.Context) {
+	setPhone := setPhone{}
+	if err := c.BindJSON(&setPhone); err != nil {
+		err = fmt.Errorf("error parsing SetPhone: %w", err)
+		c.JSON(http.StatusBadRequest, errorResp{
+			Error: err.Error(),
+		})
+		return
+	}
+
+	userID, ok := authenticate(c, setPhone.Token)
+	if !ok {
+		return
+	}
+
+	db := db.FromGinContext(c)
+	if setPhone.Phone == "" {
+		if err := removePhone(db, userID); err != nil {
+			c.JSON(http.StatusInternalServerError, errorResp{
+				Error: err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusOK, errorResp{})
+		return
+	}
+
+	if !notify.ValidPhoneNumber(setPhone.Phone) {
+		c.JSON(http.StatusBadRequest, errorResp{
+			Error: notify.InvalidPhoneNumber.Error(),
+		})
+		return
+	}
+
+	if setPhone.SMSOptIn {
+		optedOut, err := notify.SMSOptedOut(db, setPhone.Phone)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, errorResp{
+				Error: err.Error(),
+			})
+			return
+		}
+		if optedOut {
+			c.JSON(http.StatusConflict, errorResp{
+				Error: notify.PhoneOptedOut.Error(),
+			})
+			return
+		}
+	}
+
+	var verified bool
+	err := db.QueryRow("select exists(select 1 from users where id = ? and phone = ? and phone_verified_at is not null)", userID, setPhone.Phone).Scan(&verified)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResp{
+			Error: err.Error(),
+		})
+		return
+	}
+	// numbers that are already verified, or that aren't being opted in, don't need a code
+	if verified || !setPhone.SMSOptIn {
+		_, err := db.Exec(
+			"update users set phone = ?1, sms_opt_in = ?2, phone_verified_at = iif(phone = ?1, phone_verified_at, null) where id = ?3",
+			setPhone.Phone, setPhone.SMSOptIn, userID,
+		)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, errorResp{
+				Error: err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusOK, errorResp{})
+		return
+	}
+
+	client := notify.SMSClientFromGinContext(c)
+	if client == nil {
+		c.JSON(http.StatusServiceUnavailable, errorResp{
+			Error: notify.SMSNotConfigured.Error(),
+		})
+		return
+	}
+
+	code, err := startPhoneVerification(db, userID, setPhone.Phone)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResp{
+			Error: err.Error(),
+		})
+		return
+	}
+
+	if err := notify.SendVerificationCode(db, client, userID, setPhone.Phone, code); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, notify.DailyCapReached) {
+			status = http.StatusTooManyRequests
+		}
+		c.JSON(status, errorResp{
+			Error: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, errorResp{})
+}
+
+func removePhone(db *sql.DB, userID int32) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("update users set phone = null, sms_opt_in = false, phone_verified_at = null where id = ?", userID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("delete from phone_verifications where user_id = ?", userID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// startPhoneVerification sets the user's unverified phone number, opting it in once it's verified, and returns the code
+// to text it
+func startPhoneVerification(db *sql.DB, userID int32, phone string) (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(1_000_000))
+	if err != nil {
+		return "", err
+	}
+	code := fmt.Sprintf("%06d", n)
+
+	tx, err := db.Begin()
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("update users set phone = ?, sms_opt_in = true, phone_verified_at = null where id = ?", phone, userID); err != nil {
+		return "", err
+	}
+	// only the newest code works
+	_, err = tx.Exec(
+		`insert into phone_verifications (user_id, phone, code, expires_at) values (?, ?, ?, datetime('now', '+10 minutes'))
+		on conflict (user_id) do update set phone = excluded.phone, code = excluded.code, attempts = 0, expires_at = excluded.expires_at`,
+		userID, phone, code,
+	)
+	if err != nil {
+		return "", err
+	}
+
+	return code, tx.Commit()
+}
+
+// maxPhoneVerificationAttempts is how many wrong codes can be tried before the code stops working, since there are only a
+// million of them
+const maxPhoneVerificationAttempts = 5
+
+var invalidVerificationCode error = fmt.Errorf("invalid or expired verification code")
+
+type verifyPhone struct {
+	// Deprecated: send an Authorization: Bearer header instead
+	Token string `json:"token"`
+	Code  string `json:"code"`
+}
+
+// VerifyPhone godoc
+//
+//	@Summary	Verifies the user's phone number with the code texted to it, after which it's texted about their packages
+//	@ID			verify-phone
+//	@Accept		json
+//	@Produce	json
+//	@Param		Authorization	header		string		false	"Bearer token"
+//	@Param		verifyPhone		body		verifyPhone	true	"Code"
+//	@Success	200				{object}	errorResp
+//	@Failure	400				{object}	errorResp
+//	@Failure	401				{object}	errorResp
+//	@Failure	403				{object}	errorResp
+//	@Failure	500				{object}	errorResp
+//	@Router		/verify_phone [post]
+func VerifyPhone(c *gin.Context) {
+	verifyPhone := verifyPhone{}
+	if err := c.BindJSON(&verifyPhone); err != nil {
+		err = fmt.Errorf("error parsing VerifyPhone: %w", err)
+		c.JSON(http.StatusBadRequest, errorResp{
+			Error: err.Error(),
+		})
+		return
+	}
+
+	userID, ok := authenticate(c, verifyPhone.Token)
+	if !ok {
+		return
+	}
+
+	err := verifyPhoneCode(db.FromGinContext(c), userID, verifyPhone.Code)
+	switch {
+	case errors.Is(err, invalidVerificationCode):
+		c.JSON(http.StatusBadRequest, errorResp{
+			Error: err.Error(),
+		})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, errorResp{
+			Error: err.Error(),
+		})
+	default:
+		c.JSON(http.StatusOK, errorResp{})
+	}
+}
+
+func verifyPhoneCode(db *sql.DB, userID int32, code string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	row := tx.QueryRow(
+		`select phone, code from phone_verifications
+		where user_id = ? and attempts < ? and expires_at > datetime('now') and phone = (select phone from users where id = user_id)`,
+		userID, maxPhoneVerificationAttempts,
+	)
+	var phone, expectedCode string
+	if err := row.Scan(&phone, &expectedCode); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return invalidVerificationCode
+		default:
+			return err
+		}
+	}
+
+	if subtle.ConstantTimeCompare([]byte(code), []byte(expectedCode)) != 1 {
+		if _, err := tx.Exec("update phone_verifications set attempts = attempts + 1 where user_id = ?", userID); err != nil {
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+		return invalidVerificationCode
+	}
+
+	if _, err := tx.Exec("update users set phone_verified_at = datetime('now') where id = ? and phone = ?", userID, phone); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("delete from phone_verifications where user_id = ?", userID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+const smsOptOutReply = "You won't get any more texts about your packages. Reply START to opt back in."
+const smsOptInReply = "You'll get texts when your packages are out for delivery. Reply STOP to opt out."
+
+// SMSInbound godoc
+//
+//	@Summary	Twilio compatible webhook for inbound texts, which opts the sender out of texts if they reply STOP, or back in if they reply START
+//	@ID			sms-inbound
+//	@Accept		x-www-form-urlencoded
+//	@Produce	xml
+//	@Param		From	formData	string	true	"Sender's phone number"
+//	@Param		Body	formData	string	true	"Text"
+//	@Success	200
+//	@Failure	403
+//	@Failure	500
+//	@Failure	503
+//	@Router		/sms/inbound [post]
+func SMSInbound(c *gin.Context) {
+	client := notify.SMSClientFromGinContext(c)
+	if client == nil {
+		c.Status(http.StatusServiceUnavailable)
+		return
+	}
+
+	if err := c.Request.ParseForm(); err != nil {
+		c.Status(http.StatusBadRequest)
+		return
+	}
+
+	// the signature covers the URL twilio was configured with, which we can't know for sure behind a proxy
+	webhookURL := os.Getenv("PUBLIC_URL") + c.Request.URL.RequestURI()
+	if !client.ValidSignature(webhookURL, c.Request.PostForm, c.GetHeader("X-Twilio-Signature")) {
+		c.Status(http.StatusForbidden)
+		return
+	}
+
+	optIn, ok := notify.SMSKeywordOptIn(c.Request.PostForm.Get("Body"))
+	if !ok {
+		c.Data(http.StatusOK, "text/xml", []byte("<Response></Response>"))
+		return
+	}
+
+	if err := notify.SetSMSOptIn(db.FromGinContext(c), c.Request.PostForm.Get("From"), optIn); err != nil {
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+
+	reply := smsOptOutReply
+	if optIn {
+		reply = smsOptInReply
+	}
+	c.Data(http.StatusOK, "text/xml", []byte("<Response><Message>"+reply+"</Message></Response>"))
+}
@@ -0,0 +1,116 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/billyb2/tracking_server/db"
+	"github.com/billyb2/tracking_server/history"
+	"github.com/gin-gonic/gin"
+)
+
+const streamPollInterval = time.Second
+const streamHeartbeatInterval = 15 * time.Second
+const streamBatchLimit = 100
+
+// StreamTrackingChanges godoc
+//
+//	@Summary		Streams the status changes of the organization's tracking numbers as server-sent events
+//	@Description	Each event has the change's id, which browsers send back as Last-Event-ID when they reconnect so that no changes are missed. Without one, only changes after connecting are sent. A comment is sent every 15 seconds to keep the connection open.
+//	@ID				stream-tracking-changes
+//	@Produce		text/event-stream
+//	@Param			Authorization		header		string	false	"Bearer token"
+//	@Param			X-Organization-ID	header		int		false	"Organization, defaulting to the user's first"
+//	@Param			token				query		string	false	"Token, for clients that can't set headers"
+//	@Param			Last-Event-ID		header		string	false	"Id of the last change the client saw"
+//	@Param			last_event_id		query		string	false	"Id of the last change the client saw, for clients that can't set headers"
+//	@Success		200					{object}	history.Change
+//	@Failure		400					{object}	errorResp
+//	@Failure		401					{object}	errorResp
+//	@Failure		403					{object}	errorResp
+//	@Failure		500					{object}	errorResp
+//	@Router			/trackings/stream [get]
+func StreamTrackingChanges(c *gin.Context) {
+	userID, ok := authenticateQuery(c)
+	if !ok {
+		return
+	}
+	db := db.FromGinContext(c)
+	orgID := currentOrgID(c)
+
+	lastEventID := c.GetHeader("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = c.Query("last_event_id")
+	}
+
+	var cursor int64
+	if lastEventID != "" {
+		var err error
+		if cursor, err = strconv.ParseInt(lastEventID, 10, 64); err != nil || cursor < 0 {
+			c.JSON(http.StatusBadRequest, errorResp{
+				Error: fmt.Sprintf("invalid Last-Event-ID %q", lastEventID),
+			})
+			return
+		}
+	} else {
+		var err error
+		if cursor, err = history.LatestID(db); err != nil {
+			c.JSON(http.StatusInternalServerError, errorResp{
+				Error: err.Error(),
+			})
+			return
+		}
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	// stops nginx from buffering the stream
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	// how long browsers wait before reconnecting, in milliseconds
+	fmt.Fprint(c.Writer, "retry: 3000\n\n")
+	c.Writer.Flush()
+
+	poll := time.NewTicker(streamPollInterval)
+	defer poll.Stop()
+	heartbeat := time.NewTicker(streamHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+
+		case <-heartbeat.C:
+			fmt.Fprint(c.Writer, ": heartbeat\n\n")
+			c.Writer.Flush()
+
+		case <-poll.C:
+			changes, err := history.Since(db, userID, orgID, cursor, streamBatchLimit)
+			if err != nil {
+				// the client reconnects with the last id it saw, so nothing is lost by ending the stream
+				fmt.Fprintln(os.Stderr, "error streaming tracking changes", err)
+				return
+			}
+
+			for _, change := range changes {
+				data, err := json.Marshal(change)
+				if err != nil {
+					fmt.Fprintln(os.Stderr, "error streaming tracking changes", err)
+					return
+				}
+
+				fmt.Fprintf(c.Writer, "id: %d\nevent: status_changed\ndata: %s\n\n", change.ID, data)
+				cursor = change.ID
+			}
+			if len(changes) > 0 {
+				c.Writer.Flush()
+			}
+		}
+	}
+}
@@ -1,14 +1,15 @@
 package api
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
-	"strings"
+	"os"
 
-	"github.com/billyb2/tracking_server/auth"
 	"github.com/billyb2/tracking_server/db"
-	fedex "github.com/billyb2/tracking_server/tracking"
+	"github.com/billyb2/tracking_server/idempotency"
+	"github.com/billyb2/tracking_server/trackings"
 	"github.com/gin-gonic/gin"
 )
 
@@ -19,66 +20,137 @@ type trackingNumberGroup struct {
 
 type startTracking struct {
 	TrackingNumberGroups []trackingNumberGroup `json:"tracking_number_groups"`
-	Token                string                `json:"token"`
+	// Deprecated: send an Authorization: Bearer header instead
+	Token string `json:"token"`
 }
 
 type startTrackingResp struct {
-	Error string `json:"error,omitempty"`
+	// What happened to each number, in the order they were sent
+	Results []trackings.StartResult `json:"results,omitempty"`
+	Error   string                  `json:"error,omitempty"`
 }
 
 // Register godoc
 //
-//	@Summary	Starts tracking the package tracking numbers given by the user
-//	@ID			start-tracking-groups
-//	@Accept		json
-//	@Produce	json
-//	@Param		startTrackingInfo	body		startTracking	true	"Tracking Info"
-//	@Success	201					{object}	startTrackingResp
-//	@Failure	403					{object}	startTrackingResp
-//	@Failure	500					{object}	startTrackingResp
-//	@Router		/start_tracking [post]
+//	@Summary		Starts tracking the package tracking numbers given by the user
+//	@Description	Numbers that are already tracked are moved into the group they're sent with, so sending the same numbers again is harmless. Send an Idempotency-Key header to have a retried request get the first one's response back.
+//	@ID				start-tracking-groups
+//	@Accept			json
+//	@Produce		json
+//	@Param			Authorization		header		string			false	"Bearer token"
+//	@Param			X-Organization-ID	header		int				false	"Organization, defaulting to the user's first"
+//	@Param			Idempotency-Key		header		string			false	"Any unique string, like a UUID"
+//	@Param			startTrackingInfo	body		startTracking	true	"Tracking Info"
+//	@Success		201					{object}	startTrackingResp
+//	@Failure		400					{object}	startTrackingResp
+//	@Failure		401					{object}	startTrackingResp
+//	@Failure		403					{object}	startTrackingResp
+//	@Failure		409					{object}	startTrackingResp
+//	@Failure		422					{object}	startTrackingResp
+//	@Failure		500					{object}	startTrackingResp
+//	@Router			/start_tracking [post]
 func StartTrackingGroups(c *gin.Context) {
 	startTracking := startTracking{}
 	if err := c.BindJSON(&startTracking); err != nil {
-		err = fmt.Errorf("error parsing StartTracking: %w")
+		err = fmt.Errorf("error parsing StartTracking: %w", err)
+		c.JSON(http.StatusBadRequest, startTrackingResp{
+			Error: err.Error(),
+		})
+		return
 	}
 
-	userID, err := auth.UserIDFromToken(c, startTracking.Token)
-	if err != nil {
-		err = fmt.Errorf("auth error: %w", err)
-		switch {
-		case errors.Is(err, auth.InvalidToken):
+	userID, ok := authenticate(c, startTracking.Token)
+	if !ok {
+		return
+	}
+
+	for i := range startTracking.TrackingNumberGroups {
+		if err := restrictToGroup(c, &startTracking.TrackingNumberGroups[i].GroupName); err != nil {
 			c.JSON(http.StatusForbidden, startTrackingResp{
 				Error: err.Error(),
 			})
 			return
-		default:
-			c.JSON(http.StatusInternalServerError, startTrackingResp{
+		}
+	}
+
+	db := db.FromGinContext(c)
+	key := c.GetHeader(idempotency.Header)
+	if key != "" {
+		saved, err := idempotency.Begin(db, userID, currentOrgID(c), key, startTracking.TrackingNumberGroups)
+		if err != nil {
+			status := http.StatusInternalServerError
+			switch {
+			case errors.Is(err, idempotency.InvalidKey):
+				status = http.StatusBadRequest
+			case errors.Is(err, idempotency.InProgress):
+				status = http.StatusConflict
+			case errors.Is(err, idempotency.KeyReused):
+				status = http.StatusUnprocessableEntity
+			}
+			c.JSON(status, startTrackingResp{
 				Error: err.Error(),
 			})
 			return
 		}
+		if saved != nil {
+			c.Header("Idempotent-Replayed", "true")
+			c.Data(saved.Status, "application/json; charset=utf-8", saved.Body)
+			return
+		}
 	}
 
+	newTrackings := []trackings.NewTracking{}
 	for _, group := range startTracking.TrackingNumberGroups {
-		err := createTrackingNumberGroup(c, &group, userID)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, startTrackingResp{
-				Error: err.Error(),
+		for _, trackingNumber := range group.TrackingNumbers {
+			newTrackings = append(newTrackings, trackings.NewTracking{
+				TrackingNumber: trackingNumber,
+				GroupName:      group.GroupName,
 			})
-			return
 		}
 	}
 
-	c.JSON(http.StatusCreated, startTrackingResp{
-		Error: "",
-	})
+	status := http.StatusCreated
+	resp := startTrackingResp{}
+	// numbers in other groups stay out of reach of keys restricted to one
+	var onlyGroup *string
+	if group, ok := keyGroup(c); ok {
+		onlyGroup = &group
+	}
+	results, err := trackings.StartEach(db, currentOrgID(c), onlyGroup, newTrackings)
+	if err != nil {
+		status = http.StatusInternalServerError
+		resp.Error = err.Error()
+	} else {
+		resp.Results = results
+	}
+
+	body, err := json.Marshal(resp)
+	if err != nil {
+		status = http.StatusInternalServerError
+		body, _ = json.Marshal(startTrackingResp{
+			Error: err.Error(),
+		})
+	}
+
+	if key != "" {
+		// failures are forgotten, so that retrying them can work
+		if status >= http.StatusInternalServerError {
+			err = idempotency.Release(db, userID, currentOrgID(c), key)
+		} else {
+			err = idempotency.Finish(db, userID, currentOrgID(c), key, &idempotency.Response{Status: status, Body: body})
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "error saving idempotent response", err)
+		}
+	}
 
+	c.Data(status, "application/json; charset=utf-8", body)
 }
 
 type getTracking struct {
 	TrackingNumbers []string `json:"tracking_numbers"`
-	Token           string   `json:"token"`
+	// Deprecated: send an Authorization: Bearer header instead
+	Token string `json:"token"`
 }
 
 type trackingInfo struct {
@@ -91,86 +163,42 @@ type trackingInfo struct {
 //	@ID			get-tracking-numbers
 //	@Accept		json
 //	@Produce	json
-//	@Param		getTrackingInfo	body		getTracking	true	"Tracking numbers"
-//	@Success	200				{object}	trackingInfo
-//	@Failure	403				{object}	trackingInfo
-//	@Failure	500				{object}	trackingInfo
+//	@Param		Authorization		header		string		false	"Bearer token"
+//	@Param		X-Organization-ID	header		int			false	"Organization, defaulting to the user's first"
+//	@Param		getTrackingInfo		body		getTracking	true	"Tracking numbers"
+//	@Success	200					{object}	trackingInfo
+//	@Failure	401					{object}	trackingInfo
+//	@Failure	403					{object}	trackingInfo
+//	@Failure	500					{object}	trackingInfo
 //	@Router		/get_tracking [post]
 func GetTrackingNumbers(c *gin.Context) {
 	getTracking := getTracking{}
 	if err := c.BindJSON(&getTracking); err != nil {
-		err = fmt.Errorf("error parsing StartTracking: %w")
+		err = fmt.Errorf("error parsing GetTracking: %w", err)
+		c.JSON(http.StatusBadRequest, err.Error())
+		return
 	}
 
-	userID, err := auth.UserIDFromToken(c, getTracking.Token)
-	if err != nil {
-		err = fmt.Errorf("auth error: %w", err)
-		switch {
-		case errors.Is(err, auth.InvalidToken):
-			c.JSON(http.StatusForbidden, err.Error())
-			return
-		default:
-			c.JSON(http.StatusInternalServerError, err.Error())
-			return
-		}
+	if _, ok := authenticate(c, getTracking.Token); !ok {
+		return
 	}
 
-	db := db.FromGinContext(c)
-
-	rows, err := db.Query("select tracking_number, status from tracking where tracking_number in (?) and user_id = ?", strings.Join(getTracking.TrackingNumbers, "?"), userID)
+	found, err := trackings.GetMany(db.FromGinContext(c), currentOrgID(c), getTracking.TrackingNumbers)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, err.Error())
 		return
 	}
-	defer rows.Close()
 
 	trackingInfo := trackingInfo{
 		TrackingNumberStatuses: map[string]string{},
 	}
-
-	for rows.Next() {
-		var trackingNumber string
-		var status string
-		if err := rows.Scan(&trackingNumber, &status); err != nil {
-			return
+	group, restricted := keyGroup(c)
+	for _, tracking := range found {
+		if restricted && tracking.GroupName != group {
+			continue
 		}
-
-		trackingInfo.TrackingNumberStatuses[trackingNumber] = status
+		trackingInfo.TrackingNumberStatuses[tracking.TrackingNumber] = tracking.Status
 	}
 
 	c.JSON(http.StatusOK, trackingInfo)
 }
-
-func createTrackingNumberGroup(c *gin.Context, trackingNumberGroup *trackingNumberGroup, userID int32) error {
-	db := db.FromGinContext(c)
-	if db == nil {
-		return fmt.Errorf("db is nil")
-	}
-
-	tx, err := db.Begin()
-	if err != nil {
-		return err
-	}
-	defer tx.Commit()
-
-	groupName := &trackingNumberGroup.GroupName
-	if *groupName == "" {
-		groupName = nil
-	}
-	resp, err := fedex.TrackByTrackingNumber(trackingNumberGroup.TrackingNumbers)
-	if err != nil {
-		panic(err)
-	}
-
-	for trackingNumber, trackingStatus := range resp {
-		_, err := tx.Exec(
-			"insert into tracking (tracking_number, status, group_name, status_last_updated, user_id) values ( ?, ?, ?, datetime('now'), ? );",
-			trackingNumber, trackingStatus.StatusDescription, trackingNumberGroup.GroupName, userID,
-		)
-		if err != nil {
-			return err
-		}
-	}
-
-	return tx.Commit()
-}
@@ -0,0 +1,440 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/billyb2/tracking_server/auth"
+	"github.com/billyb2/tracking_server/db"
+	"github.com/billyb2/tracking_server/trackings"
+	"github.com/gin-gonic/gin"
+)
+
+// Error codes used by the /v1 endpoints
+const (
+	codeInvalidRequest     = "invalid_request"
+	codeUnauthorized       = "unauthorized"
+	codeForbidden          = "forbidden"
+	codeNotFound           = "not_found"
+	codeAlreadyTracked     = "already_tracked"
+	codeGroupExists        = "group_exists"
+	codeAlreadyMember      = "already_member"
+	codeCarrierNotFound    = "carrier_not_found"
+	codeCarrierUnavailable = "carrier_unavailable"
+	codeUnavailable        = "unavailable"
+	codeInternal           = "internal_error"
+)
+
+type apiError struct {
+	// Stable, machine readable, like not_found
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// errorEnvelope is how every /v1 endpoint reports errors
+type errorEnvelope struct {
+	Error apiError `json:"error"`
+}
+
+func abortWithError(c *gin.Context, status int, code string, err error) {
+	c.AbortWithStatusJSON(status, errorEnvelope{
+		Error: apiError{
+			Code:    code,
+			Message: err.Error(),
+		},
+	})
+}
+
+// abortWithTrackingError picks the status code for an error from the trackings package
+func abortWithTrackingError(c *gin.Context, err error) {
+	var carrierErr *trackings.CarrierError
+	switch {
+	case errors.Is(err, trackings.NotFound), errors.Is(err, trackings.GroupNotFound):
+		abortWithError(c, http.StatusNotFound, codeNotFound, err)
+	case errors.Is(err, trackings.InvalidTrackingNumber), errors.Is(err, trackings.InvalidListOptions), errors.Is(err, trackings.InvalidGroupName), errors.Is(err, trackings.InvalidSelection),
+		errors.Is(err, trackings.InvalidImport):
+		abortWithError(c, http.StatusBadRequest, codeInvalidRequest, err)
+	case errors.Is(err, trackings.GroupExists):
+		abortWithError(c, http.StatusConflict, codeGroupExists, err)
+	case errors.Is(err, trackings.AlreadyTracked):
+		abortWithError(c, http.StatusConflict, codeAlreadyTracked, err)
+	case errors.Is(err, trackings.CarrierNotFound):
+		abortWithError(c, http.StatusUnprocessableEntity, codeCarrierNotFound, err)
+	case errors.As(err, &carrierErr):
+		abortWithError(c, http.StatusBadGateway, codeCarrierUnavailable, err)
+	default:
+		abortWithError(c, http.StatusInternalServerError, codeInternal, err)
+	}
+}
+
+// trackingFilters are the query params list and export filter numbers with
+type trackingFilters struct {
+	Group string `form:"group"`
+	// Canonical statuses, either repeated or comma separated
+	Status  []string `form:"status"`
+	Carrier string   `form:"carrier"`
+	// RFC 3339 timestamps
+	UpdatedSince *time.Time `form:"updated_since" time_format:"2006-01-02T15:04:05Z07:00"`
+	ETAFrom      *time.Time `form:"eta_from" time_format:"2006-01-02T15:04:05Z07:00"`
+	ETABefore    *time.Time `form:"eta_before" time_format:"2006-01-02T15:04:05Z07:00"`
+	// Only numbers delivered in [delivered_from, delivered_before)
+	DeliveredFrom   *time.Time `form:"delivered_from" time_format:"2006-01-02T15:04:05Z07:00"`
+	DeliveredBefore *time.Time `form:"delivered_before" time_format:"2006-01-02T15:04:05Z07:00"`
+	// Searches tracking numbers and notes
+	Q string `form:"q"`
+	// false leaves out archived numbers, true only has archived numbers, and any has both
+	Archived string `form:"archived"`
+}
+
+func (f *trackingFilters) listOptions() *trackings.ListOptions {
+	statuses := []string{}
+	for _, status := range f.Status {
+		statuses = append(statuses, strings.Split(status, ",")...)
+	}
+
+	return &trackings.ListOptions{
+		GroupName:       f.Group,
+		Statuses:        statuses,
+		Carrier:         f.Carrier,
+		UpdatedSince:    f.UpdatedSince,
+		ETAFrom:         f.ETAFrom,
+		ETABefore:       f.ETABefore,
+		DeliveredFrom:   f.DeliveredFrom,
+		DeliveredBefore: f.DeliveredBefore,
+		Search:          f.Q,
+		Archived:        f.Archived,
+	}
+}
+
+type listTrackings struct {
+	trackingFilters
+	// tracking_number, created_at, eta or status_last_updated, with a - in front to sort descending
+	Sort   string `form:"sort"`
+	Limit  int    `form:"limit"`
+	Cursor string `form:"cursor"`
+}
+
+type trackingsResp struct {
+	Trackings []trackings.Tracking `json:"trackings"`
+	// Pass this as the cursor to get the next page, empty on the last page
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// ListTrackings godoc
+//
+//	@Summary	Lists the tracking numbers the organization is tracking, a page at a time
+//	@ID			list-trackings
+//	@Produce	json
+//	@Param		Authorization		header		string			true	"Bearer token"
+//	@Param		X-Organization-ID	header		int				false	"Organization, defaulting to the user's first"
+//	@Param		filters				query		listTrackings	false	"Filters, sorting and paging"
+//	@Success	200					{object}	trackingsResp
+//	@Failure	400					{object}	errorEnvelope
+//	@Failure	401					{object}	errorEnvelope
+//	@Failure	403					{object}	errorEnvelope
+//	@Failure	500					{object}	errorEnvelope
+//	@Router		/v1/trackings [get]
+func ListTrackings(c *gin.Context) {
+	orgID := currentOrgID(c)
+
+	listTrackings := listTrackings{}
+	if err := c.ShouldBindQuery(&listTrackings); err != nil {
+		abortWithError(c, http.StatusBadRequest, codeInvalidRequest, fmt.Errorf("error parsing ListTrackings: %w", err))
+		return
+	}
+
+	opts := listTrackings.listOptions()
+	opts.Sort = listTrackings.Sort
+	opts.Limit = listTrackings.Limit
+	opts.Cursor = listTrackings.Cursor
+	if err := restrictToGroup(c, &opts.GroupName); err != nil {
+		abortWithError(c, http.StatusForbidden, codeForbidden, err)
+		return
+	}
+
+	list, nextCursor, err := trackings.List(db.FromGinContext(c), orgID, opts)
+	if err != nil {
+		abortWithTrackingError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, trackingsResp{
+		Trackings:  list,
+		NextCursor: nextCursor,
+	})
+}
+
+// GetTracking godoc
+//
+//	@Summary	Gets one of the organization's tracking numbers
+//	@ID			get-tracking
+//	@Produce	json
+//	@Param		Authorization		header		string	true	"Bearer token"
+//	@Param		X-Organization-ID	header		int		false	"Organization, defaulting to the user's first"
+//	@Param		number				path		string	true	"Tracking number"
+//	@Success	200					{object}	trackings.Tracking
+//	@Failure	401					{object}	errorEnvelope
+//	@Failure	403					{object}	errorEnvelope
+//	@Failure	404					{object}	errorEnvelope
+//	@Failure	500					{object}	errorEnvelope
+//	@Router		/v1/trackings/{number} [get]
+func GetTracking(c *gin.Context) {
+	orgID := currentOrgID(c)
+
+	tracking, err := trackings.Get(db.FromGinContext(c), orgID, c.Param("number"))
+	if group, ok := keyGroup(c); err == nil && ok && tracking.GroupName != group {
+		err = trackings.NotFound
+	}
+	if err != nil {
+		abortWithTrackingError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, tracking)
+}
+
+type createTracking struct {
+	TrackingNumber string `json:"tracking_number"`
+	GroupName      string `json:"group_name,omitempty"`
+	Notes          string `json:"notes,omitempty"`
+}
+
+// CreateTracking godoc
+//
+//	@Summary	Starts tracking a tracking number
+//	@ID			create-tracking
+//	@Accept		json
+//	@Produce	json
+//	@Param		Authorization		header		string			true	"Bearer token"
+//	@Param		X-Organization-ID	header		int				false	"Organization, defaulting to the user's first"
+//	@Param		createTracking		body		createTracking	true	"Tracking number"
+//	@Success	201					{object}	trackings.Tracking
+//	@Failure	400					{object}	errorEnvelope
+//	@Failure	401					{object}	errorEnvelope
+//	@Failure	403					{object}	errorEnvelope
+//	@Failure	409					{object}	errorEnvelope
+//	@Failure	422					{object}	errorEnvelope
+//	@Failure	500					{object}	errorEnvelope
+//	@Failure	502					{object}	errorEnvelope
+//	@Router		/v1/trackings [post]
+func CreateTracking(c *gin.Context) {
+	orgID := currentOrgID(c)
+
+	createTracking := createTracking{}
+	if err := c.ShouldBindJSON(&createTracking); err != nil {
+		abortWithError(c, http.StatusBadRequest, codeInvalidRequest, fmt.Errorf("error parsing CreateTracking: %w", err))
+		return
+	}
+	if err := restrictToGroup(c, &createTracking.GroupName); err != nil {
+		abortWithError(c, http.StatusForbidden, codeForbidden, err)
+		return
+	}
+
+	created, err := trackings.Start(db.FromGinContext(c), orgID, createTracking.GroupName, createTracking.Notes, []string{createTracking.TrackingNumber})
+	if err == nil && len(created) == 0 {
+		err = trackings.CarrierNotFound
+	}
+	if err != nil {
+		abortWithTrackingError(c, err)
+		return
+	}
+
+	c.Header("Location", "/api/v1/trackings/"+url.PathEscape(created[0].TrackingNumber))
+	c.JSON(http.StatusCreated, created[0])
+}
+
+// UpdateTracking godoc
+//
+//	@Summary	Changes a tracking number's group or notes, or archives it. Fields that are left out aren't changed, and empty strings clear them
+//	@ID			update-tracking
+//	@Accept		json
+//	@Produce	json
+//	@Param		Authorization		header		string			true	"Bearer token"
+//	@Param		X-Organization-ID	header		int				false	"Organization, defaulting to the user's first"
+//	@Param		number				path		string			true	"Tracking number"
+//	@Param		patch				body		trackings.Patch	true	"Fields to change"
+//	@Success	200					{object}	trackings.Tracking
+//	@Failure	400					{object}	errorEnvelope
+//	@Failure	401					{object}	errorEnvelope
+//	@Failure	403					{object}	errorEnvelope
+//	@Failure	404					{object}	errorEnvelope
+//	@Failure	500					{object}	errorEnvelope
+//	@Router		/v1/trackings/{number} [patch]
+func UpdateTracking(c *gin.Context) {
+	orgID := currentOrgID(c)
+
+	patch := trackings.Patch{}
+	if err := c.ShouldBindJSON(&patch); err != nil {
+		abortWithError(c, http.StatusBadRequest, codeInvalidRequest, fmt.Errorf("error parsing UpdateTracking: %w", err))
+		return
+	}
+	if err := checkKeyGroup(c, c.Param("number")); err != nil {
+		abortWithTrackingError(c, err)
+		return
+	}
+	if group, ok := keyGroup(c); ok && patch.GroupName != nil && *patch.GroupName != group {
+		abortWithError(c, http.StatusForbidden, codeForbidden, fmt.Errorf("%w: it can only use %q", auth.GroupRestricted, group))
+		return
+	}
+
+	tracking, err := trackings.Update(db.FromGinContext(c), orgID, c.Param("number"), &patch)
+	if err != nil {
+		abortWithTrackingError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, tracking)
+}
+
+// DeleteTracking godoc
+//
+//	@Summary	Stops tracking a tracking number and deletes its history
+//	@ID			delete-tracking
+//	@Param		Authorization		header	string	true	"Bearer token"
+//	@Param		X-Organization-ID	header	int		false	"Organization, defaulting to the user's first"
+//	@Param		number				path	string	true	"Tracking number"
+//	@Success	204
+//	@Failure	401	{object}	errorEnvelope
+//	@Failure	403	{object}	errorEnvelope
+//	@Failure	404	{object}	errorEnvelope
+//	@Failure	500	{object}	errorEnvelope
+//	@Router		/v1/trackings/{number} [delete]
+func DeleteTracking(c *gin.Context) {
+	orgID := currentOrgID(c)
+
+	if err := checkKeyGroup(c, c.Param("number")); err != nil {
+		abortWithTrackingError(c, err)
+		return
+	}
+
+	if err := trackings.Delete(db.FromGinContext(c), orgID, c.Param("number")); err != nil {
+		abortWithTrackingError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+type archiveResp struct {
+	Archived int64 `json:"archived"`
+}
+
+// ArchiveTrackings godoc
+//
+//	@Summary	Archives tracking numbers, or every number in a group. Archived numbers keep their history, but aren't polled and are left out of lists by default
+//	@ID			archive-trackings
+//	@Accept		json
+//	@Produce	json
+//	@Param		Authorization		header		string				true	"Bearer token"
+//	@Param		X-Organization-ID	header		int					false	"Organization, defaulting to the user's first"
+//	@Param		selection			body		trackings.Selection	true	"Numbers to archive"
+//	@Success	200					{object}	archiveResp
+//	@Failure	400					{object}	errorEnvelope
+//	@Failure	401					{object}	errorEnvelope
+//	@Failure	403					{object}	errorEnvelope
+//	@Failure	404					{object}	errorEnvelope
+//	@Failure	500					{object}	errorEnvelope
+//	@Router		/v1/trackings/archive [post]
+func ArchiveTrackings(c *gin.Context) {
+	orgID := currentOrgID(c)
+
+	selection := trackings.Selection{}
+	if err := c.ShouldBindJSON(&selection); err != nil {
+		abortWithError(c, http.StatusBadRequest, codeInvalidRequest, fmt.Errorf("error parsing ArchiveTrackings: %w", err))
+		return
+	}
+
+	archived, err := trackings.Archive(db.FromGinContext(c), orgID, &selection)
+	if err != nil {
+		abortWithTrackingError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, archiveResp{
+		Archived: archived,
+	})
+}
+
+type unarchiveResp struct {
+	Unarchived int64 `json:"unarchived"`
+}
+
+// UnarchiveTrackings godoc
+//
+//	@Summary	Unarchives tracking numbers, or every number in a group, so they're polled again
+//	@ID			unarchive-trackings
+//	@Accept		json
+//	@Produce	json
+//	@Param		Authorization		header		string				true	"Bearer token"
+//	@Param		X-Organization-ID	header		int					false	"Organization, defaulting to the user's first"
+//	@Param		selection			body		trackings.Selection	true	"Numbers to unarchive"
+//	@Success	200					{object}	unarchiveResp
+//	@Failure	400					{object}	errorEnvelope
+//	@Failure	401					{object}	errorEnvelope
+//	@Failure	403					{object}	errorEnvelope
+//	@Failure	404					{object}	errorEnvelope
+//	@Failure	500					{object}	errorEnvelope
+//	@Router		/v1/trackings/unarchive [post]
+func UnarchiveTrackings(c *gin.Context) {
+	orgID := currentOrgID(c)
+
+	selection := trackings.Selection{}
+	if err := c.ShouldBindJSON(&selection); err != nil {
+		abortWithError(c, http.StatusBadRequest, codeInvalidRequest, fmt.Errorf("error parsing UnarchiveTrackings: %w", err))
+		return
+	}
+
+	unarchived, err := trackings.Unarchive(db.FromGinContext(c), orgID, &selection)
+	if err != nil {
+		abortWithTrackingError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, unarchiveResp{
+		Unarchived: unarchived,
+	})
+}
+
+type deleteResp struct {
+	Deleted int64 `json:"deleted"`
+}
+
+// DeleteTrackings godoc
+//
+//	@Summary	Stops tracking tracking numbers, or every number in a group, and deletes their history
+//	@ID			delete-trackings
+//	@Accept		json
+//	@Produce	json
+//	@Param		Authorization		header		string				true	"Bearer token"
+//	@Param		X-Organization-ID	header		int					false	"Organization, defaulting to the user's first"
+//	@Param		selection			body		trackings.Selection	true	"Numbers to stop tracking"
+//	@Success	200					{object}	deleteResp
+//	@Failure	400					{object}	errorEnvelope
+//	@Failure	401					{object}	errorEnvelope
+//	@Failure	403					{object}	errorEnvelope
+//	@Failure	404					{object}	errorEnvelope
+//	@Failure	500					{object}	errorEnvelope
+//	@Router		/v1/trackings/delete [post]
+func DeleteTrackings(c *gin.Context) {
+	orgID := currentOrgID(c)
+
+	selection := trackings.Selection{}
+	if err := c.ShouldBindJSON(&selection); err != nil {
+		abortWithError(c, http.StatusBadRequest, codeInvalidRequest, fmt.Errorf("error parsing DeleteTrackings: %w", err))
+		return
+	}
+
+	deleted, err := trackings.DeleteMany(db.FromGinContext(c), orgID, &selection)
+	if err != nil {
+		abortWithTrackingError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, deleteResp{
+		Deleted: deleted,
+	})
+}
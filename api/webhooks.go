@@ -0,0 +1,325 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+
+	"github.com/billyb2/tracking_server/db"
+	fedex "github.com/billyb2/tracking_server/tracking"
+	"github.com/billyb2/tracking_server/webhooks"
+	"github.com/gin-gonic/gin"
+)
+
+const defaultDeliveriesLimit = 50
+const maxDeliveriesLimit = 500
+
+type createWebhook struct {
+	// Deprecated: send an Authorization: Bearer header instead
+	Token string `json:"token"`
+	URL   string `json:"url"`
+	// Generated if left empty
+	Secret     string   `json:"secret,omitempty"`
+	Statuses   []string `json:"statuses,omitempty"`
+	GroupNames []string `json:"group_names,omitempty"`
+}
+
+type webhookResp struct {
+	Webhook *webhooks.Endpoint `json:"webhook,omitempty"`
+	Error   string             `json:"error,omitempty"`
+}
+
+// CreateWebhook godoc
+//
+//	@Summary	Registers a URL to POST tracking status change events to
+//	@ID			create-webhook
+//	@Accept		json
+//	@Produce	json
+//	@Param		Authorization		header		string			false	"Bearer token"
+//	@Param		X-Organization-ID	header		int				false	"Organization, defaulting to the user's first"
+//	@Param		createWebhook		body		createWebhook	true	"Webhook"
+//	@Success	201					{object}	webhookResp
+//	@Failure	400					{object}	webhookResp
+//	@Failure	401					{object}	errorResp
+//	@Failure	403					{object}	errorResp
+//	@Failure	500					{object}	webhookResp
+//	@Router		/create_webhook [post]
+func CreateWebhook(c *gin.Context) {
+	createWebhook := createWebhook{}
+	if err := c.BindJSON(&createWebhook); err != nil {
+		err = fmt.Errorf("error parsing CreateWebhook: %w", err)
+		c.JSON(http.StatusBadRequest, webhookResp{
+			Error: err.Error(),
+		})
+		return
+	}
+
+	userID, ok := authenticate(c, createWebhook.Token)
+	if !ok {
+		return
+	}
+
+	if err := checkStatuses(createWebhook.Statuses); err != nil {
+		c.JSON(http.StatusBadRequest, webhookResp{
+			Error: err.Error(),
+		})
+		return
+	}
+
+	endpoint := webhooks.Endpoint{
+		URL:        createWebhook.URL,
+		Secret:     createWebhook.Secret,
+		Statuses:   createWebhook.Statuses,
+		GroupNames: createWebhook.GroupNames,
+	}
+	err := webhooks.Create(db.FromGinContext(c), userID, currentOrgID(c), &endpoint)
+	switch {
+	case errors.Is(err, webhooks.InvalidURL):
+		c.JSON(http.StatusBadRequest, webhookResp{
+			Error: err.Error(),
+		})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, webhookResp{
+			Error: err.Error(),
+		})
+	default:
+		c.JSON(http.StatusCreated, webhookResp{
+			Webhook: &endpoint,
+		})
+	}
+}
+
+// checkStatuses makes sure every status in a filter is a canonical status
+func checkStatuses(statuses []string) error {
+	for _, status := range statuses {
+		if !slices.Contains(fedex.CanonicalStatuses, status) {
+			return fmt.Errorf("unknown status %q", status)
+		}
+	}
+
+	return nil
+}
+
+type getWebhooks struct {
+	// Deprecated: send an Authorization: Bearer header instead
+	Token string `json:"token"`
+}
+
+type webhooksResp struct {
+	Webhooks []webhooks.Endpoint `json:"webhooks"`
+	Error    string              `json:"error,omitempty"`
+}
+
+// GetWebhooks godoc
+//
+//	@Summary	Lists the user's webhooks
+//	@ID			get-webhooks
+//	@Accept		json
+//	@Produce	json
+//	@Param		Authorization		header		string		false	"Bearer token"
+//	@Param		X-Organization-ID	header		int			false	"Organization, defaulting to the user's first"
+//	@Param		getWebhooks			body		getWebhooks	true	"Token"
+//	@Success	200					{object}	webhooksResp
+//	@Failure	400					{object}	webhooksResp
+//	@Failure	401					{object}	errorResp
+//	@Failure	403					{object}	errorResp
+//	@Failure	500					{object}	webhooksResp
+//	@Router		/get_webhooks [post]
+func GetWebhooks(c *gin.Context) {
+	getWebhooks := getWebhooks{}
+	if err := c.BindJSON(&getWebhooks); err != nil {
+		err = fmt.Errorf("error parsing GetWebhooks: %w", err)
+		c.JSON(http.StatusBadRequest, webhooksResp{
+			Error: err.Error(),
+		})
+		return
+	}
+
+	userID, ok := authenticate(c, getWebhooks.Token)
+	if !ok {
+		return
+	}
+
+	endpoints, err := webhooks.List(db.FromGinContext(c), userID, currentOrgID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, webhooksResp{
+			Error: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, webhooksResp{
+		Webhooks: endpoints,
+	})
+}
+
+type deleteWebhook struct {
+	// Deprecated: send an Authorization: Bearer header instead
+	Token     string `json:"token"`
+	WebhookID int64  `json:"webhook_id"`
+}
+
+// DeleteWebhook godoc
+//
+//	@Summary	Deletes one of the user's webhooks along with its delivery logs
+//	@ID			delete-webhook
+//	@Accept		json
+//	@Produce	json
+//	@Param		Authorization		header		string			false	"Bearer token"
+//	@Param		X-Organization-ID	header		int				false	"Organization, defaulting to the user's first"
+//	@Param		deleteWebhook		body		deleteWebhook	true	"Webhook"
+//	@Success	200					{object}	errorResp
+//	@Failure	400					{object}	errorResp
+//	@Failure	401					{object}	errorResp
+//	@Failure	403					{object}	errorResp
+//	@Failure	404					{object}	errorResp
+//	@Failure	500					{object}	errorResp
+//	@Router		/delete_webhook [post]
+func DeleteWebhook(c *gin.Context) {
+	deleteWebhook := deleteWebhook{}
+	if err := c.BindJSON(&deleteWebhook); err != nil {
+		err = fmt.Errorf("error parsing DeleteWebhook: %w", err)
+		c.JSON(http.StatusBadRequest, errorResp{
+			Error: err.Error(),
+		})
+		return
+	}
+
+	userID, ok := authenticate(c, deleteWebhook.Token)
+	if !ok {
+		return
+	}
+
+	err := webhooks.Delete(db.FromGinContext(c), userID, currentOrgID(c), deleteWebhook.WebhookID)
+	switch {
+	case errors.Is(err, webhooks.NotFound):
+		c.JSON(http.StatusNotFound, errorResp{
+			Error: err.Error(),
+		})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, errorResp{
+			Error: err.Error(),
+		})
+	default:
+		c.JSON(http.StatusOK, errorResp{})
+	}
+}
+
+type getWebhookDeliveries struct {
+	// Deprecated: send an Authorization: Bearer header instead
+	Token     string `json:"token"`
+	WebhookID int64  `json:"webhook_id"`
+	Limit     int    `json:"limit,omitempty"`
+}
+
+type webhookDeliveriesResp struct {
+	Deliveries []webhooks.Delivery `json:"deliveries"`
+	Error      string              `json:"error,omitempty"`
+}
+
+// GetWebhookDeliveries godoc
+//
+//	@Summary	Gets the newest deliveries to one of the user's webhooks, including every attempt made
+//	@ID			get-webhook-deliveries
+//	@Accept		json
+//	@Produce	json
+//	@Param		Authorization			header		string					false	"Bearer token"
+//	@Param		X-Organization-ID		header		int						false	"Organization, defaulting to the user's first"
+//	@Param		getWebhookDeliveries	body		getWebhookDeliveries	true	"Webhook"
+//	@Success	200						{object}	webhookDeliveriesResp
+//	@Failure	400						{object}	webhookDeliveriesResp
+//	@Failure	401						{object}	errorResp
+//	@Failure	403						{object}	errorResp
+//	@Failure	404						{object}	webhookDeliveriesResp
+//	@Failure	500						{object}	webhookDeliveriesResp
+//	@Router		/get_webhook_deliveries [post]
+func GetWebhookDeliveries(c *gin.Context) {
+	getWebhookDeliveries := getWebhookDeliveries{}
+	if err := c.BindJSON(&getWebhookDeliveries); err != nil {
+		err = fmt.Errorf("error parsing GetWebhookDeliveries: %w", err)
+		c.JSON(http.StatusBadRequest, webhookDeliveriesResp{
+			Error: err.Error(),
+		})
+		return
+	}
+
+	userID, ok := authenticate(c, getWebhookDeliveries.Token)
+	if !ok {
+		return
+	}
+
+	limit := getWebhookDeliveries.Limit
+	if limit <= 0 {
+		limit = defaultDeliveriesLimit
+	}
+	limit = min(limit, maxDeliveriesLimit)
+
+	deliveries, err := webhooks.Deliveries(db.FromGinContext(c), userID, currentOrgID(c), getWebhookDeliveries.WebhookID, limit)
+	switch {
+	case errors.Is(err, webhooks.NotFound):
+		c.JSON(http.StatusNotFound, webhookDeliveriesResp{
+			Error: err.Error(),
+		})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, webhookDeliveriesResp{
+			Error: err.Error(),
+		})
+	default:
+		c.JSON(http.StatusOK, webhookDeliveriesResp{
+			Deliveries: deliveries,
+		})
+	}
+}
+
+type redeliverWebhook struct {
+	// Deprecated: send an Authorization: Bearer header instead
+	Token      string `json:"token"`
+	DeliveryID int64  `json:"delivery_id"`
+}
+
+// RedeliverWebhook godoc
+//
+//	@Summary	Queues a webhook delivery to be sent again right away
+//	@ID			redeliver-webhook
+//	@Accept		json
+//	@Produce	json
+//	@Param		Authorization		header		string				false	"Bearer token"
+//	@Param		X-Organization-ID	header		int					false	"Organization, defaulting to the user's first"
+//	@Param		redeliverWebhook	body		redeliverWebhook	true	"Delivery"
+//	@Success	202					{object}	errorResp
+//	@Failure	400					{object}	errorResp
+//	@Failure	401					{object}	errorResp
+//	@Failure	403					{object}	errorResp
+//	@Failure	404					{object}	errorResp
+//	@Failure	500					{object}	errorResp
+//	@Router		/redeliver_webhook [post]
+func RedeliverWebhook(c *gin.Context) {
+	redeliverWebhook := redeliverWebhook{}
+	if err := c.BindJSON(&redeliverWebhook); err != nil {
+		err = fmt.Errorf("error parsing RedeliverWebhook: %w", err)
+		c.JSON(http.StatusBadRequest, errorResp{
+			Error: err.Error(),
+		})
+		return
+	}
+
+	userID, ok := authenticate(c, redeliverWebhook.Token)
+	if !ok {
+		return
+	}
+
+	err := webhooks.Redeliver(db.FromGinContext(c), userID, currentOrgID(c), redeliverWebhook.DeliveryID)
+	switch {
+	case errors.Is(err, webhooks.DeliveryNotFound):
+		c.JSON(http.StatusNotFound, errorResp{
+			Error: err.Error(),
+		})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, errorResp{
+			Error: err.Error(),
+		})
+	default:
+		c.JSON(http.StatusAccepted, errorResp{})
+	}
+}
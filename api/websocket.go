@@ -0,0 +1,191 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/billyb2/tracking_server/history"
+	"github.com/billyb2/tracking_server/hub"
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+const wsWriteWait = 10 * time.Second
+const wsPongWait = 60 * time.Second
+const wsPingInterval = wsPongWait * 9 / 10
+const wsMaxMessageSize = 64 * 1024
+
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+	// clients authenticate with a token rather than cookies, so other sites can't connect as the user
+	CheckOrigin: func(r *http.Request) bool {
+		return true
+	},
+}
+
+// wsRequest is sent by the client to change what it's watching
+type wsRequest struct {
+	// subscribe or unsubscribe
+	Action          string   `json:"action"`
+	TrackingNumbers []string `json:"tracking_numbers,omitempty"`
+	GroupNames      []string `json:"group_names,omitempty"`
+}
+
+// wsMessage is sent by the server. Type is subscribed after each request, status_changed for each change, or error.
+type wsMessage struct {
+	Type            string          `json:"type"`
+	TrackingNumbers []string        `json:"tracking_numbers,omitempty"`
+	GroupNames      []string        `json:"group_names,omitempty"`
+	Change          *history.Change `json:"change,omitempty"`
+	Error           string          `json:"error,omitempty"`
+}
+
+// TrackingWebSocket godoc
+//
+//	@Summary		Opens a WebSocket that sends status changes for the organization's tracking numbers and groups the client subscribes to
+//	@Description	Send {"action": "subscribe" or "unsubscribe", "tracking_numbers": [...], "group_names": [...]} to change what's watched, which is answered with a subscribed message listing everything being watched. Subscribing past 1000 numbers and groups is answered with an error instead. Each change is sent as {"type": "status_changed", "change": {...}}. Clients that fall too far behind are disconnected with a 1013 close code, and should catch up with get_tracking_changes before resubscribing.
+//	@ID				tracking-websocket
+//	@Param			Authorization		header		string	false	"Bearer token"
+//	@Param			X-Organization-ID	header		int		false	"Organization, defaulting to the user's first"
+//	@Param			token				query		string	false	"Token, for clients that can't set headers"
+//	@Success		101					{object}	wsMessage
+//	@Failure		401					{object}	errorResp
+//	@Failure		403					{object}	errorResp
+//	@Failure		429					{object}	errorResp
+//	@Router			/trackings/ws [get]
+func TrackingWebSocket(c *gin.Context) {
+	userID, ok := authenticateQuery(c)
+	if !ok {
+		return
+	}
+
+	h := hub.FromGinContext(c)
+	subscriber, err := h.Subscribe(userID, currentOrgID(c))
+	if err != nil {
+		switch {
+		case errors.Is(err, hub.TooManyConnections):
+			c.JSON(http.StatusTooManyRequests, errorResp{
+				Error: err.Error(),
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, errorResp{
+				Error: err.Error(),
+			})
+		}
+		return
+	}
+	defer h.Unsubscribe(subscriber)
+
+	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		// the upgrader has already responded
+		return
+	}
+	defer conn.Close()
+
+	replies := make(chan wsMessage, 16)
+	done := make(chan struct{})
+	stop := make(chan struct{})
+	defer close(stop)
+	go readWebSocket(conn, h, subscriber, replies, done, stop)
+
+	ping := time.NewTicker(wsPingInterval)
+	defer ping.Stop()
+
+	// this is the only goroutine that writes to the connection
+	for {
+		var msg wsMessage
+		select {
+		case <-done:
+			return
+
+		case change, ok := <-subscriber.C:
+			if !ok {
+				if h.Slow(subscriber) {
+					conn.WriteControl(
+						websocket.CloseMessage,
+						websocket.FormatCloseMessage(websocket.CloseTryAgainLater, "too slow to keep up with updates"),
+						time.Now().Add(wsWriteWait),
+					)
+				}
+				return
+			}
+			msg = wsMessage{Type: "status_changed", Change: &change}
+
+		case msg = <-replies:
+
+		case <-ping.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsWriteWait)); err != nil {
+				return
+			}
+			continue
+		}
+
+		conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+		if err := conn.WriteJSON(msg); err != nil {
+			return
+		}
+	}
+}
+
+// readWebSocket handles subscribe and unsubscribe requests until the connection closes or the writer stops
+func readWebSocket(conn *websocket.Conn, h *hub.Hub, subscriber *hub.Subscriber, replies chan<- wsMessage, done chan<- struct{}, stop <-chan struct{}) {
+	defer close(done)
+
+	conn.SetReadLimit(wsMaxMessageSize)
+	conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	})
+
+	reply := func(msg wsMessage) bool {
+		select {
+		case replies <- msg:
+			return true
+		case <-stop:
+			return false
+		}
+	}
+
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		req := wsRequest{}
+		if err := json.Unmarshal(data, &req); err != nil {
+			if !reply(wsMessage{Type: "error", Error: fmt.Sprintf("invalid message: %s", err)}) {
+				return
+			}
+			continue
+		}
+
+		var numbers, groups []string
+		switch req.Action {
+		case "subscribe":
+			numbers, groups, err = h.Watch(subscriber, req.TrackingNumbers, req.GroupNames)
+			if err != nil {
+				if !reply(wsMessage{Type: "error", Error: err.Error()}) {
+					return
+				}
+				continue
+			}
+		case "unsubscribe":
+			numbers, groups = h.Unwatch(subscriber, req.TrackingNumbers, req.GroupNames)
+		default:
+			if !reply(wsMessage{Type: "error", Error: fmt.Sprintf("unknown action %q, expected subscribe or unsubscribe", req.Action)}) {
+				return
+			}
+			continue
+		}
+
+		if !reply(wsMessage{Type: "subscribed", TrackingNumbers: numbers, GroupNames: groups}) {
+			return
+		}
+	}
+}
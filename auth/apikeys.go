@@ -0,0 +1,258 @@
+package auth
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// APIKeyPrefix starts every API key, which is how they're told apart from tokens
+const APIKeyPrefix = "tsk_"
+
+const apiKeyContextKey = "apiKeyContextKey"
+
+// How many characters of the key after the prefix are kept to tell keys apart
+const apiKeyPrefixLength = 6
+
+const maxAPIKeyNameLength = 100
+
+// Scopes limit what an API key can do
+const (
+	// Reading tracking numbers, groups, history and digests
+	ScopeRead = "read"
+	// Starting, changing, archiving and stopping tracking, and managing groups
+	ScopeWriteTrackings = "write:trackings"
+	// Creating, listing and deleting webhooks and chat webhooks
+	ScopeManageWebhooks = "manage:webhooks"
+)
+
+var Scopes = []string{ScopeRead, ScopeWriteTrackings, ScopeManageWebhooks}
+
+var InvalidNewAPIKey error = fmt.Errorf("invalid api key")
+var APIKeyNotFound error = fmt.Errorf("api key not found")
+var ExpiredAPIKey error = fmt.Errorf("%w: the api key expired", InvalidToken)
+
+// Forbidden is wrapped by errors for users or API keys that aren't allowed to do something
+var Forbidden error = fmt.Errorf("forbidden")
+var APIKeysNotAllowed error = fmt.Errorf("%w: api keys can't be used here, log in instead", Forbidden)
+var MissingScope error = fmt.Errorf("%w: the api key doesn't have the scope needed", Forbidden)
+var GroupRestricted error = fmt.Errorf("%w: the api key is restricted to a group", Forbidden)
+
+type APIKey struct {
+	ID     int64 `json:"id"`
+	UserID int32 `json:"-"`
+	// The organization the key acts in
+	OrgID int64  `json:"org_id"`
+	Name  string `json:"name"`
+	// The start of the key, to tell keys apart
+	Prefix string   `json:"prefix"`
+	Scopes []string `json:"scopes"`
+	// Restricts the key to the numbers in this group
+	GroupID    *int64     `json:"group_id,omitempty"`
+	GroupName  *string    `json:"group_name,omitempty"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+const apiKeyColumns = "k.id, k.user_id, k.org_id, k.name, k.prefix, k.scopes, k.group_id, g.name, k.expires_at, k.last_used_at, k.created_at"
+
+// apiKeyTables is what apiKeyColumns are selected from
+const apiKeyTables = "api_keys k left join tracking_groups g on g.id = k.group_id"
+
+func scanAPIKey(row interface{ Scan(...any) error }, key *APIKey, extra ...any) error {
+	var scopesJSON string
+	if err := row.Scan(append([]any{&key.ID, &key.UserID, &key.OrgID, &key.Name, &key.Prefix, &scopesJSON, &key.GroupID, &key.GroupName, &key.ExpiresAt, &key.LastUsedAt, &key.CreatedAt}, extra...)...); err != nil {
+		return err
+	}
+
+	return json.Unmarshal([]byte(scopesJSON), &key.Scopes)
+}
+
+type NewAPIKey struct {
+	Name string `json:"name"`
+	// read, write:trackings and/or manage:webhooks
+	Scopes []string `json:"scopes"`
+	// Restricts the key to the numbers in this group
+	GroupID   int64      `json:"group_id,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// CreateAPIKey makes a new API key that acts in the organization, returning it along with the key itself, which is only
+// ever shown this once
+func CreateAPIKey(db *sql.DB, userID int32, orgID int64, newKey *NewAPIKey) (*APIKey, string, error) {
+	name := strings.TrimSpace(newKey.Name)
+	if name == "" || len(name) > maxAPIKeyNameLength {
+		return nil, "", fmt.Errorf("%w: names can't be empty or longer than %d characters", InvalidNewAPIKey, maxAPIKeyNameLength)
+	}
+	if len(newKey.Scopes) == 0 {
+		return nil, "", fmt.Errorf("%w: pick at least one of the scopes %s", InvalidNewAPIKey, strings.Join(Scopes, ", "))
+	}
+	scopes := []string{}
+	for _, scope := range newKey.Scopes {
+		if !slices.Contains(Scopes, scope) {
+			return nil, "", fmt.Errorf("%w: unknown scope %q", InvalidNewAPIKey, scope)
+		}
+		if !slices.Contains(scopes, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+	if newKey.ExpiresAt != nil && !newKey.ExpiresAt.After(time.Now()) {
+		return nil, "", fmt.Errorf("%w: expires_at has to be in the future", InvalidNewAPIKey)
+	}
+
+	var groupID *int64
+	if newKey.GroupID != 0 {
+		if err := db.QueryRow("select id from tracking_groups where id = ? and org_id = ?", newKey.GroupID, orgID).Scan(&groupID); err != nil {
+			switch {
+			case errors.Is(err, sql.ErrNoRows):
+				return nil, "", fmt.Errorf("%w: no group with id %d", InvalidNewAPIKey, newKey.GroupID)
+			default:
+				return nil, "", err
+			}
+		}
+	}
+
+	var expiresAt *string
+	if newKey.ExpiresAt != nil {
+		expires := newKey.ExpiresAt.UTC().Format(time.DateTime)
+		expiresAt = &expires
+	}
+
+	scopesJSON, err := json.Marshal(scopes)
+	if err != nil {
+		return nil, "", err
+	}
+
+	secret, err := newToken(APIKeyPrefix)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var id int64
+	if err := db.QueryRow(
+		"insert into api_keys (user_id, org_id, name, key_hash, prefix, scopes, group_id, expires_at, created_at) values (?, ?, ?, ?, ?, ?, ?, ?, datetime('now')) returning id",
+		userID, orgID, name, hashToken(secret), secret[:len(APIKeyPrefix)+apiKeyPrefixLength], string(scopesJSON), groupID, expiresAt,
+	).Scan(&id); err != nil {
+		return nil, "", err
+	}
+
+	key := APIKey{}
+	if err := scanAPIKey(db.QueryRow("select "+apiKeyColumns+" from "+apiKeyTables+" where k.id = ?", id), &key); err != nil {
+		return nil, "", err
+	}
+
+	return &key, secret, nil
+}
+
+func ListAPIKeys(db *sql.DB, userID int32) ([]APIKey, error) {
+	rows, err := db.Query("select "+apiKeyColumns+" from "+apiKeyTables+" where k.user_id = ? order by k.id", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []APIKey{}
+	for rows.Next() {
+		key := APIKey{}
+		if err := scanAPIKey(rows, &key); err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	return keys, rows.Err()
+}
+
+func RevokeAPIKey(db *sql.DB, userID int32, id int64) error {
+	result, err := db.Exec("delete from api_keys where id = ? and user_id = ?", id, userID)
+	if err != nil {
+		return err
+	}
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return APIKeyNotFound
+	}
+
+	return nil
+}
+
+// RevokeOrgAPIKeys deletes the API keys the user made in the organization
+func RevokeOrgAPIKeys(tx *sql.Tx, userID int32, orgID int64) error {
+	_, err := tx.Exec("delete from api_keys where user_id = ? and org_id = ?", userID, orgID)
+	return err
+}
+
+// RevokeUserAPIKeys deletes every API key the user made, in any organization
+func RevokeUserAPIKeys(tx *sql.Tx, userID int32) error {
+	_, err := tx.Exec("delete from api_keys where user_id = ?", userID)
+	return err
+}
+
+// APIKeyFromToken looks up the API key a secret belongs to
+func APIKeyFromToken(db *sql.DB, secret string) (*APIKey, error) {
+	key := APIKey{}
+	var expired, stale bool
+	row := db.QueryRow(
+		`select `+apiKeyColumns+`, coalesce(unixepoch(k.expires_at) <= unixepoch('now'), false),
+			k.last_used_at is null or unixepoch(k.last_used_at) <= unixepoch('now') - ?
+		from `+apiKeyTables+` where k.key_hash = ?`,
+		int(lastUsedResolution.Seconds()), hashToken(secret),
+	)
+	if err := scanAPIKey(row, &key, &expired, &stale); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, InvalidToken
+		default:
+			return nil, err
+		}
+	}
+	if expired {
+		return nil, ExpiredAPIKey
+	}
+
+	if stale {
+		if _, err := db.Exec("update api_keys set last_used_at = datetime('now') where id = ?", key.ID); err != nil {
+			fmt.Fprintln(os.Stderr, "error updating when an api key was last used", err)
+		}
+	}
+
+	return &key, nil
+}
+
+// Allows checks that the key can be used for something needing the scope. Keys restricted to a group can only be used
+// where groupScoped says the restriction is enforced.
+func (k *APIKey) Allows(scope string, groupScoped bool) error {
+	switch {
+	case scope == "":
+		return APIKeysNotAllowed
+	case !slices.Contains(k.Scopes, scope):
+		return fmt.Errorf("%w: it needs %s", MissingScope, scope)
+	case k.GroupID != nil && !groupScoped:
+		return fmt.Errorf("%w, which can't be enforced here", GroupRestricted)
+	}
+
+	return nil
+}
+
+func APIKeyWithGinContext(c *gin.Context, key *APIKey) {
+	c.Set(apiKeyContextKey, key)
+}
+
+// APIKeyFromGinContext returns the API key the request was authenticated with, or nil if it wasn't one
+func APIKeyFromGinContext(c *gin.Context) *APIKey {
+	key, _ := c.Get(apiKeyContextKey)
+	apiKey, _ := key.(*APIKey)
+
+	return apiKey
+}
@@ -1,89 +1,200 @@
 package auth
 
 import (
-	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
 	"database/sql"
+	"encoding/base64"
 	"errors"
 	"fmt"
+	"os"
+	"time"
 
 	"github.com/billyb2/tracking_server/db"
 	"github.com/gin-gonic/gin"
 	"github.com/oklog/ulid/v2"
-	"golang.org/x/crypto/argon2"
 )
 
 var BadUsernameOrPassword error = fmt.Errorf("incorrect username or password")
 
-func CheckUserCreds(c *gin.Context, username, password string) (string, error) {
+func CheckUserCreds(c *gin.Context, username, password string) (*Tokens, error) {
 	db := db.FromGinContext(c)
 	if db == nil {
-		return "", fmt.Errorf("db is nil")
+		return nil, fmt.Errorf("db is nil")
 	}
 
-	row := db.QueryRow("select id, password_hash, salt from users where username = ?", username)
+	row := db.QueryRow("select id, password_hash from users where username = ?", username)
 	if row.Err() != nil {
-		return "", row.Err()
+		return nil, row.Err()
 	}
 
 	var userID int32
-	var passwordHash []byte
-	var passwordSalt []byte
-	if err := row.Scan(&userID, &passwordHash, &passwordSalt); err != nil {
+	var passwordHash string
+	if err := row.Scan(&userID, &passwordHash); err != nil {
 		switch {
 		case errors.Is(err, sql.ErrNoRows):
-			return "", BadUsernameOrPassword
+			return nil, BadUsernameOrPassword
 		default:
-			return "", err
+			return nil, err
 		}
 	}
 
-	passwordBytes := []byte(password)
-	attemptedPasswordHash := argon2.IDKey(passwordBytes, passwordSalt, 1, 47104, 1, 32)
-	if !bytes.Equal(passwordHash, attemptedPasswordHash) {
-		return "", BadUsernameOrPassword
+	matches, needsRehash, err := checkPassword(passwordHash, password)
+	if err != nil {
+		return nil, err
+	}
+	if !matches {
+		return nil, BadUsernameOrPassword
 	}
 
 	tx, err := db.Begin()
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	defer tx.Commit()
-	token, err := CreateToken(tx, userID)
+	// the password is only known here, so this is when hashes made with older parameters can be remade with the
+	// current ones. Logging in shouldn't fail because of it though
+	if needsRehash {
+		if err := updatePasswordHash(tx, userID, password); err != nil {
+			fmt.Fprintln(os.Stderr, "error rehashing password", err)
+		}
+	}
+	tokens, err := CreateToken(tx, userID)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	if err := tx.Commit(); err != nil {
-		return "", err
+		return nil, err
 	}
 
-	return token, nil
+	return tokens, nil
 }
 
-func CreateToken(tx *sql.Tx, userID int32) (string, error) {
-	token := ulid.Make()
-	_, err := tx.Exec("insert into tokens (user_id, token) values (?, ?)", userID, token.String())
+// Prefixes make tokens easy to recognize, like for secret scanning
+const AccessTokenPrefix = "tsa_"
+const RefreshTokenPrefix = "tsr_"
+
+// Access tokens are short lived, and clients swap their refresh token for a new pair before they expire
+const AccessTokenTTL = 15 * time.Minute
+const RefreshTokenTTL = 30 * 24 * time.Hour
+
+// last_used_at is only updated this often, so that every request doesn't write to the db
+const lastUsedResolution = time.Minute
+
+// Tokens are what a client gets for logging in
+type Tokens struct {
+	AccessToken  string
+	RefreshToken string
+	// When the access token expires
+	ExpiresAt time.Time
+}
+
+// CreateToken starts a new session for the user
+func CreateToken(tx *sql.Tx, userID int32) (*Tokens, error) {
+	return createTokens(tx, userID, ulid.Make().String())
+}
+
+func createTokens(tx *sql.Tx, userID int32, sessionID string) (*Tokens, error) {
+	accessToken, err := newToken(AccessTokenPrefix)
+	if err != nil {
+		return nil, err
+	}
+	refreshToken, err := newToken(RefreshTokenPrefix)
 	if err != nil {
+		return nil, err
+	}
+	tokens := Tokens{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+	}
+
+	if err := tx.QueryRow(
+		"insert into tokens (user_id, token_hash, session_id, issued_at, expires_at) values (?, ?, ?, datetime('now'), datetime('now', ?)) returning expires_at",
+		userID, hashToken(tokens.AccessToken), sessionID, fromNow(AccessTokenTTL),
+	).Scan(&tokens.ExpiresAt); err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.Exec(
+		"insert into refresh_tokens (user_id, token_hash, session_id, issued_at, expires_at) values (?, ?, ?, datetime('now'), datetime('now', ?))",
+		userID, hashToken(tokens.RefreshToken), sessionID, fromNow(RefreshTokenTTL),
+	); err != nil {
+		return nil, err
+	}
+
+	return &tokens, nil
+}
+
+// newToken makes a token with 256 random bits, after a prefix that makes it easy to recognize, like for secret scanning
+func newToken(prefix string) (string, error) {
+	token := make([]byte, 32)
+	if _, err := rand.Read(token); err != nil {
 		return "", err
 	}
 
-	return token.String(), nil
+	return prefix + base64.RawURLEncoding.EncodeToString(token), nil
+}
+
+// hashToken is what tokens are stored and looked up as. They're random enough that they don't need a salt or a slow hash.
+func hashToken(token string) []byte {
+	hash := sha256.Sum256([]byte(token))
+	return hash[:]
+}
+
+// HashPlaintextTokens hashes the tokens that were stored as plaintext before tokens were hashed
+func HashPlaintextTokens(db *sql.DB) error {
+	for _, table := range []string{"tokens", "refresh_tokens"} {
+		rows, err := db.Query("select token_hash from " + table + " where typeof(token_hash) = 'text'")
+		if err != nil {
+			return err
+		}
+		plaintextTokens := []string{}
+		for rows.Next() {
+			var token string
+			if err := rows.Scan(&token); err != nil {
+				rows.Close()
+				return err
+			}
+			plaintextTokens = append(plaintextTokens, token)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		for _, token := range plaintextTokens {
+			if _, err := db.Exec("update "+table+" set token_hash = ? where token_hash = ?", hashToken(token), token); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// fromNow is the SQLite datetime modifier for d from now
+func fromNow(d time.Duration) string {
+	return fmt.Sprintf("+%d seconds", int(d.Seconds()))
 }
 
 var InvalidToken error = fmt.Errorf("invalid token")
+var ExpiredToken error = fmt.Errorf("%w: it expired, use the refresh token to get a new one", InvalidToken)
 
 func UserIDFromToken(c *gin.Context, token string) (int32, error) {
 	db := db.FromGinContext(c)
 	if db == nil {
 		return 0, fmt.Errorf("db is nil")
 	}
-	row := db.QueryRow("select user_id from tokens where token = ?", token)
-	if row == nil {
-		return 0, InvalidToken
-	}
+	row := db.QueryRow(
+		`select user_id, unixepoch(expires_at) <= unixepoch('now'), last_used_at is null or unixepoch(last_used_at) <= unixepoch('now') - ?
+		from tokens where token_hash = ?`,
+		int(lastUsedResolution.Seconds()), hashToken(token),
+	)
 
 	var userID int32
-	if err := row.Scan(&userID); err != nil {
+	var expired, stale bool
+	if err := row.Scan(&userID, &expired, &stale); err != nil {
 		switch {
 		case errors.Is(err, sql.ErrNoRows):
 			return 0, InvalidToken
@@ -91,6 +202,33 @@ func UserIDFromToken(c *gin.Context, token string) (int32, error) {
 			return 0, err
 		}
 	}
+	if expired {
+		return 0, ExpiredToken
+	}
+
+	if stale {
+		if _, err := db.Exec("update tokens set last_used_at = datetime('now') where token_hash = ?", hashToken(token)); err != nil {
+			fmt.Fprintln(os.Stderr, "error updating when a token was last used", err)
+		}
+	}
 
 	return userID, nil
 }
+
+const userIDContextKey = "userIDContextKey"
+
+// UserIDWithGinContext puts the user the request was authenticated as on the context
+func UserIDWithGinContext(c *gin.Context, userID int32) {
+	c.Set(userIDContextKey, userID)
+}
+
+// UserIDFromGinContext returns the user the request was authenticated as, and false if it wasn't
+func UserIDFromGinContext(c *gin.Context) (int32, bool) {
+	userID, ok := c.Get(userIDContextKey)
+	if !ok {
+		return 0, false
+	}
+	id, ok := userID.(int32)
+
+	return id, ok
+}
@@ -0,0 +1,366 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// PasswordResetPrefix starts every password reset token
+const PasswordResetPrefix = "tsp_"
+
+// PasswordResetTTL is how long password reset tokens work for
+const PasswordResetTTL = time.Hour
+
+const minPasswordLength = 8
+
+var InvalidPassword error = fmt.Errorf("passwords have to be at least %d characters", minPasswordLength)
+var WrongPassword error = fmt.Errorf("the current password is wrong")
+var InvalidResetToken error = fmt.Errorf("invalid or expired password reset token")
+
+// Argon2Params are the argon2id parameters password hashes are made with
+type Argon2Params struct {
+	// In KiB
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+	KeyLength   uint32
+}
+
+// DefaultArgon2Params are the parameters every hash was made with before they were configurable
+var DefaultArgon2Params = Argon2Params{
+	Memory:      47104,
+	Iterations:  1,
+	Parallelism: 1,
+	KeyLength:   32,
+}
+
+const saltLength = 32
+
+// argon2Params are what new hashes are made with, and older hashes are remade with when their user logs in
+var argon2Params = DefaultArgon2Params
+
+var invalidPasswordHash error = fmt.Errorf("invalid password hash")
+
+// Argon2ParamsFromEnv reads ARGON2_MEMORY_KIB, ARGON2_ITERATIONS and ARGON2_PARALLELISM, defaulting to
+// DefaultArgon2Params for the ones that aren't set
+func Argon2ParamsFromEnv() (*Argon2Params, error) {
+	params := DefaultArgon2Params
+	vars := []struct {
+		name  string
+		value any
+		min   uint64
+		max   uint64
+	}{
+		{"ARGON2_MEMORY_KIB", &params.Memory, 8, math.MaxUint32},
+		{"ARGON2_ITERATIONS", &params.Iterations, 1, math.MaxUint32},
+		{"ARGON2_PARALLELISM", &params.Parallelism, 1, math.MaxUint8},
+	}
+	for _, v := range vars {
+		env := os.Getenv(v.name)
+		if env == "" {
+			continue
+		}
+
+		n, err := strconv.ParseUint(env, 10, 64)
+		if err != nil || n < v.min || n > v.max {
+			return nil, fmt.Errorf("invalid %s: %q", v.name, env)
+		}
+		switch value := v.value.(type) {
+		case *uint32:
+			*value = uint32(n)
+		case *uint8:
+			*value = uint8(n)
+		}
+	}
+	if params.Memory < 8*uint32(params.Parallelism) {
+		return nil, fmt.Errorf("ARGON2_MEMORY_KIB has to be at least 8 times ARGON2_PARALLELISM")
+	}
+
+	return &params, nil
+}
+
+// SetArgon2Params changes the parameters new password hashes are made with
+func SetArgon2Params(params *Argon2Params) {
+	argon2Params = *params
+}
+
+// HashPassword hashes a password with the current parameters into a PHC string like
+// $argon2id$v=19$m=47104,t=1,p=1$<salt>$<hash>, which has everything needed to check a password against it later
+func HashPassword(password string) (string, error) {
+	salt := make([]byte, saltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	return encodePasswordHash(&argon2Params, salt, argon2.IDKey([]byte(password), salt, argon2Params.Iterations, argon2Params.Memory, argon2Params.Parallelism, argon2Params.KeyLength)), nil
+}
+
+func encodePasswordHash(params *Argon2Params, salt, hash []byte) string {
+	return fmt.Sprintf(
+		"$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, params.Memory, params.Iterations, params.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(hash),
+	)
+}
+
+func decodePasswordHash(encoded string) (*Argon2Params, []byte, []byte, error) {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[0] != "" || parts[1] != "argon2id" || parts[2] != fmt.Sprintf("v=%d", argon2.Version) {
+		return nil, nil, nil, invalidPasswordHash
+	}
+
+	params := Argon2Params{}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Iterations, &params.Parallelism); err != nil {
+		return nil, nil, nil, invalidPasswordHash
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return nil, nil, nil, invalidPasswordHash
+	}
+	hash, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(hash) == 0 {
+		return nil, nil, nil, invalidPasswordHash
+	}
+	params.KeyLength = uint32(len(hash))
+
+	return &params, salt, hash, nil
+}
+
+// checkPassword returns whether the password matches the hash, and whether the hash should be remade because it was
+// made with other parameters than the current ones
+func checkPassword(passwordHash, password string) (bool, bool, error) {
+	params, salt, hash, err := decodePasswordHash(passwordHash)
+	if err != nil {
+		return false, false, err
+	}
+
+	attempt := argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory, params.Parallelism, params.KeyLength)
+	if subtle.ConstantTimeCompare(hash, attempt) != 1 {
+		return false, false, nil
+	}
+
+	return true, *params != argon2Params || len(salt) != saltLength, nil
+}
+
+// UpgradeLegacyPasswordHashes turns the bare hashes and salts from before hashes were PHC strings into PHC strings. They
+// were all made with DefaultArgon2Params.
+func UpgradeLegacyPasswordHashes(db *sql.DB) error {
+	rows, err := db.Query("select id, password_hash, legacy_salt from users where legacy_salt is not null")
+	if err != nil {
+		return err
+	}
+	type legacyHash struct {
+		userID int32
+		hash   []byte
+		salt   []byte
+	}
+	legacyHashes := []legacyHash{}
+	for rows.Next() {
+		legacy := legacyHash{}
+		if err := rows.Scan(&legacy.userID, &legacy.hash, &legacy.salt); err != nil {
+			rows.Close()
+			return err
+		}
+		legacyHashes = append(legacyHashes, legacy)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, legacy := range legacyHashes {
+		if _, err := db.Exec(
+			"update users set password_hash = ?, legacy_salt = null where id = ?",
+			encodePasswordHash(&DefaultArgon2Params, legacy.salt, legacy.hash), legacy.userID,
+		); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ValidatePassword checks that a new password is long enough
+func ValidatePassword(password string) error {
+	if len(password) < minPasswordLength {
+		return InvalidPassword
+	}
+
+	return nil
+}
+
+func setPassword(tx *sql.Tx, userID int32, password string) error {
+	if err := ValidatePassword(password); err != nil {
+		return err
+	}
+
+	return updatePasswordHash(tx, userID, password)
+}
+
+// updatePasswordHash doesn't check the password's length, so that users from before there was a minimum can still have
+// their hash remade
+func updatePasswordHash(tx *sql.Tx, userID int32, password string) error {
+	passwordHash, err := HashPassword(password)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec("update users set password_hash = ? where id = ?", passwordHash, userID)
+	return err
+}
+
+// ChangePassword sets a new password after checking the current one, and logs out every session but the one the token
+// belongs to
+func ChangePassword(db *sql.DB, userID int32, token, currentPassword, newPassword string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var passwordHash string
+	if err := tx.QueryRow("select password_hash from users where id = ?", userID).Scan(&passwordHash); err != nil {
+		return err
+	}
+	matches, _, err := checkPassword(passwordHash, currentPassword)
+	if err != nil {
+		return err
+	}
+	if !matches {
+		return WrongPassword
+	}
+	if err := setPassword(tx, userID, newPassword); err != nil {
+		return err
+	}
+
+	var sessionID *string
+	if err := tx.QueryRow("select session_id from tokens where token_hash = ?", hashToken(token)).Scan(&sessionID); err != nil {
+		return err
+	}
+	// tokens from before sessions don't have one, so only the token itself is kept
+	if sessionID == nil {
+		if _, err := tx.Exec("delete from tokens where user_id = ? and token_hash != ?", userID, hashToken(token)); err != nil {
+			return err
+		}
+		if _, err := tx.Exec("delete from refresh_tokens where user_id = ?", userID); err != nil {
+			return err
+		}
+	} else {
+		if _, err := tx.Exec("delete from tokens where user_id = ? and session_id is not ?", userID, *sessionID); err != nil {
+			return err
+		}
+		if _, err := tx.Exec("delete from refresh_tokens where user_id = ? and session_id is not ?", userID, *sessionID); err != nil {
+			return err
+		}
+	}
+	// reset tokens sent before the change shouldn't be able to undo it
+	if _, err := tx.Exec("delete from password_resets where user_id = ?", userID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// PasswordReset is a reset token to email to a user who forgot their password
+type PasswordReset struct {
+	Username string
+	Email    string
+	Token    string
+}
+
+// StartPasswordReset makes a reset token for each user whose verified email is the one given, replacing any they had
+// before. Unknown emails get no tokens rather than an error, so that nobody can find out who has an account.
+func StartPasswordReset(db *sql.DB, email string) ([]PasswordReset, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query("select id, username, email from users where lower(email) = lower(?) and email_verified_at is not null", email)
+	if err != nil {
+		return nil, err
+	}
+	userIDs := []int32{}
+	resets := []PasswordReset{}
+	for rows.Next() {
+		var userID int32
+		reset := PasswordReset{}
+		if err := rows.Scan(&userID, &reset.Username, &reset.Email); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		userIDs = append(userIDs, userID)
+		resets = append(resets, reset)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i, userID := range userIDs {
+		if _, err := tx.Exec("delete from password_resets where user_id = ?", userID); err != nil {
+			return nil, err
+		}
+
+		if resets[i].Token, err = newToken(PasswordResetPrefix); err != nil {
+			return nil, err
+		}
+		if _, err := tx.Exec(
+			"insert into password_resets (token_hash, user_id, expires_at, created_at) values (?, ?, datetime('now', ?), datetime('now'))",
+			hashToken(resets[i].Token), userID, fromNow(PasswordResetTTL),
+		); err != nil {
+			return nil, err
+		}
+	}
+
+	return resets, tx.Commit()
+}
+
+// ResetPassword sets a new password with a reset token, which only works once, and logs the user out everywhere. Their
+// API keys are deleted too, since whoever had the account might have made some.
+func ResetPassword(db *sql.DB, token, newPassword string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var userID int32
+	if err := tx.QueryRow(
+		"select user_id from password_resets where token_hash = ? and used_at is null and unixepoch(expires_at) > unixepoch('now')",
+		hashToken(token),
+	).Scan(&userID); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return InvalidResetToken
+		default:
+			return err
+		}
+	}
+
+	if err := setPassword(tx, userID, newPassword); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("update password_resets set used_at = datetime('now') where token_hash = ?", hashToken(token)); err != nil {
+		return err
+	}
+	if err := RevokeUserTokens(tx, userID); err != nil {
+		return err
+	}
+	if err := RevokeUserAPIKeys(tx, userID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
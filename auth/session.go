@@ -0,0 +1,139 @@
+package auth
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+var ReusedRefreshToken error = fmt.Errorf("%w: the refresh token was already used, so the session was logged out", InvalidToken)
+
+// Refresh swaps a refresh token for a new access and refresh token in the same session. Each refresh token can only be
+// used once, and using one again logs the session out, since it means someone else has a copy of it.
+func Refresh(db *sql.DB, refreshToken string) (*Tokens, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var userID int32
+	var sessionID string
+	var expired, used bool
+	if err := tx.QueryRow(
+		"select user_id, session_id, unixepoch(expires_at) <= unixepoch('now'), used_at is not null from refresh_tokens where token_hash = ?",
+		hashToken(refreshToken),
+	).Scan(&userID, &sessionID, &expired, &used); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, InvalidToken
+		default:
+			return nil, err
+		}
+	}
+
+	switch {
+	case used:
+		if err := revokeSession(tx, sessionID); err != nil {
+			return nil, err
+		}
+		if err := tx.Commit(); err != nil {
+			return nil, err
+		}
+		return nil, ReusedRefreshToken
+	case expired:
+		return nil, ExpiredToken
+	}
+
+	if _, err := tx.Exec("update refresh_tokens set used_at = datetime('now') where token_hash = ?", hashToken(refreshToken)); err != nil {
+		return nil, err
+	}
+
+	tokens, err := createTokens(tx, userID, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	return tokens, tx.Commit()
+}
+
+// Logout revokes the session the access token belongs to
+func Logout(db *sql.DB, token string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var sessionID *string
+	if err := tx.QueryRow("select session_id from tokens where token_hash = ?", hashToken(token)).Scan(&sessionID); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return InvalidToken
+		default:
+			return err
+		}
+	}
+
+	// tokens from before sessions don't have one
+	if sessionID == nil {
+		if _, err := tx.Exec("delete from tokens where token_hash = ?", hashToken(token)); err != nil {
+			return err
+		}
+	} else if err := revokeSession(tx, *sessionID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// LogoutEverywhere revokes every one of the user's sessions
+func LogoutEverywhere(db *sql.DB, userID int32) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := RevokeUserTokens(tx, userID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// RevokeUserTokens deletes every one of the user's tokens and refresh tokens, but not their API keys
+func RevokeUserTokens(tx *sql.Tx, userID int32) error {
+	if _, err := tx.Exec("delete from tokens where user_id = ?", userID); err != nil {
+		return err
+	}
+	_, err := tx.Exec("delete from refresh_tokens where user_id = ?", userID)
+	return err
+}
+
+func revokeSession(tx *sql.Tx, sessionID string) error {
+	if _, err := tx.Exec("delete from tokens where session_id = ?", sessionID); err != nil {
+		return err
+	}
+	_, err := tx.Exec("delete from refresh_tokens where session_id = ?", sessionID)
+	return err
+}
+
+// Run deletes expired tokens and password reset tokens forever, once an hour
+func Run(db *sql.DB) {
+	for {
+		if _, err := db.Exec("delete from tokens where unixepoch(expires_at) < unixepoch('now')"); err != nil {
+			fmt.Fprintln(os.Stderr, "error deleting expired tokens", err)
+		}
+		if _, err := db.Exec("delete from refresh_tokens where unixepoch(expires_at) < unixepoch('now')"); err != nil {
+			fmt.Fprintln(os.Stderr, "error deleting expired refresh tokens", err)
+		}
+		if _, err := db.Exec("delete from password_resets where unixepoch(expires_at) < unixepoch('now')"); err != nil {
+			fmt.Fprintln(os.Stderr, "error deleting expired password reset tokens", err)
+		}
+
+		time.Sleep(time.Hour)
+	}
+}
@@ -0,0 +1,20 @@
+-- migrate:up
+alter table tracking add column canonical_status text;
+
+create table tracking_status_history (
+  id integer primary key not null,
+  tracking_id int not null,
+  old_status text,
+  new_status text,
+  canonical_status text not null,
+  observed_at datetime not null,
+  source text not null,
+  foreign key(tracking_id) references tracking(id)
+);
+
+create index tracking_status_history_tracking_id on tracking_status_history(tracking_id);
+
+-- migrate:down
+drop index tracking_status_history_tracking_id;
+drop table tracking_status_history;
+alter table tracking drop column canonical_status;
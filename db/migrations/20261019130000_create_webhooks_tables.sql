@@ -0,0 +1,49 @@
+-- migrate:up
+create table webhook_endpoints (
+  id integer primary key not null,
+  user_id int not null,
+  url text not null,
+  secret text not null,
+  -- json arrays of canonical statuses and group names to send events for, null means every one
+  statuses text,
+  group_names text,
+  created_at datetime not null,
+  foreign key(user_id) references users(id)
+);
+
+create index webhook_endpoints_user_id on webhook_endpoints(user_id);
+
+create table webhook_deliveries (
+  id integer primary key not null,
+  endpoint_id int not null,
+  event_id text not null,
+  payload text not null,
+  status text not null,
+  attempts int not null default 0,
+  next_attempt_at datetime,
+  last_status_code int,
+  last_error text,
+  created_at datetime not null,
+  delivered_at datetime,
+  foreign key(endpoint_id) references webhook_endpoints(id)
+);
+
+create index webhook_deliveries_endpoint_id on webhook_deliveries(endpoint_id);
+create index webhook_deliveries_due on webhook_deliveries(status, next_attempt_at);
+
+create table webhook_delivery_attempts (
+  id integer primary key not null,
+  delivery_id int not null,
+  attempted_at datetime not null,
+  status_code int,
+  error text,
+  duration_ms int not null,
+  foreign key(delivery_id) references webhook_deliveries(id)
+);
+
+create index webhook_delivery_attempts_delivery_id on webhook_delivery_attempts(delivery_id);
+
+-- migrate:down
+drop table webhook_delivery_attempts;
+drop table webhook_deliveries;
+drop table webhook_endpoints;
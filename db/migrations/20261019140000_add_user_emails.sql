@@ -0,0 +1,28 @@
+-- migrate:up
+alter table users add column email text;
+alter table users add column email_verified_at datetime;
+
+create table email_verifications (
+  token text primary key not null,
+  user_id int not null,
+  email text not null,
+  expires_at datetime not null,
+  foreign key(user_id) references users(id)
+);
+
+create table group_email_recipients (
+  id integer primary key not null,
+  user_id int not null,
+  group_name text not null,
+  email text not null,
+  foreign key(user_id) references users(id)
+);
+
+create unique index group_email_recipients_unique on group_email_recipients(user_id, group_name, email);
+
+-- migrate:down
+drop index group_email_recipients_unique;
+drop table group_email_recipients;
+drop table email_verifications;
+alter table users drop column email_verified_at;
+alter table users drop column email;
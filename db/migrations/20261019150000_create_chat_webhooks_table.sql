@@ -0,0 +1,22 @@
+-- migrate:up
+alter table tracking add column eta datetime;
+
+create table chat_webhooks (
+  id integer primary key not null,
+  user_id int not null,
+  kind text not null,
+  url text not null,
+  -- null means every one of the user's groups
+  group_name text,
+  -- json array of canonical statuses to post about, null means every status
+  statuses text,
+  created_at datetime not null,
+  foreign key(user_id) references users(id)
+);
+
+create index chat_webhooks_user_id on chat_webhooks(user_id);
+
+-- migrate:down
+drop index chat_webhooks_user_id;
+drop table chat_webhooks;
+alter table tracking drop column eta;
@@ -0,0 +1,25 @@
+-- migrate:up
+alter table users add column phone text;
+alter table users add column sms_opt_in boolean not null default false;
+
+create index users_phone on users(phone);
+
+create table sms_sends (
+  id integer primary key not null,
+  user_id int not null,
+  phone text not null,
+  body text not null,
+  -- the message sid returned by the SMS provider
+  sid text,
+  sent_at datetime not null,
+  foreign key(user_id) references users(id)
+);
+
+create index sms_sends_user_id_sent_at on sms_sends(user_id, sent_at);
+
+-- migrate:down
+drop index sms_sends_user_id_sent_at;
+drop table sms_sends;
+drop index users_phone;
+alter table users drop column sms_opt_in;
+alter table users drop column phone;
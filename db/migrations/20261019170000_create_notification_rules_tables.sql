@@ -0,0 +1,47 @@
+-- migrate:up
+alter table tracking_status_history add column old_canonical_status text;
+
+create table notification_rules (
+  id integer primary key not null,
+  user_id int not null,
+  name text not null,
+  -- json arrays, where null means every group or every transition
+  group_names text,
+  transitions text,
+  channels text not null,
+  -- HH:MM in the rule's timezone, both null if the rule doesn't have quiet hours
+  quiet_start text,
+  quiet_end text,
+  timezone text not null default 'UTC',
+  debounce_seconds int not null default 0,
+  created_at datetime not null,
+  foreign key(user_id) references users(id)
+);
+
+create index notification_rules_user_id on notification_rules(user_id);
+
+create table notification_log (
+  id integer primary key not null,
+  user_id int not null,
+  -- null when the user doesn't have any rules and the channel's defaults were used
+  rule_id int,
+  history_id int not null,
+  tracking_number text not null,
+  channel text not null,
+  outcome text not null,
+  error text,
+  created_at datetime not null,
+  foreign key(user_id) references users(id),
+  foreign key(history_id) references tracking_status_history(id)
+);
+
+create index notification_log_user_id on notification_log(user_id, id);
+create index notification_log_debounce on notification_log(rule_id, tracking_number, channel, created_at);
+
+-- migrate:down
+drop index notification_log_debounce;
+drop index notification_log_user_id;
+drop table notification_log;
+drop index notification_rules_user_id;
+drop table notification_rules;
+alter table tracking_status_history drop column old_canonical_status;
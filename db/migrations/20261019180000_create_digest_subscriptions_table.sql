@@ -0,0 +1,22 @@
+-- migrate:up
+create table digest_subscriptions (
+  id integer primary key not null,
+  user_id int not null,
+  frequency text not null,
+  channel text not null,
+  -- local hour of the day the digest is sent at, and for weekly digests the day of the week (0 is sunday)
+  hour int not null,
+  weekday int,
+  timezone text not null default 'UTC',
+  last_sent_at datetime,
+  created_at datetime not null,
+  foreign key(user_id) references users(id)
+);
+
+create index digest_subscriptions_user_id on digest_subscriptions(user_id);
+create index tracking_status_history_observed_at on tracking_status_history(observed_at);
+
+-- migrate:down
+drop index tracking_status_history_observed_at;
+drop index digest_subscriptions_user_id;
+drop table digest_subscriptions;
@@ -0,0 +1,5 @@
+-- migrate:up
+alter table tracking add column notes text;
+
+-- migrate:down
+alter table tracking drop column notes;
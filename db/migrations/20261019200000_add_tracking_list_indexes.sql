@@ -0,0 +1,27 @@
+-- migrate:up
+alter table tracking add column carrier text not null default 'fedex';
+alter table tracking add column created_at datetime;
+
+-- numbers added before created_at existed were added when their first status was recorded
+update tracking set created_at = coalesce(
+  (select min(observed_at) from tracking_status_history where tracking_id = tracking.id),
+  status_last_updated,
+  datetime('now')
+);
+
+create index tracking_user_id_group_name on tracking(user_id, group_name);
+create index tracking_user_id_canonical_status on tracking(user_id, canonical_status);
+create index tracking_user_id_carrier on tracking(user_id, carrier);
+create index tracking_user_id_created_at on tracking(user_id, unixepoch(created_at), id);
+create index tracking_user_id_status_last_updated on tracking(user_id, unixepoch(status_last_updated), id);
+create index tracking_user_id_eta on tracking(user_id, unixepoch(eta), id);
+
+-- migrate:down
+drop index tracking_user_id_eta;
+drop index tracking_user_id_status_last_updated;
+drop index tracking_user_id_created_at;
+drop index tracking_user_id_carrier;
+drop index tracking_user_id_canonical_status;
+drop index tracking_user_id_group_name;
+alter table tracking drop column created_at;
+alter table tracking drop column carrier;
@@ -0,0 +1,21 @@
+-- migrate:up
+create table tracking_groups (
+  id integer primary key not null,
+  user_id int not null,
+  -- tracking rows, rules and recipient lists refer to groups by name, which renaming a group keeps in sync
+  name text not null,
+  archived_at datetime,
+  created_at datetime not null,
+  foreign key(user_id) references users(id)
+);
+
+create unique index tracking_groups_user_id_name on tracking_groups(user_id, name);
+
+insert into tracking_groups (user_id, name, created_at)
+select user_id, group_name, min(coalesce(created_at, datetime('now'))) from tracking
+where group_name is not null
+group by user_id, group_name;
+
+-- migrate:down
+drop index tracking_groups_user_id_name;
+drop table tracking_groups;
@@ -0,0 +1,10 @@
+-- migrate:up
+alter table tracking add column archived_at datetime;
+
+create index tracking_user_id_archived_at on tracking(user_id, archived_at);
+create index tracking_status_history_tracking_id_canonical_status on tracking_status_history(tracking_id, canonical_status);
+
+-- migrate:down
+drop index tracking_status_history_tracking_id_canonical_status;
+drop index tracking_user_id_archived_at;
+alter table tracking drop column archived_at;
@@ -0,0 +1,109 @@
+-- migrate:up
+-- a shipment is a carrier's tracking number, which is polled once no matter how many users are tracking it
+create table shipments (
+  id integer primary key not null,
+  carrier text not null default 'fedex',
+  tracking_number text not null,
+  status text,
+  canonical_status text,
+  eta datetime,
+  status_last_updated datetime,
+  created_at datetime
+);
+
+-- a subscription is one user tracking a shipment, with their own group and notes
+create table subscriptions (
+  id integer primary key not null,
+  user_id int not null,
+  shipment_id int not null,
+  group_name text,
+  notes text,
+  archived_at datetime,
+  created_at datetime,
+  foreign key(user_id) references users(id),
+  foreign key(shipment_id) references shipments(id)
+);
+
+-- every tracking number was only tracked by one user until now, so both keep the tracking row's id
+insert into shipments (id, carrier, tracking_number, status, canonical_status, eta, status_last_updated, created_at)
+  select id, carrier, tracking_number, status, canonical_status, eta, status_last_updated, created_at from tracking;
+insert into subscriptions (id, user_id, shipment_id, group_name, notes, archived_at, created_at)
+  select id, user_id, id, group_name, notes, archived_at, created_at from tracking;
+
+-- history belongs to the shipment, so everyone tracking it shares it
+create table tracking_status_history_new (
+  id integer primary key not null,
+  shipment_id int not null,
+  old_status text,
+  new_status text,
+  old_canonical_status text,
+  canonical_status text not null,
+  observed_at datetime not null,
+  source text not null,
+  foreign key(shipment_id) references shipments(id)
+);
+insert into tracking_status_history_new (id, shipment_id, old_status, new_status, old_canonical_status, canonical_status, observed_at, source)
+  select id, tracking_id, old_status, new_status, old_canonical_status, canonical_status, observed_at, source from tracking_status_history;
+drop table tracking_status_history;
+alter table tracking_status_history_new rename to tracking_status_history;
+
+drop table tracking;
+
+create unique index shipments_carrier_tracking_number on shipments(carrier, tracking_number);
+create index shipments_tracking_number on shipments(tracking_number);
+create index shipments_status_last_updated on shipments(unixepoch(status_last_updated));
+create unique index subscriptions_user_id_shipment_id on subscriptions(user_id, shipment_id);
+create index subscriptions_shipment_id on subscriptions(shipment_id);
+create index subscriptions_user_id_group_name on subscriptions(user_id, group_name);
+create index subscriptions_user_id_archived_at on subscriptions(user_id, archived_at);
+create index subscriptions_user_id_created_at on subscriptions(user_id, unixepoch(created_at), id);
+create index tracking_status_history_shipment_id on tracking_status_history(shipment_id);
+create index tracking_status_history_shipment_id_canonical_status on tracking_status_history(shipment_id, canonical_status);
+create index tracking_status_history_observed_at on tracking_status_history(observed_at);
+
+-- migrate:down
+create table tracking (
+  id integer primary key not null,
+  tracking_number text unique not null,
+  status text,
+  group_name text,
+  status_last_updated datetime,
+  user_id int not null, canonical_status text, eta datetime, notes text, carrier text not null default 'fedex', created_at datetime, archived_at datetime,
+  foreign key(user_id) references users(id)
+);
+
+-- only one user can track each number, so the first one to have started tracking it keeps it
+insert into tracking (id, tracking_number, status, group_name, status_last_updated, user_id, canonical_status, eta, notes, carrier, created_at, archived_at)
+  select sh.id, sh.tracking_number, sh.status, s.group_name, sh.status_last_updated, s.user_id, sh.canonical_status, sh.eta, s.notes, sh.carrier, s.created_at, s.archived_at
+  from shipments sh join subscriptions s on s.shipment_id = sh.id
+  where s.id = (select min(id) from subscriptions where shipment_id = sh.id);
+
+create table tracking_status_history_old (
+  id integer primary key not null,
+  tracking_id int not null,
+  old_status text,
+  new_status text,
+  canonical_status text not null,
+  observed_at datetime not null,
+  source text not null, old_canonical_status text,
+  foreign key(tracking_id) references tracking(id)
+);
+insert into tracking_status_history_old (id, tracking_id, old_status, new_status, canonical_status, observed_at, source, old_canonical_status)
+  select id, shipment_id, old_status, new_status, canonical_status, observed_at, source, old_canonical_status from tracking_status_history
+  where shipment_id in (select id from tracking);
+drop table tracking_status_history;
+alter table tracking_status_history_old rename to tracking_status_history;
+
+drop table subscriptions;
+drop table shipments;
+
+create index tracking_status_history_tracking_id on tracking_status_history(tracking_id);
+create index tracking_status_history_observed_at on tracking_status_history(observed_at);
+create index tracking_status_history_tracking_id_canonical_status on tracking_status_history(tracking_id, canonical_status);
+create index tracking_user_id_group_name on tracking(user_id, group_name);
+create index tracking_user_id_canonical_status on tracking(user_id, canonical_status);
+create index tracking_user_id_carrier on tracking(user_id, carrier);
+create index tracking_user_id_created_at on tracking(user_id, unixepoch(created_at), id);
+create index tracking_user_id_status_last_updated on tracking(user_id, unixepoch(status_last_updated), id);
+create index tracking_user_id_eta on tracking(user_id, unixepoch(eta), id);
+create index tracking_user_id_archived_at on tracking(user_id, archived_at);
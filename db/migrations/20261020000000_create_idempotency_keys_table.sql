@@ -0,0 +1,21 @@
+-- migrate:up
+create table idempotency_keys (
+  id integer primary key not null,
+  user_id int not null,
+  key text not null,
+  -- sha256 of the request, so that a key can't be reused for a different request
+  request_hash blob not null,
+  -- null until the first request with the key has been handled
+  status int,
+  response blob,
+  created_at datetime not null,
+  foreign key(user_id) references users(id)
+);
+
+create unique index idempotency_keys_user_id_key on idempotency_keys(user_id, key);
+create index idempotency_keys_created_at on idempotency_keys(created_at);
+
+-- migrate:down
+drop index idempotency_keys_created_at;
+drop index idempotency_keys_user_id_key;
+drop table idempotency_keys;
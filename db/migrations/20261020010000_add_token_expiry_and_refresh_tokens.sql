@@ -0,0 +1,41 @@
+-- migrate:up
+alter table tokens add column issued_at datetime;
+alter table tokens add column expires_at datetime;
+alter table tokens add column last_used_at datetime;
+-- the access and refresh tokens from one login share a session, so logging out revokes both
+alter table tokens add column session_id text;
+
+-- tokens from before now have no refresh token, so they get a month for clients to log in again rather than expiring
+-- right away
+update tokens set expires_at = datetime('now', '+30 days');
+
+create table refresh_tokens (
+  token text primary key not null,
+  user_id int not null,
+  session_id text not null,
+  issued_at datetime not null,
+  expires_at datetime not null,
+  -- set once the token has been swapped for a new one. Using it again means it leaked, so the session is revoked
+  used_at datetime,
+  foreign key(user_id) references users(id)
+);
+
+create index tokens_user_id on tokens(user_id);
+create index tokens_session_id on tokens(session_id);
+create index tokens_expires_at on tokens(unixepoch(expires_at));
+create index refresh_tokens_user_id on refresh_tokens(user_id);
+create index refresh_tokens_session_id on refresh_tokens(session_id);
+create index refresh_tokens_expires_at on refresh_tokens(unixepoch(expires_at));
+
+-- migrate:down
+drop index refresh_tokens_expires_at;
+drop index refresh_tokens_session_id;
+drop index refresh_tokens_user_id;
+drop table refresh_tokens;
+drop index tokens_expires_at;
+drop index tokens_session_id;
+drop index tokens_user_id;
+alter table tokens drop column session_id;
+alter table tokens drop column last_used_at;
+alter table tokens drop column expires_at;
+alter table tokens drop column issued_at;
@@ -0,0 +1,66 @@
+-- migrate:up
+-- tokens are stored as their sha256 so that a copy of the db can't be used to log in. SQLite can't hash them, so the
+-- rows copied over still hold the plaintext token as text until auth.HashPlaintextTokens replaces it on startup
+create table tokens_new (
+  token_hash blob primary key not null,
+  user_id int not null,
+  issued_at datetime,
+  expires_at datetime,
+  last_used_at datetime,
+  session_id text,
+  foreign key(user_id) references users(id)
+);
+insert into tokens_new (token_hash, user_id, issued_at, expires_at, last_used_at, session_id)
+  select token, user_id, issued_at, expires_at, last_used_at, session_id from tokens;
+drop table tokens;
+alter table tokens_new rename to tokens;
+
+create table refresh_tokens_new (
+  token_hash blob primary key not null,
+  user_id int not null,
+  session_id text not null,
+  issued_at datetime not null,
+  expires_at datetime not null,
+  -- set once the token has been swapped for a new one. Using it again means it leaked, so the session is revoked
+  used_at datetime,
+  foreign key(user_id) references users(id)
+);
+insert into refresh_tokens_new (token_hash, user_id, session_id, issued_at, expires_at, used_at)
+  select token, user_id, session_id, issued_at, expires_at, used_at from refresh_tokens;
+drop table refresh_tokens;
+alter table refresh_tokens_new rename to refresh_tokens;
+
+create index tokens_user_id on tokens(user_id);
+create index tokens_session_id on tokens(session_id);
+create index tokens_expires_at on tokens(unixepoch(expires_at));
+create index refresh_tokens_user_id on refresh_tokens(user_id);
+create index refresh_tokens_session_id on refresh_tokens(session_id);
+create index refresh_tokens_expires_at on refresh_tokens(unixepoch(expires_at));
+
+-- migrate:down
+-- hashes can't be turned back into tokens, so everyone has to log in again
+drop table refresh_tokens;
+drop table tokens;
+
+create table tokens (
+  token string primary key not null,
+  user_id int not null, issued_at datetime, expires_at datetime, last_used_at datetime, session_id text,
+  foreign key(user_id) references users(id)
+);
+create table refresh_tokens (
+  token text primary key not null,
+  user_id int not null,
+  session_id text not null,
+  issued_at datetime not null,
+  expires_at datetime not null,
+  -- set once the token has been swapped for a new one. Using it again means it leaked, so the session is revoked
+  used_at datetime,
+  foreign key(user_id) references users(id)
+);
+
+create index tokens_user_id on tokens(user_id);
+create index tokens_session_id on tokens(session_id);
+create index tokens_expires_at on tokens(unixepoch(expires_at));
+create index refresh_tokens_user_id on refresh_tokens(user_id);
+create index refresh_tokens_session_id on refresh_tokens(session_id);
+create index refresh_tokens_expires_at on refresh_tokens(unixepoch(expires_at));
@@ -0,0 +1,27 @@
+-- migrate:up
+create table api_keys (
+  id integer primary key not null,
+  user_id int not null,
+  name text not null,
+  -- sha256 of the key, like tokens
+  key_hash blob not null,
+  -- the start of the key, so people can tell their keys apart
+  prefix text not null,
+  -- json array of scopes
+  scopes text not null,
+  -- restricts the key to the numbers in this group
+  group_id int,
+  expires_at datetime,
+  last_used_at datetime,
+  created_at datetime not null,
+  foreign key(user_id) references users(id),
+  foreign key(group_id) references tracking_groups(id)
+);
+
+create unique index api_keys_key_hash on api_keys(key_hash);
+create index api_keys_user_id on api_keys(user_id);
+
+-- migrate:down
+drop index api_keys_user_id;
+drop index api_keys_key_hash;
+drop table api_keys;
@@ -0,0 +1,123 @@
+-- migrate:up
+create table organizations (
+  id integer primary key not null,
+  name text not null,
+  created_at datetime not null
+);
+
+create table organization_members (
+  org_id int not null,
+  user_id int not null,
+  -- owner, admin, member or viewer
+  role text not null,
+  created_at datetime not null,
+  primary key (org_id, user_id),
+  foreign key(org_id) references organizations(id),
+  foreign key(user_id) references users(id)
+);
+
+create index organization_members_user_id on organization_members(user_id);
+
+-- every user owns an organization named after the company they typed in. Users who typed the same company aren't put
+-- in the same organization, since anyone could have typed it
+insert into organizations (id, name, created_at)
+  select id, coalesce(nullif(trim(company), ''), username), datetime('now') from users;
+insert into organization_members (org_id, user_id, role, created_at)
+  select id, id, 'owner', datetime('now') from users;
+
+alter table users drop column company;
+
+-- tracking numbers and groups belong to the organization, so everyone in it shares them
+create table subscriptions_new (
+  id integer primary key not null,
+  org_id int not null,
+  shipment_id int not null,
+  group_name text,
+  notes text,
+  archived_at datetime,
+  created_at datetime,
+  foreign key(org_id) references organizations(id),
+  foreign key(shipment_id) references shipments(id)
+);
+insert into subscriptions_new (id, org_id, shipment_id, group_name, notes, archived_at, created_at)
+  select id, user_id, shipment_id, group_name, notes, archived_at, created_at from subscriptions;
+drop table subscriptions;
+alter table subscriptions_new rename to subscriptions;
+
+create table tracking_groups_new (
+  id integer primary key not null,
+  org_id int not null,
+  -- tracking rows, rules and recipient lists refer to groups by name, which renaming a group keeps in sync
+  name text not null,
+  archived_at datetime,
+  created_at datetime not null,
+  foreign key(org_id) references organizations(id)
+);
+insert into tracking_groups_new (id, org_id, name, archived_at, created_at)
+  select id, user_id, name, archived_at, created_at from tracking_groups;
+drop table tracking_groups;
+alter table tracking_groups_new rename to tracking_groups;
+
+-- api keys act in the organization they were made in
+alter table api_keys add column org_id int;
+update api_keys set org_id = user_id;
+
+create unique index subscriptions_org_id_shipment_id on subscriptions(org_id, shipment_id);
+create index subscriptions_shipment_id on subscriptions(shipment_id);
+create index subscriptions_org_id_group_name on subscriptions(org_id, group_name);
+create index subscriptions_org_id_archived_at on subscriptions(org_id, archived_at);
+create index subscriptions_org_id_created_at on subscriptions(org_id, unixepoch(created_at), id);
+create unique index tracking_groups_org_id_name on tracking_groups(org_id, name);
+
+-- migrate:down
+alter table api_keys drop column org_id;
+
+-- everything goes back to the organization's first owner
+create table tracking_groups_old (
+  id integer primary key not null,
+  user_id int not null,
+  -- tracking rows, rules and recipient lists refer to groups by name, which renaming a group keeps in sync
+  name text not null,
+  archived_at datetime,
+  created_at datetime not null,
+  foreign key(user_id) references users(id)
+);
+insert into tracking_groups_old (id, user_id, name, archived_at, created_at)
+  select id, (select min(user_id) from organization_members where org_id = tracking_groups.org_id and role = 'owner'), name, archived_at, created_at
+  from tracking_groups;
+drop table tracking_groups;
+alter table tracking_groups_old rename to tracking_groups;
+
+create table subscriptions_old (
+  id integer primary key not null,
+  user_id int not null,
+  shipment_id int not null,
+  group_name text,
+  notes text,
+  archived_at datetime,
+  created_at datetime,
+  foreign key(user_id) references users(id),
+  foreign key(shipment_id) references shipments(id)
+);
+insert into subscriptions_old (id, user_id, shipment_id, group_name, notes, archived_at, created_at)
+  select id, (select min(user_id) from organization_members where org_id = subscriptions.org_id and role = 'owner'), shipment_id, group_name, notes, archived_at, created_at
+  from subscriptions;
+drop table subscriptions;
+alter table subscriptions_old rename to subscriptions;
+
+alter table users add column company text not null default '';
+update users set company = coalesce((
+  select o.name from organizations o join organization_members m on m.org_id = o.id
+  where m.user_id = users.id order by unixepoch(m.created_at), m.rowid limit 1
+), '');
+
+drop index organization_members_user_id;
+drop table organization_members;
+drop table organizations;
+
+create unique index subscriptions_user_id_shipment_id on subscriptions(user_id, shipment_id);
+create index subscriptions_shipment_id on subscriptions(shipment_id);
+create index subscriptions_user_id_group_name on subscriptions(user_id, group_name);
+create index subscriptions_user_id_archived_at on subscriptions(user_id, archived_at);
+create index subscriptions_user_id_created_at on subscriptions(user_id, unixepoch(created_at), id);
+create unique index tracking_groups_user_id_name on tracking_groups(user_id, name);
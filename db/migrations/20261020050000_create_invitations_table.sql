@@ -0,0 +1,23 @@
+-- migrate:up
+create table invitations (
+  -- autoincrement, so invite tokens for deleted invitations can't match new ones
+  id integer primary key autoincrement not null,
+  org_id int not null,
+  email text not null,
+  -- the role the invited user joins with
+  role text not null,
+  invited_by int not null,
+  -- invite tokens carry this too, signed so it can't be changed
+  expires_at datetime not null,
+  accepted_at datetime,
+  declined_at datetime,
+  created_at datetime not null,
+  foreign key(org_id) references organizations(id),
+  foreign key(invited_by) references users(id)
+);
+
+create index invitations_org_id on invitations(org_id);
+
+-- migrate:down
+drop index invitations_org_id;
+drop table invitations;
@@ -0,0 +1,16 @@
+-- migrate:up
+create table password_resets (
+  -- sha256 of the reset token, like tokens
+  token_hash blob primary key not null,
+  user_id int not null,
+  expires_at datetime not null,
+  used_at datetime,
+  created_at datetime not null,
+  foreign key(user_id) references users(id)
+);
+
+create index password_resets_user_id on password_resets(user_id);
+
+-- migrate:down
+drop index password_resets_user_id;
+drop table password_resets;
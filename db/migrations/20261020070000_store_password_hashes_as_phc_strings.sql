@@ -0,0 +1,14 @@
+-- migrate:up
+-- password_hash becomes a PHC string like $argon2id$v=19$m=47104,t=1,p=1$<salt>$<hash>, which carries the salt and the
+-- parameters that made it. SQLite can't base64 encode, so auth.UpgradeLegacyPasswordHashes converts the existing hashes
+-- when the server starts, using the salt kept here until then
+alter table users add column legacy_salt blob;
+update users set legacy_salt = salt;
+alter table users drop column salt;
+
+-- migrate:down
+-- hashes that were converted or made since can't be turned back into a bare hash and salt in SQL, so those users can't
+-- log in until their password is set again
+alter table users add column salt bytea not null default x'';
+update users set salt = legacy_salt where legacy_salt is not null;
+alter table users drop column legacy_salt;
@@ -0,0 +1,60 @@
+-- migrate:up
+-- the same key sent to two organizations is two different requests
+create table idempotency_keys_new (
+  id integer primary key not null,
+  org_id int not null,
+  user_id int not null,
+  key text not null,
+  -- sha256 of the request, so that a key can't be reused for a different request
+  request_hash blob not null,
+  -- null until the first request with the key has been handled
+  status int,
+  response blob,
+  created_at datetime not null,
+  foreign key(org_id) references organizations(id),
+  foreign key(user_id) references users(id)
+);
+
+-- keys from before organizations were sent to the user's first one, which is where requests without an
+-- X-Organization-ID header act
+insert into idempotency_keys_new (id, org_id, user_id, key, request_hash, status, response, created_at)
+  select id, (select org_id from organization_members where user_id = idempotency_keys.user_id order by unixepoch(created_at), rowid limit 1),
+    user_id, key, request_hash, status, response, created_at
+  from idempotency_keys
+  where exists(select 1 from organization_members where user_id = idempotency_keys.user_id);
+
+drop index idempotency_keys_created_at;
+drop index idempotency_keys_user_id_key;
+drop table idempotency_keys;
+alter table idempotency_keys_new rename to idempotency_keys;
+
+create unique index idempotency_keys_org_id_user_id_key on idempotency_keys(org_id, user_id, key);
+create index idempotency_keys_created_at on idempotency_keys(created_at);
+
+-- migrate:down
+create table idempotency_keys_old (
+  id integer primary key not null,
+  user_id int not null,
+  key text not null,
+  -- sha256 of the request, so that a key can't be reused for a different request
+  request_hash blob not null,
+  -- null until the first request with the key has been handled
+  status int,
+  response blob,
+  created_at datetime not null,
+  foreign key(user_id) references users(id)
+);
+
+-- only one organization's use of a key can be kept
+insert into idempotency_keys_old (id, user_id, key, request_hash, status, response, created_at)
+  select id, user_id, key, request_hash, status, response, created_at
+  from idempotency_keys
+  where id in (select min(id) from idempotency_keys group by user_id, key);
+
+drop index idempotency_keys_created_at;
+drop index idempotency_keys_org_id_user_id_key;
+drop table idempotency_keys;
+alter table idempotency_keys_old rename to idempotency_keys;
+
+create unique index idempotency_keys_user_id_key on idempotency_keys(user_id, key);
+create index idempotency_keys_created_at on idempotency_keys(created_at);
@@ -0,0 +1,29 @@
+-- migrate:up
+-- digests summarize one organization's shipments, and the notification log is read one organization at a time
+alter table digest_subscriptions add column org_id int references organizations(id);
+-- subscriptions from before were for every organization the user was in, and are kept for their first one
+update digest_subscriptions set org_id = (
+  select org_id from organization_members where user_id = digest_subscriptions.user_id order by unixepoch(created_at), rowid limit 1
+);
+
+alter table notification_log add column org_id int references organizations(id);
+-- the organization the user tracks the change's shipment through, or their first one if that's gone
+update notification_log set org_id = coalesce(
+  (
+    select s.org_id from tracking_status_history h
+    join subscriptions s on s.shipment_id = h.shipment_id
+    join organization_members m on m.org_id = s.org_id and m.user_id = notification_log.user_id
+    where h.id = notification_log.history_id
+    order by s.org_id limit 1
+  ),
+  (select org_id from organization_members where user_id = notification_log.user_id order by unixepoch(created_at), rowid limit 1)
+);
+
+drop index notification_log_user_id;
+create index notification_log_org_id_user_id on notification_log(org_id, user_id, id);
+
+-- migrate:down
+drop index notification_log_org_id_user_id;
+create index notification_log_user_id on notification_log(user_id, id);
+alter table notification_log drop column org_id;
+alter table digest_subscriptions drop column org_id;
@@ -0,0 +1,72 @@
+-- migrate:up
+-- settings that refer to groups by name belong to the organization the groups are in, so that renaming a group only
+-- touches its own organization's settings and changes only go to the settings of the organization they happened in.
+-- Settings from before go to the user's first organization with one of the groups they name, or their first one.
+alter table group_email_recipients add column org_id int references organizations(id);
+update group_email_recipients set org_id = coalesce(
+  (
+    select g.org_id from tracking_groups g
+    join organization_members m on m.org_id = g.org_id and m.user_id = group_email_recipients.user_id
+    where g.name = group_email_recipients.group_name
+    order by unixepoch(m.created_at), m.rowid limit 1
+  ),
+  (select org_id from organization_members where user_id = group_email_recipients.user_id order by unixepoch(created_at), rowid limit 1)
+);
+drop index group_email_recipients_unique;
+create unique index group_email_recipients_unique on group_email_recipients(org_id, user_id, group_name, email);
+
+alter table chat_webhooks add column org_id int references organizations(id);
+update chat_webhooks set org_id = coalesce(
+  (
+    select g.org_id from tracking_groups g
+    join organization_members m on m.org_id = g.org_id and m.user_id = chat_webhooks.user_id
+    where g.name = chat_webhooks.group_name
+    order by unixepoch(m.created_at), m.rowid limit 1
+  ),
+  (select org_id from organization_members where user_id = chat_webhooks.user_id order by unixepoch(created_at), rowid limit 1)
+);
+drop index chat_webhooks_user_id;
+create index chat_webhooks_org_id_user_id on chat_webhooks(org_id, user_id);
+
+alter table notification_rules add column org_id int references organizations(id);
+update notification_rules set org_id = coalesce(
+  (
+    select g.org_id from tracking_groups g
+    join organization_members m on m.org_id = g.org_id and m.user_id = notification_rules.user_id
+    where g.name in (select value from json_each(notification_rules.group_names))
+    order by unixepoch(m.created_at), m.rowid limit 1
+  ),
+  (select org_id from organization_members where user_id = notification_rules.user_id order by unixepoch(created_at), rowid limit 1)
+);
+drop index notification_rules_user_id;
+create index notification_rules_org_id_user_id on notification_rules(org_id, user_id);
+
+alter table webhook_endpoints add column org_id int references organizations(id);
+update webhook_endpoints set org_id = coalesce(
+  (
+    select g.org_id from tracking_groups g
+    join organization_members m on m.org_id = g.org_id and m.user_id = webhook_endpoints.user_id
+    where g.name in (select value from json_each(webhook_endpoints.group_names))
+    order by unixepoch(m.created_at), m.rowid limit 1
+  ),
+  (select org_id from organization_members where user_id = webhook_endpoints.user_id order by unixepoch(created_at), rowid limit 1)
+);
+drop index webhook_endpoints_user_id;
+create index webhook_endpoints_org_id_user_id on webhook_endpoints(org_id, user_id);
+
+-- migrate:down
+drop index webhook_endpoints_org_id_user_id;
+create index webhook_endpoints_user_id on webhook_endpoints(user_id);
+alter table webhook_endpoints drop column org_id;
+
+drop index notification_rules_org_id_user_id;
+create index notification_rules_user_id on notification_rules(user_id);
+alter table notification_rules drop column org_id;
+
+drop index chat_webhooks_org_id_user_id;
+create index chat_webhooks_user_id on chat_webhooks(user_id);
+alter table chat_webhooks drop column org_id;
+
+drop index group_email_recipients_unique;
+create unique index group_email_recipients_unique on group_email_recipients(user_id, group_name, email);
+alter table group_email_recipients drop column org_id;
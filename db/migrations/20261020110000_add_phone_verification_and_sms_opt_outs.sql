@@ -0,0 +1,24 @@
+-- migrate:up
+-- numbers are only texted once they've been verified with a code texted to them. Numbers added before this never were.
+alter table users add column phone_verified_at datetime;
+
+create table phone_verifications (
+  user_id int primary key not null,
+  phone text not null,
+  code text not null,
+  -- wrong codes tried, after which the code stops working
+  attempts int not null default 0,
+  expires_at datetime not null,
+  foreign key(user_id) references users(id)
+);
+
+-- numbers that replied STOP, which can't be opted back in through the API until they reply START
+create table sms_opt_outs (
+  phone text primary key not null,
+  created_at datetime not null
+);
+
+-- migrate:down
+drop table sms_opt_outs;
+drop table phone_verifications;
+alter table users drop column phone_verified_at;
@@ -0,0 +1,9 @@
+-- migrate:up
+-- shipments FedEx can't be asked about, or that it returns an error for, are polled less and less often
+alter table shipments add column failed_polls int not null default 0;
+-- null when the shipment is polled on the usual schedule
+alter table shipments add column next_poll_at datetime;
+
+-- migrate:down
+alter table shipments drop column next_poll_at;
+alter table shipments drop column failed_polls;
@@ -2,26 +2,330 @@ CREATE TABLE IF NOT EXISTS "schema_migrations" (version varchar(128) primary key
 CREATE TABLE users (
   id integer primary key not null,
   username text unique not null,
-  company text not null,
   password_hash bytea not null,
-  salt bytea not null
+  email text, email_verified_at datetime, phone text, sms_opt_in boolean not null default false, legacy_salt blob, phone_verified_at datetime);
+CREATE TABLE webhook_endpoints (
+  id integer primary key not null,
+  user_id int not null,
+  url text not null,
+  secret text not null,
+  -- json arrays of canonical statuses and group names to send events for, null means every one
+  statuses text,
+  group_names text,
+  created_at datetime not null, org_id int references organizations(id),
+  foreign key(user_id) references users(id)
+);
+CREATE TABLE webhook_deliveries (
+  id integer primary key not null,
+  endpoint_id int not null,
+  event_id text not null,
+  payload text not null,
+  status text not null,
+  attempts int not null default 0,
+  next_attempt_at datetime,
+  last_status_code int,
+  last_error text,
+  created_at datetime not null,
+  delivered_at datetime,
+  foreign key(endpoint_id) references webhook_endpoints(id)
+);
+CREATE INDEX webhook_deliveries_endpoint_id on webhook_deliveries(endpoint_id);
+CREATE INDEX webhook_deliveries_due on webhook_deliveries(status, next_attempt_at);
+CREATE TABLE webhook_delivery_attempts (
+  id integer primary key not null,
+  delivery_id int not null,
+  attempted_at datetime not null,
+  status_code int,
+  error text,
+  duration_ms int not null,
+  foreign key(delivery_id) references webhook_deliveries(id)
 );
-CREATE TABLE tokens (
-  token string primary key not null,
+CREATE INDEX webhook_delivery_attempts_delivery_id on webhook_delivery_attempts(delivery_id);
+CREATE TABLE email_verifications (
+  token text primary key not null,
   user_id int not null,
+  email text not null,
+  expires_at datetime not null,
   foreign key(user_id) references users(id)
 );
-CREATE TABLE tracking (
+CREATE TABLE group_email_recipients (
   id integer primary key not null,
-  tracking_number text unique not null,
-  status text,
+  user_id int not null,
+  group_name text not null,
+  email text not null, org_id int references organizations(id),
+  foreign key(user_id) references users(id)
+);
+CREATE TABLE chat_webhooks (
+  id integer primary key not null,
+  user_id int not null,
+  kind text not null,
+  url text not null,
+  -- null means every one of the user's groups
   group_name text,
+  -- json array of canonical statuses to post about, null means every status
+  statuses text,
+  created_at datetime not null, org_id int references organizations(id),
+  foreign key(user_id) references users(id)
+);
+CREATE INDEX users_phone on users(phone);
+CREATE TABLE sms_sends (
+  id integer primary key not null,
+  user_id int not null,
+  phone text not null,
+  body text not null,
+  -- the message sid returned by the SMS provider
+  sid text,
+  sent_at datetime not null,
+  foreign key(user_id) references users(id)
+);
+CREATE INDEX sms_sends_user_id_sent_at on sms_sends(user_id, sent_at);
+CREATE TABLE notification_rules (
+  id integer primary key not null,
+  user_id int not null,
+  name text not null,
+  -- json arrays, where null means every group or every transition
+  group_names text,
+  transitions text,
+  channels text not null,
+  -- HH:MM in the rule's timezone, both null if the rule doesn't have quiet hours
+  quiet_start text,
+  quiet_end text,
+  timezone text not null default 'UTC',
+  debounce_seconds int not null default 0,
+  created_at datetime not null, org_id int references organizations(id),
+  foreign key(user_id) references users(id)
+);
+CREATE TABLE notification_log (
+  id integer primary key not null,
+  user_id int not null,
+  -- null when the user doesn't have any rules and the channel's defaults were used
+  rule_id int,
+  history_id int not null,
+  tracking_number text not null,
+  channel text not null,
+  outcome text not null,
+  error text,
+  created_at datetime not null, org_id int references organizations(id),
+  foreign key(user_id) references users(id),
+  foreign key(history_id) references tracking_status_history(id)
+);
+CREATE INDEX notification_log_debounce on notification_log(rule_id, tracking_number, channel, created_at);
+CREATE TABLE digest_subscriptions (
+  id integer primary key not null,
+  user_id int not null,
+  frequency text not null,
+  channel text not null,
+  -- local hour of the day the digest is sent at, and for weekly digests the day of the week (0 is sunday)
+  hour int not null,
+  weekday int,
+  timezone text not null default 'UTC',
+  last_sent_at datetime,
+  created_at datetime not null, org_id int references organizations(id),
+  foreign key(user_id) references users(id)
+);
+CREATE INDEX digest_subscriptions_user_id on digest_subscriptions(user_id);
+CREATE TABLE shipments (
+  id integer primary key not null,
+  carrier text not null default 'fedex',
+  tracking_number text not null,
+  status text,
+  canonical_status text,
+  eta datetime,
   status_last_updated datetime,
+  created_at datetime
+, failed_polls int not null default 0, next_poll_at datetime);
+CREATE TABLE IF NOT EXISTS "tracking_status_history" (
+  id integer primary key not null,
+  shipment_id int not null,
+  old_status text,
+  new_status text,
+  old_canonical_status text,
+  canonical_status text not null,
+  observed_at datetime not null,
+  source text not null,
+  foreign key(shipment_id) references shipments(id)
+);
+CREATE UNIQUE INDEX shipments_carrier_tracking_number on shipments(carrier, tracking_number);
+CREATE INDEX shipments_tracking_number on shipments(tracking_number);
+CREATE INDEX shipments_status_last_updated on shipments(unixepoch(status_last_updated));
+CREATE INDEX tracking_status_history_shipment_id on tracking_status_history(shipment_id);
+CREATE INDEX tracking_status_history_shipment_id_canonical_status on tracking_status_history(shipment_id, canonical_status);
+CREATE INDEX tracking_status_history_observed_at on tracking_status_history(observed_at);
+CREATE TABLE IF NOT EXISTS "tokens" (
+  token_hash blob primary key not null,
+  user_id int not null,
+  issued_at datetime,
+  expires_at datetime,
+  last_used_at datetime,
+  session_id text,
+  foreign key(user_id) references users(id)
+);
+CREATE TABLE IF NOT EXISTS "refresh_tokens" (
+  token_hash blob primary key not null,
+  user_id int not null,
+  session_id text not null,
+  issued_at datetime not null,
+  expires_at datetime not null,
+  -- set once the token has been swapped for a new one. Using it again means it leaked, so the session is revoked
+  used_at datetime,
+  foreign key(user_id) references users(id)
+);
+CREATE INDEX tokens_user_id on tokens(user_id);
+CREATE INDEX tokens_session_id on tokens(session_id);
+CREATE INDEX tokens_expires_at on tokens(unixepoch(expires_at));
+CREATE INDEX refresh_tokens_user_id on refresh_tokens(user_id);
+CREATE INDEX refresh_tokens_session_id on refresh_tokens(session_id);
+CREATE INDEX refresh_tokens_expires_at on refresh_tokens(unixepoch(expires_at));
+CREATE TABLE api_keys (
+  id integer primary key not null,
+  user_id int not null,
+  name text not null,
+  -- sha256 of the key, like tokens
+  key_hash blob not null,
+  -- the start of the key, so people can tell their keys apart
+  prefix text not null,
+  -- json array of scopes
+  scopes text not null,
+  -- restricts the key to the numbers in this group
+  group_id int,
+  expires_at datetime,
+  last_used_at datetime,
+  created_at datetime not null, org_id int,
+  foreign key(user_id) references users(id),
+  foreign key(group_id) references tracking_groups(id)
+);
+CREATE UNIQUE INDEX api_keys_key_hash on api_keys(key_hash);
+CREATE INDEX api_keys_user_id on api_keys(user_id);
+CREATE TABLE organizations (
+  id integer primary key not null,
+  name text not null,
+  created_at datetime not null
+);
+CREATE TABLE organization_members (
+  org_id int not null,
+  user_id int not null,
+  -- owner, admin, member or viewer
+  role text not null,
+  created_at datetime not null,
+  primary key (org_id, user_id),
+  foreign key(org_id) references organizations(id),
+  foreign key(user_id) references users(id)
+);
+CREATE INDEX organization_members_user_id on organization_members(user_id);
+CREATE TABLE IF NOT EXISTS "subscriptions" (
+  id integer primary key not null,
+  org_id int not null,
+  shipment_id int not null,
+  group_name text,
+  notes text,
+  archived_at datetime,
+  created_at datetime,
+  foreign key(org_id) references organizations(id),
+  foreign key(shipment_id) references shipments(id)
+);
+CREATE TABLE IF NOT EXISTS "tracking_groups" (
+  id integer primary key not null,
+  org_id int not null,
+  -- tracking rows, rules and recipient lists refer to groups by name, which renaming a group keeps in sync
+  name text not null,
+  archived_at datetime,
+  created_at datetime not null,
+  foreign key(org_id) references organizations(id)
+);
+CREATE UNIQUE INDEX subscriptions_org_id_shipment_id on subscriptions(org_id, shipment_id);
+CREATE INDEX subscriptions_shipment_id on subscriptions(shipment_id);
+CREATE INDEX subscriptions_org_id_group_name on subscriptions(org_id, group_name);
+CREATE INDEX subscriptions_org_id_archived_at on subscriptions(org_id, archived_at);
+CREATE INDEX subscriptions_org_id_created_at on subscriptions(org_id, unixepoch(created_at), id);
+CREATE UNIQUE INDEX tracking_groups_org_id_name on tracking_groups(org_id, name);
+CREATE TABLE invitations (
+  -- autoincrement, so invite tokens for deleted invitations can't match new ones
+  id integer primary key autoincrement not null,
+  org_id int not null,
+  email text not null,
+  -- the role the invited user joins with
+  role text not null,
+  invited_by int not null,
+  -- invite tokens carry this too, signed so it can't be changed
+  expires_at datetime not null,
+  accepted_at datetime,
+  declined_at datetime,
+  created_at datetime not null,
+  foreign key(org_id) references organizations(id),
+  foreign key(invited_by) references users(id)
+);
+CREATE INDEX invitations_org_id on invitations(org_id);
+CREATE TABLE password_resets (
+  -- sha256 of the reset token, like tokens
+  token_hash blob primary key not null,
   user_id int not null,
+  expires_at datetime not null,
+  used_at datetime,
+  created_at datetime not null,
   foreign key(user_id) references users(id)
 );
+CREATE INDEX password_resets_user_id on password_resets(user_id);
+CREATE TABLE IF NOT EXISTS "idempotency_keys" (
+  id integer primary key not null,
+  org_id int not null,
+  user_id int not null,
+  key text not null,
+  -- sha256 of the request, so that a key can't be reused for a different request
+  request_hash blob not null,
+  -- null until the first request with the key has been handled
+  status int,
+  response blob,
+  created_at datetime not null,
+  foreign key(org_id) references organizations(id),
+  foreign key(user_id) references users(id)
+);
+CREATE UNIQUE INDEX idempotency_keys_org_id_user_id_key on idempotency_keys(org_id, user_id, key);
+CREATE INDEX idempotency_keys_created_at on idempotency_keys(created_at);
+CREATE INDEX notification_log_org_id_user_id on notification_log(org_id, user_id, id);
+CREATE UNIQUE INDEX group_email_recipients_unique on group_email_recipients(org_id, user_id, group_name, email);
+CREATE INDEX chat_webhooks_org_id_user_id on chat_webhooks(org_id, user_id);
+CREATE INDEX notification_rules_org_id_user_id on notification_rules(org_id, user_id);
+CREATE INDEX webhook_endpoints_org_id_user_id on webhook_endpoints(org_id, user_id);
+CREATE TABLE phone_verifications (
+  user_id int primary key not null,
+  phone text not null,
+  code text not null,
+  -- wrong codes tried, after which the code stops working
+  attempts int not null default 0,
+  expires_at datetime not null,
+  foreign key(user_id) references users(id)
+);
+CREATE TABLE sms_opt_outs (
+  phone text primary key not null,
+  created_at datetime not null
+);
 -- Dbmate schema migrations
 INSERT INTO "schema_migrations" (version) VALUES
   ('20240902001155'),
   ('20240905192308'),
-  ('20240910192425');
+  ('20240910192425'),
+  ('20261019120000'),
+  ('20261019130000'),
+  ('20261019140000'),
+  ('20261019150000'),
+  ('20261019160000'),
+  ('20261019170000'),
+  ('20261019180000'),
+  ('20261019190000'),
+  ('20261019200000'),
+  ('20261019210000'),
+  ('20261019220000'),
+  ('20261019230000'),
+  ('20261020000000'),
+  ('20261020010000'),
+  ('20261020020000'),
+  ('20261020030000'),
+  ('20261020040000'),
+  ('20261020050000'),
+  ('20261020060000'),
+  ('20261020070000'),
+  ('20261020080000'),
+  ('20261020090000'),
+  ('20261020100000'),
+  ('20261020110000'),
+  ('20261020120000');
@@ -0,0 +1,162 @@
+package digest
+
+import (
+	"database/sql"
+	"sort"
+	"time"
+
+	fedex "github.com/billyb2/tracking_server/tracking"
+)
+
+type Package struct {
+	TrackingNumber string     `json:"tracking_number"`
+	Status         string     `json:"status"`
+	ETA            *time.Time `json:"eta,omitempty"`
+	// When it was delivered or hit the exception
+	ObservedAt *time.Time `json:"observed_at,omitempty"`
+}
+
+type Group struct {
+	// Empty for numbers that aren't in a group
+	GroupName string `json:"group_name"`
+	// Delivered during the period
+	Delivered []Package `json:"delivered"`
+	// Still on the way at the end of the period
+	InTransit []Package `json:"in_transit"`
+	// Still on the way at the end of the period, but past their ETA. These are in in_transit too
+	Late []Package `json:"late"`
+	// Hit an exception during the period
+	Exceptions []Package `json:"exceptions"`
+}
+
+type Totals struct {
+	Delivered  int `json:"delivered"`
+	InTransit  int `json:"in_transit"`
+	Late       int `json:"late"`
+	Exceptions int `json:"exceptions"`
+}
+
+// Digest summarizes what happened to an organization's packages over a period, group by group
+type Digest struct {
+	Frequency   string    `json:"frequency"`
+	PeriodStart time.Time `json:"period_start"`
+	PeriodEnd   time.Time `json:"period_end"`
+	Totals      Totals    `json:"totals"`
+	Groups      []Group   `json:"groups"`
+}
+
+func (d *Digest) Empty() bool {
+	return d.Totals.Delivered == 0 && d.Totals.InTransit == 0 && d.Totals.Exceptions == 0
+}
+
+// Build summarizes the organization's packages between since and until. What's in transit or late is based on each
+// number's current status, so until should be about now.
+func Build(db *sql.DB, orgID int64, frequency string, since, until time.Time) (*Digest, error) {
+	groups := map[string]*Group{}
+	group := func(groupName string) *Group {
+		if groups[groupName] == nil {
+			groups[groupName] = &Group{
+				GroupName:  groupName,
+				Delivered:  []Package{},
+				InTransit:  []Package{},
+				Late:       []Package{},
+				Exceptions: []Package{},
+			}
+		}
+		return groups[groupName]
+	}
+
+	// history's timestamps are stored by sqlite's datetime(), so they're compared as UTC strings
+	sinceStr := since.UTC().Format(time.DateTime)
+	untilStr := until.UTC().Format(time.DateTime)
+
+	// only the most recent delivery or exception of each number counts
+	rows, err := db.Query(
+		`select sh.tracking_number, coalesce(s.group_name, ''), h.canonical_status, coalesce(h.new_status, ''), sh.eta, h.observed_at
+		from tracking_status_history h
+		join shipments sh on sh.id = h.shipment_id
+		join subscriptions s on s.shipment_id = h.shipment_id
+		where s.org_id = ? and h.id in (
+			select max(id) from tracking_status_history
+			where canonical_status in (?, ?) and observed_at >= ? and observed_at < ?
+			group by shipment_id, canonical_status
+		)
+		order by sh.tracking_number`,
+		orgID, fedex.StatusDelivered, fedex.StatusException, sinceStr, untilStr,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	digest := Digest{
+		Frequency:   frequency,
+		PeriodStart: since,
+		PeriodEnd:   until,
+	}
+	for rows.Next() {
+		pkg := Package{}
+		var groupName, canonicalStatus string
+		var observedAt time.Time
+		if err := rows.Scan(&pkg.TrackingNumber, &groupName, &canonicalStatus, &pkg.Status, &pkg.ETA, &observedAt); err != nil {
+			return nil, err
+		}
+		pkg.ObservedAt = &observedAt
+
+		g := group(groupName)
+		switch canonicalStatus {
+		case fedex.StatusDelivered:
+			g.Delivered = append(g.Delivered, pkg)
+			digest.Totals.Delivered++
+		case fedex.StatusException:
+			g.Exceptions = append(g.Exceptions, pkg)
+			digest.Totals.Exceptions++
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = db.Query(
+		`select sh.tracking_number, coalesce(s.group_name, ''), coalesce(sh.status, ''), sh.eta, coalesce(datetime(sh.eta) < ?, false)
+		from subscriptions s join shipments sh on sh.id = s.shipment_id
+		where s.org_id = ? and s.archived_at is null and sh.canonical_status in (?, ?, ?)
+		order by sh.tracking_number`,
+		untilStr, orgID, fedex.StatusLabelCreated, fedex.StatusInTransit, fedex.StatusOutForDelivery,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		pkg := Package{}
+		var groupName string
+		var late bool
+		if err := rows.Scan(&pkg.TrackingNumber, &groupName, &pkg.Status, &pkg.ETA, &late); err != nil {
+			return nil, err
+		}
+
+		g := group(groupName)
+		g.InTransit = append(g.InTransit, pkg)
+		digest.Totals.InTransit++
+		if late {
+			g.Late = append(g.Late, pkg)
+			digest.Totals.Late++
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	digest.Groups = make([]Group, 0, len(groups))
+	for _, g := range groups {
+		digest.Groups = append(digest.Groups, *g)
+	}
+	// numbers without a group sort first
+	sort.Slice(digest.Groups, func(i, j int) bool {
+		return digest.Groups[i].GroupName < digest.Groups[j].GroupName
+	})
+
+	return &digest, nil
+}
@@ -0,0 +1,279 @@
+package digest
+
+import (
+	"bytes"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"os"
+	texttemplate "text/template"
+	"time"
+	// subscriptions have timezones, which the docker image doesn't have a database of
+	_ "time/tzdata"
+
+	"github.com/billyb2/tracking_server/mailer"
+	"github.com/billyb2/tracking_server/webhooks"
+)
+
+const (
+	FrequencyDaily  = "daily"
+	FrequencyWeekly = "weekly"
+)
+
+const (
+	ChannelEmail   = "email"
+	ChannelWebhook = "webhook"
+)
+
+var InvalidSubscription error = fmt.Errorf("invalid digest subscription")
+var SubscriptionNotFound error = fmt.Errorf("digest subscription not found")
+
+//go:embed templates
+var templates embed.FS
+
+var digestText = texttemplate.Must(texttemplate.ParseFS(templates, "templates/digest.txt"))
+var digestHTML = htmltemplate.Must(htmltemplate.ParseFS(templates, "templates/digest.html"))
+
+type Subscription struct {
+	ID int64 `json:"id"`
+	// The organization whose packages the digest summarizes
+	OrgID     int64  `json:"org_id"`
+	Frequency string `json:"frequency"`
+	// Email goes to the user's verified email, webhook goes to every one of the user's webhooks as a digest event
+	Channel string `json:"channel"`
+	// Hour of the day, 0-23 in the subscription's timezone, the digest is sent at
+	Hour int `json:"hour"`
+	// Day of the week weekly digests are sent on, 0 is Sunday
+	Weekday *int `json:"weekday,omitempty"`
+	// IANA timezone, like America/Chicago. Defaults to UTC
+	Timezone   string     `json:"timezone,omitempty"`
+	LastSentAt *time.Time `json:"last_sent_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+func (s *Subscription) validate() error {
+	switch s.Frequency {
+	case FrequencyDaily:
+		s.Weekday = nil
+	case FrequencyWeekly:
+		if s.Weekday == nil || *s.Weekday < 0 || *s.Weekday > 6 {
+			return fmt.Errorf("%w: weekly digests need a weekday from 0 (Sunday) to 6", InvalidSubscription)
+		}
+	default:
+		return fmt.Errorf("%w: frequency must be daily or weekly", InvalidSubscription)
+	}
+
+	if s.Channel != ChannelEmail && s.Channel != ChannelWebhook {
+		return fmt.Errorf("%w: channel must be email or webhook", InvalidSubscription)
+	}
+	if s.Hour < 0 || s.Hour > 23 {
+		return fmt.Errorf("%w: hour must be from 0 to 23", InvalidSubscription)
+	}
+
+	if s.Timezone == "" {
+		s.Timezone = "UTC"
+	}
+	if _, err := time.LoadLocation(s.Timezone); err != nil {
+		return fmt.Errorf("%w: unknown timezone %q", InvalidSubscription, s.Timezone)
+	}
+
+	return nil
+}
+
+// Period is how far back a digest looks when it's never been sent before
+func Period(frequency string) time.Duration {
+	if frequency == FrequencyWeekly {
+		return 7 * 24 * time.Hour
+	}
+	return 24 * time.Hour
+}
+
+// due is true during the subscription's hour, unless it was already sent then
+func (s *Subscription) due(now time.Time) bool {
+	location, err := time.LoadLocation(s.Timezone)
+	if err != nil {
+		location = time.UTC
+	}
+	local := now.In(location)
+
+	if local.Hour() != s.Hour {
+		return false
+	}
+	if s.Weekday != nil && int(local.Weekday()) != *s.Weekday {
+		return false
+	}
+
+	return s.LastSentAt == nil || now.Sub(*s.LastSentAt) > 12*time.Hour
+}
+
+// Subscribe subscribes the user to digests of the organization
+func Subscribe(db *sql.DB, userID int32, orgID int64, subscription *Subscription) error {
+	if err := subscription.validate(); err != nil {
+		return err
+	}
+
+	subscription.OrgID = orgID
+	row := db.QueryRow(
+		`insert into digest_subscriptions (user_id, org_id, frequency, channel, hour, weekday, timezone, created_at)
+		values (?, ?, ?, ?, ?, ?, ?, datetime('now')) returning id, created_at`,
+		userID, orgID, subscription.Frequency, subscription.Channel, subscription.Hour, subscription.Weekday, subscription.Timezone,
+	)
+
+	return row.Scan(&subscription.ID, &subscription.CreatedAt)
+}
+
+// ListSubscriptions returns the user's subscriptions to digests of the organization
+func ListSubscriptions(db *sql.DB, userID int32, orgID int64) ([]Subscription, error) {
+	subscriptions, _, err := subscriptions(db, "where user_id = ? and org_id = ?", userID, orgID)
+	return subscriptions, err
+}
+
+func Unsubscribe(db *sql.DB, userID int32, orgID int64, subscriptionID int64) error {
+	result, err := db.Exec("delete from digest_subscriptions where id = ? and user_id = ? and org_id = ?", subscriptionID, userID, orgID)
+	if err != nil {
+		return err
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return SubscriptionNotFound
+	}
+
+	return nil
+}
+
+// subscriptions also returns the user id each subscription belongs to
+func subscriptions(db *sql.DB, where string, args ...any) ([]Subscription, []int32, error) {
+	rows, err := db.Query(
+		"select id, user_id, org_id, frequency, channel, hour, weekday, timezone, last_sent_at, created_at from digest_subscriptions "+where+" order by id",
+		args...,
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	subscriptions := []Subscription{}
+	userIDs := []int32{}
+	for rows.Next() {
+		subscription := Subscription{}
+		var userID int32
+		if err := rows.Scan(&subscription.ID, &userID, &subscription.OrgID, &subscription.Frequency, &subscription.Channel, &subscription.Hour, &subscription.Weekday, &subscription.Timezone, &subscription.LastSentAt, &subscription.CreatedAt); err != nil {
+			return nil, nil, err
+		}
+		subscriptions = append(subscriptions, subscription)
+		userIDs = append(userIDs, userID)
+	}
+
+	return subscriptions, userIDs, rows.Err()
+}
+
+// Run sends digests as they come due forever, checking once a minute. m can be nil if email isn't configured.
+func Run(db *sql.DB, m mailer.Mailer) {
+	for {
+		if err := SendDue(db, m, time.Now()); err != nil {
+			fmt.Fprintln(os.Stderr, "error sending digests", err)
+		}
+
+		time.Sleep(60 * time.Second)
+	}
+}
+
+// SendDue sends every digest that's due. Digests with nothing in them aren't sent, but still count as sent so that
+// the next one covers the period after this one.
+func SendDue(db *sql.DB, m mailer.Mailer, now time.Time) error {
+	// users who left an organization don't get its digests anymore
+	subscriptions, userIDs, err := subscriptions(db, "where exists(select 1 from organization_members where org_id = digest_subscriptions.org_id and user_id = digest_subscriptions.user_id)")
+	if err != nil {
+		return err
+	}
+
+	errs := []error{}
+	for i, subscription := range subscriptions {
+		if !subscription.due(now) {
+			continue
+		}
+
+		if err := send(db, m, userIDs[i], &subscription, now); err != nil {
+			errs = append(errs, fmt.Errorf("error sending digest %d: %w", subscription.ID, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func send(db *sql.DB, m mailer.Mailer, userID int32, subscription *Subscription, now time.Time) error {
+	since := now.Add(-Period(subscription.Frequency))
+	if subscription.LastSentAt != nil {
+		since = *subscription.LastSentAt
+	}
+
+	digest, err := Build(db, subscription.OrgID, subscription.Frequency, since, now)
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// marked as sent first, so that a digest that can't be sent isn't retried every minute for the rest of the hour
+	if _, err := tx.Exec("update digest_subscriptions set last_sent_at = ? where id = ?", now.UTC(), subscription.ID); err != nil {
+		return err
+	}
+	if digest.Empty() {
+		return tx.Commit()
+	}
+
+	switch subscription.Channel {
+	case ChannelWebhook:
+		if err := webhooks.EnqueueEvent(tx, userID, subscription.OrgID, webhooks.EventDigest, digest); err != nil {
+			return err
+		}
+		return tx.Commit()
+	default:
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+		return sendEmail(db, m, userID, digest)
+	}
+}
+
+func sendEmail(db *sql.DB, m mailer.Mailer, userID int32, digest *Digest) error {
+	if m == nil {
+		return mailer.NotConfigured
+	}
+
+	var email string
+	err := db.QueryRow("select email from users where id = ? and email is not null and email_verified_at is not null", userID).Scan(&email)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		// nobody to send it to
+		return nil
+	case err != nil:
+		return err
+	}
+
+	text := bytes.Buffer{}
+	if err := digestText.Execute(&text, digest); err != nil {
+		return err
+	}
+	html := bytes.Buffer{}
+	if err := digestHTML.Execute(&html, digest); err != nil {
+		return err
+	}
+
+	return m.Send(&mailer.Message{
+		To:      []string{email},
+		Subject: fmt.Sprintf("Your %s tracking digest", digest.Frequency),
+		Text:    text.String(),
+		HTML:    html.String(),
+	})
+}
@@ -15,7 +15,7 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/get_tracking": {
+        "/create_chat_webhook": {
             "post": {
                 "consumes": [
                     "application/json"
@@ -23,42 +23,66 @@ const docTemplate = `{
                 "produces": [
                     "application/json"
                 ],
-                "summary": "Gets the status of tracking numbers",
-                "operationId": "get-tracking-numbers",
+                "summary": "Registers a Slack or Microsoft Teams incoming webhook to post status changes to",
+                "operationId": "create-chat-webhook",
                 "parameters": [
                     {
-                        "description": "Tracking numbers",
-                        "name": "getTrackingInfo",
+                        "type": "string",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Organization, defaulting to the user's first",
+                        "name": "X-Organization-ID",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Chat webhook",
+                        "name": "createChatWebhook",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/api.getTracking"
+                            "$ref": "#/definitions/api.createChatWebhook"
                         }
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
+                    "201": {
+                        "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/api.trackingInfo"
+                            "$ref": "#/definitions/api.chatWebhookResp"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.chatWebhookResp"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorResp"
                         }
                     },
                     "403": {
                         "description": "Forbidden",
                         "schema": {
-                            "$ref": "#/definitions/api.trackingInfo"
+                            "$ref": "#/definitions/api.errorResp"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/api.trackingInfo"
+                            "$ref": "#/definitions/api.chatWebhookResp"
                         }
                     }
                 }
             }
         },
-        "/login": {
+        "/create_digest_subscription": {
             "post": {
                 "consumes": [
                     "application/json"
@@ -66,42 +90,66 @@ const docTemplate = `{
                 "produces": [
                     "application/json"
                 ],
-                "summary": "Verifies and logs in the user, returning a token",
-                "operationId": "login-user",
+                "summary": "Subscribes the user to a daily or weekly digest of what was delivered, is in transit, is late, or hit exceptions in each group",
+                "operationId": "create-digest-subscription",
                 "parameters": [
                     {
-                        "description": "Login Info",
-                        "name": "loginInfo",
+                        "type": "string",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Organization, defaulting to the user's first",
+                        "name": "X-Organization-ID",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Subscription",
+                        "name": "createDigestSubscription",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/api.loginInfo"
+                            "$ref": "#/definitions/api.createDigestSubscription"
                         }
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
+                    "201": {
+                        "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/api.loginResponse"
+                            "$ref": "#/definitions/api.digestSubscriptionResp"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.digestSubscriptionResp"
                         }
                     },
                     "401": {
                         "description": "Unauthorized",
                         "schema": {
-                            "$ref": "#/definitions/api.loginResponse"
+                            "$ref": "#/definitions/api.errorResp"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorResp"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/api.loginResponse"
+                            "$ref": "#/definitions/api.digestSubscriptionResp"
                         }
                     }
                 }
             }
         },
-        "/register": {
+        "/create_notification_rule": {
             "post": {
                 "consumes": [
                     "application/json"
@@ -109,16 +157,28 @@ const docTemplate = `{
                 "produces": [
                     "application/json"
                 ],
-                "summary": "Registers a new user",
-                "operationId": "register-user",
+                "summary": "Creates a rule picking which channels the user is notified on about which status changes. Once a user has any rules, only matching changes are sent",
+                "operationId": "create-notification-rule",
                 "parameters": [
                     {
-                        "description": "Registration Info",
-                        "name": "registrationInfo",
+                        "type": "string",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Organization, defaulting to the user's first",
+                        "name": "X-Organization-ID",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Rule",
+                        "name": "createNotificationRule",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/api.registrationInfo"
+                            "$ref": "#/definitions/api.createNotificationRule"
                         }
                     }
                 ],
@@ -126,25 +186,37 @@ const docTemplate = `{
                     "201": {
                         "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/api.registerResponse"
+                            "$ref": "#/definitions/api.notificationRuleResp"
                         }
                     },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/api.registerResponse"
+                            "$ref": "#/definitions/api.notificationRuleResp"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorResp"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorResp"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/api.registerResponse"
+                            "$ref": "#/definitions/api.notificationRuleResp"
                         }
                     }
                 }
             }
         },
-        "/start_tracking": {
+        "/create_webhook": {
             "post": {
                 "consumes": [
                     "application/json"
@@ -152,16 +224,28 @@ const docTemplate = `{
                 "produces": [
                     "application/json"
                 ],
-                "summary": "Starts tracking the package tracking numbers given by the user",
-                "operationId": "start-tracking-groups",
+                "summary": "Registers a URL to POST tracking status change events to",
+                "operationId": "create-webhook",
                 "parameters": [
                     {
-                        "description": "Tracking Info",
-                        "name": "startTrackingInfo",
+                        "type": "string",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Organization, defaulting to the user's first",
+                        "name": "X-Organization-ID",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Webhook",
+                        "name": "createWebhook",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/api.startTracking"
+                            "$ref": "#/definitions/api.createWebhook"
                         }
                     }
                 ],
@@ -169,126 +253,5335 @@ const docTemplate = `{
                     "201": {
                         "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/api.startTrackingResp"
+                            "$ref": "#/definitions/api.webhookResp"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.webhookResp"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorResp"
                         }
                     },
                     "403": {
                         "description": "Forbidden",
                         "schema": {
-                            "$ref": "#/definitions/api.startTrackingResp"
+                            "$ref": "#/definitions/api.errorResp"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/api.startTrackingResp"
+                            "$ref": "#/definitions/api.webhookResp"
                         }
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "api.getTracking": {
-            "type": "object",
-            "properties": {
-                "token": {
-                    "type": "string"
-                },
-                "tracking_numbers": {
-                    "type": "array",
-                    "items": {
-                        "type": "string"
-                    }
-                }
-            }
-        },
-        "api.loginInfo": {
-            "type": "object",
-            "properties": {
-                "password": {
-                    "type": "string"
-                },
-                "username": {
-                    "type": "string"
-                }
-            }
-        },
-        "api.loginResponse": {
-            "type": "object",
-            "properties": {
-                "error": {
-                    "type": "string"
-                },
-                "token": {
-                    "type": "string"
-                }
-            }
         },
-        "api.registerResponse": {
-            "type": "object",
-            "properties": {
-                "error": {
-                    "type": "string"
-                },
-                "token": {
-                    "type": "string"
+        "/delete_chat_webhook": {
+            "post": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Stops posting status changes to one of the user's Slack or Microsoft Teams webhooks",
+                "operationId": "delete-chat-webhook",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Organization, defaulting to the user's first",
+                        "name": "X-Organization-ID",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Chat webhook",
+                        "name": "deleteChatWebhook",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.deleteChatWebhook"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorResp"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorResp"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorResp"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorResp"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorResp"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorResp"
+                        }
+                    }
                 }
             }
         },
-        "api.registrationInfo": {
-            "type": "object",
-            "properties": {
-                "company": {
-                    "type": "string"
-                },
-                "password": {
-                    "type": "string"
-                },
-                "username": {
-                    "type": "string"
+        "/delete_digest_subscription": {
+            "post": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Unsubscribes the user from one of their digests",
+                "operationId": "delete-digest-subscription",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Organization, defaulting to the user's first",
+                        "name": "X-Organization-ID",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Subscription",
+                        "name": "deleteDigestSubscription",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.deleteDigestSubscription"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorResp"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorResp"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorResp"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorResp"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorResp"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorResp"
+                        }
+                    }
                 }
             }
         },
-        "api.startTracking": {
-            "type": "object",
-            "properties": {
+        "/delete_notification_rule": {
+            "post": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Deletes one of the user's notification rules",
+                "operationId": "delete-notification-rule",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Organization, defaulting to the user's first",
+                        "name": "X-Organization-ID",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Rule",
+                        "name": "deleteNotificationRule",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.deleteNotificationRule"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorResp"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorResp"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorResp"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorResp"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorResp"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorResp"
+                        }
+                    }
+                }
+            }
+        },
+        "/delete_webhook": {
+            "post": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Deletes one of the user's webhooks along with its delivery logs",
+                "operationId": "delete-webhook",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Organization, defaulting to the user's first",
+                        "name": "X-Organization-ID",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Webhook",
+                        "name": "deleteWebhook",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.deleteWebhook"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorResp"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorResp"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorResp"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorResp"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorResp"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorResp"
+                        }
+                    }
+                }
+            }
+        },
+        "/get_chat_webhooks": {
+            "post": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Lists the user's Slack and Microsoft Teams webhooks",
+                "operationId": "get-chat-webhooks",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Organization, defaulting to the user's first",
+                        "name": "X-Organization-ID",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Token",
+                        "name": "getChatWebhooks",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.getChatWebhooks"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.chatWebhooksResp"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.chatWebhooksResp"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorResp"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorResp"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.chatWebhooksResp"
+                        }
+                    }
+                }
+            }
+        },
+        "/get_digest": {
+            "post": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Builds the user's digest of the organization's packages for the last day or week, without sending it",
+                "operationId": "get-digest",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Organization, defaulting to the user's first",
+                        "name": "X-Organization-ID",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Frequency",
+                        "name": "getDigest",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.getDigest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.digestResp"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.digestResp"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorResp"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorResp"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.digestResp"
+                        }
+                    }
+                }
+            }
+        },
+        "/get_digest_subscriptions": {
+            "post": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Lists the user's digest subscriptions",
+                "operationId": "get-digest-subscriptions",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Organization, defaulting to the user's first",
+                        "name": "X-Organization-ID",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Token",
+                        "name": "getDigestSubscriptions",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.getDigestSubscriptions"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.digestSubscriptionsResp"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.digestSubscriptionsResp"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorResp"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorResp"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.digestSubscriptionsResp"
+                        }
+                    }
+                }
+            }
+        },
+        "/get_group_email_recipients": {
+            "post": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Gets the extra people emailed about status changes in each of the user's groups",
+                "operationId": "get-group-email-recipients",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Organization, defaulting to the user's first",
+                        "name": "X-Organization-ID",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Token",
+                        "name": "getGroupEmailRecipients",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.getGroupEmailRecipients"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.groupEmailRecipientsResp"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.groupEmailRecipientsResp"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorResp"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorResp"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.groupEmailRecipientsResp"
+                        }
+                    }
+                }
+            }
+        },
+        "/get_notification_log": {
+            "post": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Gets what was sent, or why it wasn't, for each of the user's status changes",
+                "operationId": "get-notification-log",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Organization, defaulting to the user's first",
+                        "name": "X-Organization-ID",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Cursor",
+                        "name": "getNotificationLog",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.getNotificationLog"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.notificationLogResp"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.notificationLogResp"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorResp"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorResp"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.notificationLogResp"
+                        }
+                    }
+                }
+            }
+        },
+        "/get_notification_rules": {
+            "post": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Lists the user's notification rules",
+                "operationId": "get-notification-rules",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Organization, defaulting to the user's first",
+                        "name": "X-Organization-ID",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Token",
+                        "name": "getNotificationRules",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.getNotificationRules"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.notificationRulesResp"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.notificationRulesResp"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorResp"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorResp"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.notificationRulesResp"
+                        }
+                    }
+                }
+            }
+        },
+        "/get_tracking": {
+            "post": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Gets the status of tracking numbers",
+                "operationId": "get-tracking-numbers",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Organization, defaulting to the user's first",
+                        "name": "X-Organization-ID",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Tracking numbers",
+                        "name": "getTrackingInfo",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.getTracking"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.trackingInfo"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.trackingInfo"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.trackingInfo"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.trackingInfo"
+                        }
+                    }
+                }
+            }
+        },
+        "/get_tracking_changes": {
+            "post": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Gets every status change of the organization's tracking numbers since the given cursor",
+                "operationId": "get-tracking-changes",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Organization, defaulting to the user's first",
+                        "name": "X-Organization-ID",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Cursor",
+                        "name": "getTrackingChanges",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.getTrackingChanges"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.trackingChangesResp"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.trackingChangesResp"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorResp"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorResp"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.trackingChangesResp"
+                        }
+                    }
+                }
+            }
+        },
+        "/get_webhook_deliveries": {
+            "post": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Gets the newest deliveries to one of the user's webhooks, including every attempt made",
+                "operationId": "get-webhook-deliveries",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Organization, defaulting to the user's first",
+                        "name": "X-Organization-ID",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Webhook",
+                        "name": "getWebhookDeliveries",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.getWebhookDeliveries"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.webhookDeliveriesResp"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.webhookDeliveriesResp"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorResp"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorResp"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.webhookDeliveriesResp"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.webhookDeliveriesResp"
+                        }
+                    }
+                }
+            }
+        },
+        "/get_webhooks": {
+            "post": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Lists the user's webhooks",
+                "operationId": "get-webhooks",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Organization, defaulting to the user's first",
+                        "name": "X-Organization-ID",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Token",
+                        "name": "getWebhooks",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.getWebhooks"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.webhooksResp"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.webhooksResp"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorResp"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorResp"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.webhooksResp"
+                        }
+                    }
+                }
+            }
+        },
+        "/login": {
+            "post": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Verifies and logs in the user, returning a token",
+                "operationId": "login-user",
+                "parameters": [
+                    {
+                        "description": "Login Info",
+                        "name": "loginInfo",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.loginInfo"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.loginResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.loginResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.loginResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/logout": {
+            "post": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Revokes the token and its refresh token, or every one of the user's tokens",
+                "operationId": "logout",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Logout",
+                        "name": "logout",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.logout"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorResp"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorResp"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorResp"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorResp"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorResp"
+                        }
+                    }
+                }
+            }
+        },
+        "/redeliver_webhook": {
+            "post": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Queues a webhook delivery to be sent again right away",
+                "operationId": "redeliver-webhook",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Organization, defaulting to the user's first",
+                        "name": "X-Organization-ID",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Delivery",
+                        "name": "redeliverWebhook",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.redeliverWebhook"
+                        }
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorResp"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorResp"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorResp"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorResp"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorResp"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorResp"
+                        }
+                    }
+                }
+            }
+        },
+        "/refresh": {
+            "post": {
+                "description": "Each refresh token can only be used once. Using one again logs its session out, since it means someone else has a copy of it.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Swaps a refresh token for a new token and refresh token",
+                "operationId": "refresh",
+                "parameters": [
+                    {
+                        "description": "Refresh token",
+                        "name": "refresh",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.refresh"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.loginResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.loginResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.loginResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.loginResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/register": {
+            "post": {
+                "description": "Users join the organization their invite_token is for, or get their own named after company.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Registers a new user",
+                "operationId": "register-user",
+                "parameters": [
+                    {
+                        "description": "Registration Info",
+                        "name": "registrationInfo",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.registrationInfo"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/api.registerResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.registerResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.registerResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/set_email": {
+            "post": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Sets the user's email address and sends them a link to verify it. Notifications aren't emailed until it's verified",
+                "operationId": "set-email",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Email",
+                        "name": "setEmail",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.setEmail"
+                        }
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorResp"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorResp"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorResp"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorResp"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorResp"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorResp"
+                        }
+                    }
+                }
+            }
+        },
+        "/set_group_email_recipients": {
+            "post": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Replaces the list of extra people emailed about status changes in a group",
+                "operationId": "set-group-email-recipients",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Organization, defaulting to the user's first",
+                        "name": "X-Organization-ID",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Recipients",
+                        "name": "setGroupEmailRecipients",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.setGroupEmailRecipients"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorResp"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorResp"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorResp"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorResp"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorResp"
+                        }
+                    }
+                }
+            }
+        },
+        "/set_phone": {
+            "post": {
+                "description": "Opting a new number in texts it a code, and nothing else is texted to it until the code is sent to verify_phone. Numbers that replied STOP can't be opted back in until they reply START.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Sets the phone number the user is texted at when their packages are out for delivery, and whether they want texts at all",
+                "operationId": "set-phone",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Phone",
+                        "name": "setPhone",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.setPhone"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorResp"
+                        }
+                    },
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorResp"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorResp"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorResp"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorResp"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorResp"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorResp"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorResp"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorResp"
+                        }
+                    }
+                }
+            }
+        },
+        "/sms/inbound": {
+            "post": {
+                "consumes": [
+                    "application/x-www-form-urlencoded"
+                ],
+                "produces": [
+                    "text/xml"
+                ],
+                "summary": "Twilio compatible webhook for inbound texts, which opts the sender out of texts if they reply STOP, or back in if they reply START",
+                "operationId": "sms-inbound",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Sender's phone number",
+                        "name": "From",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Text",
+                        "name": "Body",
+                        "in": "formData",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    },
+                    "403": {
+                        "description": "Forbidden"
+                    },
+                    "500": {
+                        "description": "Internal Server Error"
+                    },
+                    "503": {
+                        "description": "Service Unavailable"
+                    }
+                }
+            }
+        },
+        "/start_tracking": {
+            "post": {
+                "description": "Numbers that are already tracked are moved into the group they're sent with, so sending the same numbers again is harmless. Send an Idempotency-Key header to have a retried request get the first one's response back.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Starts tracking the package tracking numbers given by the user",
+                "operationId": "start-tracking-groups",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Organization, defaulting to the user's first",
+                        "name": "X-Organization-ID",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Any unique string, like a UUID",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Tracking Info",
+                        "name": "startTrackingInfo",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.startTracking"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/api.startTrackingResp"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.startTrackingResp"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.startTrackingResp"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.startTrackingResp"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.startTrackingResp"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/api.startTrackingResp"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.startTrackingResp"
+                        }
+                    }
+                }
+            }
+        },
+        "/trackings/stream": {
+            "get": {
+                "description": "Each event has the change's id, which browsers send back as Last-Event-ID when they reconnect so that no changes are missed. Without one, only changes after connecting are sent. A comment is sent every 15 seconds to keep the connection open.",
+                "produces": [
+                    "text/event-stream"
+                ],
+                "summary": "Streams the status changes of the organization's tracking numbers as server-sent events",
+                "operationId": "stream-tracking-changes",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Organization, defaulting to the user's first",
+                        "name": "X-Organization-ID",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Token, for clients that can't set headers",
+                        "name": "token",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Id of the last change the client saw",
+                        "name": "Last-Event-ID",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Id of the last change the client saw, for clients that can't set headers",
+                        "name": "last_event_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/history.Change"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorResp"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorResp"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorResp"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorResp"
+                        }
+                    }
+                }
+            }
+        },
+        "/trackings/ws": {
+            "get": {
+                "description": "Send {\"action\": \"subscribe\" or \"unsubscribe\", \"tracking_numbers\": [...], \"group_names\": [...]} to change what's watched, which is answered with a subscribed message listing everything being watched. Subscribing past 1000 numbers and groups is answered with an error instead. Each change is sent as {\"type\": \"status_changed\", \"change\": {...}}. Clients that fall too far behind are disconnected with a 1013 close code, and should catch up with get_tracking_changes before resubscribing.",
+                "summary": "Opens a WebSocket that sends status changes for the organization's tracking numbers and groups the client subscribes to",
+                "operationId": "tracking-websocket",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Organization, defaulting to the user's first",
+                        "name": "X-Organization-ID",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Token, for clients that can't set headers",
+                        "name": "token",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "101": {
+                        "description": "Switching Protocols",
+                        "schema": {
+                            "$ref": "#/definitions/api.wsMessage"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorResp"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorResp"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorResp"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/api_keys": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Lists the user's API keys, without the keys themselves",
+                "operationId": "list-api-keys",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.apiKeysResp"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorEnvelope"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorEnvelope"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorEnvelope"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Keys act in the organization they're created in, with no more than the user's role in it. Scopes are read, write:trackings and manage:webhooks. Keys restricted to a group only see and change the numbers in it, and can't use endpoints that work across groups. The key is only shown in this response.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Creates an API key for a machine client",
+                "operationId": "create-api-key",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Organization, defaulting to the user's first",
+                        "name": "X-Organization-ID",
+                        "in": "header"
+                    },
+                    {
+                        "description": "API key",
+                        "name": "newAPIKey",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/auth.NewAPIKey"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/api.apiKeyResp"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorEnvelope"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorEnvelope"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorEnvelope"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorEnvelope"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/api_keys/{id}": {
+            "delete": {
+                "summary": "Revokes an API key",
+                "operationId": "revoke-api-key",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "API key id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorEnvelope"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorEnvelope"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorEnvelope"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorEnvelope"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorEnvelope"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/groups": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Lists the organization's groups with how many of their numbers are in each status",
+                "operationId": "list-groups",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Organization, defaulting to the user's first",
+                        "name": "X-Organization-ID",
+                        "in": "header"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Include archived groups",
+                        "name": "include_archived",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.groupsResp"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorEnvelope"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorEnvelope"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorEnvelope"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorEnvelope"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Creates a group. Groups are also created when numbers are added to a group that doesn't exist yet",
+                "operationId": "create-group",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Organization, defaulting to the user's first",
+                        "name": "X-Organization-ID",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Group",
+                        "name": "createGroup",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.createGroup"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/trackings.Group"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorEnvelope"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorEnvelope"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorEnvelope"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorEnvelope"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorEnvelope"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/groups/{id}": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Gets one of the organization's groups with how many of its numbers are in each status",
+                "operationId": "get-group",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Organization, defaulting to the user's first",
+                        "name": "X-Organization-ID",
+                        "in": "header"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Group id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/trackings.Group"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorEnvelope"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorEnvelope"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorEnvelope"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorEnvelope"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorEnvelope"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Renames, archives or unarchives a group",
+                "operationId": "update-group",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Organization, defaulting to the user's first",
+                        "name": "X-Organization-ID",
+                        "in": "header"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Group id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Fields to change",
+                        "name": "updateGroup",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.updateGroup"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/trackings.Group"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorEnvelope"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorEnvelope"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorEnvelope"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorEnvelope"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorEnvelope"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorEnvelope"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/groups/{id}/trackings": {
+            "post": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Moves tracking numbers, or every number in another group, into a group",
+                "operationId": "move-to-group",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Organization, defaulting to the user's first",
+                        "name": "X-Organization-ID",
+                        "in": "header"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Group id to move the numbers to",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Numbers to move",
+                        "name": "moveToGroup",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.moveToGroup"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.moveToGroupResp"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorEnvelope"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorEnvelope"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorEnvelope"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorEnvelope"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorEnvelope"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/invitations/accept": {
+            "post": {
+                "description": "Anyone logged in with the token can accept it, once. Send the organization's id in the X-Organization-ID header to act in it.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Joins the organization an invite token is for",
+                "operationId": "accept-invitation",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "Invite token",
+                        "name": "answerInvitation",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.answerInvitation"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/orgs.Organization"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorEnvelope"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorEnvelope"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorEnvelope"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorEnvelope"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorEnvelope"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/invitations/decline": {
+            "post": {
+                "consumes": [
+                    "application/json"
+                ],
+                "summary": "Turns down the invitation an invite token is for. It doesn't need logging in",
+                "operationId": "decline-invitation",
+                "parameters": [
+                    {
+                        "description": "Invite token",
+                        "name": "answerInvitation",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.answerInvitation"
+                        }
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorEnvelope"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorEnvelope"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/organization/invitations": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Lists the invitations to the organization the request acts in that haven't been answered or expired",
+                "operationId": "list-invitations",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Organization, defaulting to the user's first",
+                        "name": "X-Organization-ID",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.invitationsResp"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorEnvelope"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorEnvelope"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorEnvelope"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Needs the admin role or above, and only owners can invite other owners. The email has an invite token that works for 7 days, and inviting the same email again replaces it.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Emails someone an invitation to join the organization the request acts in",
+                "operationId": "create-invitation",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Organization, defaulting to the user's first",
+                        "name": "X-Organization-ID",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Invitation",
+                        "name": "createInvitation",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.createInvitation"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/orgs.Invitation"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorEnvelope"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorEnvelope"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorEnvelope"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorEnvelope"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorEnvelope"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/organization/invitations/{id}": {
+            "delete": {
+                "summary": "Revokes an invitation that hasn't been answered, so its invite token stops working",
+                "operationId": "revoke-invitation",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Organization, defaulting to the user's first",
+                        "name": "X-Organization-ID",
+                        "in": "header"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Invitation id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorEnvelope"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorEnvelope"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorEnvelope"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorEnvelope"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorEnvelope"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/organization/members": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Lists the members of the organization the request acts in",
+                "operationId": "list-members",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Organization, defaulting to the user's first",
+                        "name": "X-Organization-ID",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.membersResp"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorEnvelope"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorEnvelope"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorEnvelope"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/organization/members/{user_id}": {
+            "delete": {
+                "description": "Needs the admin role or above, and only owners can remove owners. The member's API keys for the organization are deleted and they're logged out everywhere.",
+                "summary": "Removes a member from the organization the request acts in",
+                "operationId": "remove-member",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Organization, defaulting to the user's first",
+                        "name": "X-Organization-ID",
+                        "in": "header"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Member's user id",
+                        "name": "user_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorEnvelope"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorEnvelope"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorEnvelope"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorEnvelope"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorEnvelope"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "description": "Needs the admin role or above. Only owners can change owners or make new ones, and the last owner can't stop being one.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Changes a member's role in the organization the request acts in",
+                "operationId": "update-member",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Organization, defaulting to the user's first",
+                        "name": "X-Organization-ID",
+                        "in": "header"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Member's user id",
+                        "name": "user_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Role",
+                        "name": "updateMember",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.updateMember"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/orgs.Member"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorEnvelope"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorEnvelope"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorEnvelope"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorEnvelope"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorEnvelope"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/organizations": {
+            "get": {
+                "description": "Requests act in the first organization, unless another's id is sent in the X-Organization-ID header.",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Lists the organizations the user is in, with their role in each",
+                "operationId": "list-organizations",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.organizationsResp"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorEnvelope"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorEnvelope"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorEnvelope"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/password": {
+            "put": {
+                "description": "Every other session is logged out, but the one making the request stays logged in. API keys keep working.",
+                "consumes": [
+                    "application/json"
+                ],
+                "summary": "Changes the user's password",
+                "operationId": "change-password",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "Passwords",
+                        "name": "changePassword",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.changePassword"
+                        }
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorEnvelope"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorEnvelope"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorEnvelope"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorEnvelope"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/password/forgot": {
+            "post": {
+                "description": "The response is the same whether or not any account has the email, and the token works once, for an hour.",
+                "consumes": [
+                    "application/json"
+                ],
+                "summary": "Emails a password reset token to the accounts with this verified email",
+                "operationId": "forgot-password",
+                "parameters": [
+                    {
+                        "description": "Email",
+                        "name": "forgotPassword",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.forgotPassword"
+                        }
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Accepted"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorEnvelope"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorEnvelope"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorEnvelope"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/password/reset": {
+            "post": {
+                "consumes": [
+                    "application/json"
+                ],
+                "summary": "Sets a new password with a reset token from /v1/password/forgot, logging every session out and deleting every API key",
+                "operationId": "reset-password",
+                "parameters": [
+                    {
+                        "description": "Reset token and new password",
+                        "name": "resetPassword",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.resetPassword"
+                        }
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorEnvelope"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorEnvelope"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/trackings": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Lists the tracking numbers the organization is tracking, a page at a time",
+                "operationId": "list-trackings",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Organization, defaulting to the user's first",
+                        "name": "X-Organization-ID",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "false leaves out archived numbers, true only has archived numbers, and any has both",
+                        "name": "archived",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "name": "carrier",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "name": "delivered_before",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only numbers delivered in [delivered_from, delivered_before)",
+                        "name": "delivered_from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "name": "eta_before",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "name": "eta_from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "name": "group",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Searches tracking numbers and notes",
+                        "name": "q",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "tracking_number, created_at, eta or status_last_updated, with a - in front to sort descending",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "csv",
+                        "description": "Canonical statuses, either repeated or comma separated",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "RFC 3339 timestamps",
+                        "name": "updated_since",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.trackingsResp"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorEnvelope"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorEnvelope"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorEnvelope"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorEnvelope"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Starts tracking a tracking number",
+                "operationId": "create-tracking",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Organization, defaulting to the user's first",
+                        "name": "X-Organization-ID",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Tracking number",
+                        "name": "createTracking",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.createTracking"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/trackings.Tracking"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorEnvelope"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorEnvelope"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorEnvelope"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorEnvelope"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorEnvelope"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorEnvelope"
+                        }
+                    },
+                    "502": {
+                        "description": "Bad Gateway",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorEnvelope"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/trackings/archive": {
+            "post": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Archives tracking numbers, or every number in a group. Archived numbers keep their history, but aren't polled and are left out of lists by default",
+                "operationId": "archive-trackings",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Organization, defaulting to the user's first",
+                        "name": "X-Organization-ID",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Numbers to archive",
+                        "name": "selection",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/trackings.Selection"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.archiveResp"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorEnvelope"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorEnvelope"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorEnvelope"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorEnvelope"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorEnvelope"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/trackings/delete": {
+            "post": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Stops tracking tracking numbers, or every number in a group, and deletes their history",
+                "operationId": "delete-trackings",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Organization, defaulting to the user's first",
+                        "name": "X-Organization-ID",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Numbers to stop tracking",
+                        "name": "selection",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/trackings.Selection"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.deleteResp"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorEnvelope"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorEnvelope"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorEnvelope"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorEnvelope"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorEnvelope"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/trackings/export": {
+            "get": {
+                "description": "Each row has the number's status, ETA, when it was delivered, its carrier and how many status changes it's had. Archived numbers are included unless archived is false. The file is streamed as it's read, so an error partway through cuts the download short.",
+                "produces": [
+                    "text/csv",
+                    "application/x-ndjson",
+                    "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+                ],
+                "summary": "Downloads the organization's tracking numbers as CSV, NDJSON or XLSX",
+                "operationId": "export-trackings",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Organization, defaulting to the user's first",
+                        "name": "X-Organization-ID",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "false leaves out archived numbers, true only has archived numbers, and any has both",
+                        "name": "archived",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "name": "carrier",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "name": "delivered_before",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only numbers delivered in [delivered_from, delivered_before)",
+                        "name": "delivered_from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "name": "eta_before",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "name": "eta_from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "csv, ndjson or xlsx. Defaults to csv",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "name": "group",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Searches tracking numbers and notes",
+                        "name": "q",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "csv",
+                        "description": "Canonical statuses, either repeated or comma separated",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "RFC 3339 timestamps",
+                        "name": "updated_since",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorEnvelope"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorEnvelope"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorEnvelope"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorEnvelope"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/trackings/import": {
+            "post": {
+                "description": "The first row names the columns: a tracking number column, and optionally carrier, group and notes (or reference) columns. Each row is imported on its own, and the report says what happened to every row, including rows the carrier couldn't be asked about.",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Starts tracking every number in a CSV or XLSX file",
+                "operationId": "import-trackings",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Organization, defaulting to the user's first",
+                        "name": "X-Organization-ID",
+                        "in": "header"
+                    },
+                    {
+                        "type": "file",
+                        "description": "A .csv or .xlsx file",
+                        "name": "file",
+                        "in": "formData",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/trackings.ImportReport"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorEnvelope"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorEnvelope"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorEnvelope"
+                        }
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorEnvelope"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorEnvelope"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/trackings/unarchive": {
+            "post": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Unarchives tracking numbers, or every number in a group, so they're polled again",
+                "operationId": "unarchive-trackings",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Organization, defaulting to the user's first",
+                        "name": "X-Organization-ID",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Numbers to unarchive",
+                        "name": "selection",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/trackings.Selection"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.unarchiveResp"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorEnvelope"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorEnvelope"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorEnvelope"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorEnvelope"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorEnvelope"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/trackings/{number}": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Gets one of the organization's tracking numbers",
+                "operationId": "get-tracking",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Organization, defaulting to the user's first",
+                        "name": "X-Organization-ID",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Tracking number",
+                        "name": "number",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/trackings.Tracking"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorEnvelope"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorEnvelope"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorEnvelope"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorEnvelope"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "summary": "Stops tracking a tracking number and deletes its history",
+                "operationId": "delete-tracking",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Organization, defaulting to the user's first",
+                        "name": "X-Organization-ID",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Tracking number",
+                        "name": "number",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorEnvelope"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorEnvelope"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorEnvelope"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorEnvelope"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Changes a tracking number's group or notes, or archives it. Fields that are left out aren't changed, and empty strings clear them",
+                "operationId": "update-tracking",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Organization, defaulting to the user's first",
+                        "name": "X-Organization-ID",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Tracking number",
+                        "name": "number",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Fields to change",
+                        "name": "patch",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/trackings.Patch"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/trackings.Tracking"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorEnvelope"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorEnvelope"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorEnvelope"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorEnvelope"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorEnvelope"
+                        }
+                    }
+                }
+            }
+        },
+        "/verify_email": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Verifies the user's email address using the link emailed to them",
+                "operationId": "verify-email",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Verification token",
+                        "name": "token",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorResp"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorResp"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorResp"
+                        }
+                    }
+                }
+            }
+        },
+        "/verify_phone": {
+            "post": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Verifies the user's phone number with the code texted to it, after which it's texted about their packages",
+                "operationId": "verify-phone",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Code",
+                        "name": "verifyPhone",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.verifyPhone"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorResp"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorResp"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorResp"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorResp"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.errorResp"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "api.answerInvitation": {
+            "type": "object",
+            "properties": {
+                "invite_token": {
+                    "description": "The invite token from the email",
+                    "type": "string"
+                }
+            }
+        },
+        "api.apiError": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "description": "Stable, machine readable, like not_found",
+                    "type": "string"
+                },
+                "message": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.apiKeyResp": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "group_id": {
+                    "description": "Restricts the key to the numbers in this group",
+                    "type": "integer"
+                },
+                "group_name": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "key": {
+                    "description": "The key itself, which is only ever shown when it's created",
+                    "type": "string"
+                },
+                "last_used_at": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "org_id": {
+                    "description": "The organization the key acts in",
+                    "type": "integer"
+                },
+                "prefix": {
+                    "description": "The start of the key, to tell keys apart",
+                    "type": "string"
+                },
+                "scopes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "api.apiKeysResp": {
+            "type": "object",
+            "properties": {
+                "api_keys": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/auth.APIKey"
+                    }
+                }
+            }
+        },
+        "api.archiveResp": {
+            "type": "object",
+            "properties": {
+                "archived": {
+                    "type": "integer"
+                }
+            }
+        },
+        "api.changePassword": {
+            "type": "object",
+            "properties": {
+                "current_password": {
+                    "type": "string"
+                },
+                "new_password": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.chatWebhookResp": {
+            "type": "object",
+            "properties": {
+                "chat_webhook": {
+                    "$ref": "#/definitions/notify.ChatWebhook"
+                },
+                "error": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.chatWebhooksResp": {
+            "type": "object",
+            "properties": {
+                "chat_webhooks": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/notify.ChatWebhook"
+                    }
+                },
+                "error": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.createChatWebhook": {
+            "type": "object",
+            "properties": {
+                "group_name": {
+                    "description": "Only post about this group, empty means every group",
+                    "type": "string"
+                },
+                "kind": {
+                    "description": "slack or teams",
+                    "type": "string"
+                },
+                "statuses": {
+                    "description": "Canonical statuses to post about, empty means every status",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "token": {
+                    "description": "Deprecated: send an Authorization: Bearer header instead",
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.createDigestSubscription": {
+            "type": "object",
+            "properties": {
+                "subscription": {
+                    "$ref": "#/definitions/digest.Subscription"
+                },
+                "token": {
+                    "description": "Deprecated: send an Authorization: Bearer header instead",
+                    "type": "string"
+                }
+            }
+        },
+        "api.createGroup": {
+            "type": "object",
+            "properties": {
+                "name": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.createInvitation": {
+            "type": "object",
+            "properties": {
+                "email": {
+                    "type": "string"
+                },
+                "role": {
+                    "description": "owner, admin, member or viewer",
+                    "type": "string"
+                }
+            }
+        },
+        "api.createNotificationRule": {
+            "type": "object",
+            "properties": {
+                "rule": {
+                    "$ref": "#/definitions/notify.Rule"
+                },
+                "token": {
+                    "description": "Deprecated: send an Authorization: Bearer header instead",
+                    "type": "string"
+                }
+            }
+        },
+        "api.createTracking": {
+            "type": "object",
+            "properties": {
+                "group_name": {
+                    "type": "string"
+                },
+                "notes": {
+                    "type": "string"
+                },
+                "tracking_number": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.createWebhook": {
+            "type": "object",
+            "properties": {
+                "group_names": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "secret": {
+                    "description": "Generated if left empty",
+                    "type": "string"
+                },
+                "statuses": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "token": {
+                    "description": "Deprecated: send an Authorization: Bearer header instead",
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.deleteChatWebhook": {
+            "type": "object",
+            "properties": {
+                "chat_webhook_id": {
+                    "type": "integer"
+                },
+                "token": {
+                    "description": "Deprecated: send an Authorization: Bearer header instead",
+                    "type": "string"
+                }
+            }
+        },
+        "api.deleteDigestSubscription": {
+            "type": "object",
+            "properties": {
+                "subscription_id": {
+                    "type": "integer"
+                },
+                "token": {
+                    "description": "Deprecated: send an Authorization: Bearer header instead",
+                    "type": "string"
+                }
+            }
+        },
+        "api.deleteNotificationRule": {
+            "type": "object",
+            "properties": {
+                "rule_id": {
+                    "type": "integer"
+                },
+                "token": {
+                    "description": "Deprecated: send an Authorization: Bearer header instead",
+                    "type": "string"
+                }
+            }
+        },
+        "api.deleteResp": {
+            "type": "object",
+            "properties": {
+                "deleted": {
+                    "type": "integer"
+                }
+            }
+        },
+        "api.deleteWebhook": {
+            "type": "object",
+            "properties": {
+                "token": {
+                    "description": "Deprecated: send an Authorization: Bearer header instead",
+                    "type": "string"
+                },
+                "webhook_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "api.digestResp": {
+            "type": "object",
+            "properties": {
+                "digest": {
+                    "$ref": "#/definitions/digest.Digest"
+                },
+                "error": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.digestSubscriptionResp": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "subscription": {
+                    "$ref": "#/definitions/digest.Subscription"
+                }
+            }
+        },
+        "api.digestSubscriptionsResp": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "subscriptions": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/digest.Subscription"
+                    }
+                }
+            }
+        },
+        "api.errorEnvelope": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "$ref": "#/definitions/api.apiError"
+                }
+            }
+        },
+        "api.errorResp": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.forgotPassword": {
+            "type": "object",
+            "properties": {
+                "email": {
+                    "description": "The user's verified email",
+                    "type": "string"
+                }
+            }
+        },
+        "api.getChatWebhooks": {
+            "type": "object",
+            "properties": {
+                "token": {
+                    "description": "Deprecated: send an Authorization: Bearer header instead",
+                    "type": "string"
+                }
+            }
+        },
+        "api.getDigest": {
+            "type": "object",
+            "properties": {
+                "frequency": {
+                    "description": "daily or weekly, defaults to daily",
+                    "type": "string"
+                },
+                "token": {
+                    "description": "Deprecated: send an Authorization: Bearer header instead",
+                    "type": "string"
+                }
+            }
+        },
+        "api.getDigestSubscriptions": {
+            "type": "object",
+            "properties": {
+                "token": {
+                    "description": "Deprecated: send an Authorization: Bearer header instead",
+                    "type": "string"
+                }
+            }
+        },
+        "api.getGroupEmailRecipients": {
+            "type": "object",
+            "properties": {
+                "token": {
+                    "description": "Deprecated: send an Authorization: Bearer header instead",
+                    "type": "string"
+                }
+            }
+        },
+        "api.getNotificationLog": {
+            "type": "object",
+            "properties": {
+                "cursor": {
+                    "description": "Cursor returned by the previous call, or 0 to start from the beginning",
+                    "type": "integer"
+                },
+                "limit": {
+                    "type": "integer"
+                },
+                "token": {
+                    "description": "Deprecated: send an Authorization: Bearer header instead",
+                    "type": "string"
+                }
+            }
+        },
+        "api.getNotificationRules": {
+            "type": "object",
+            "properties": {
+                "token": {
+                    "description": "Deprecated: send an Authorization: Bearer header instead",
+                    "type": "string"
+                }
+            }
+        },
+        "api.getTracking": {
+            "type": "object",
+            "properties": {
+                "token": {
+                    "description": "Deprecated: send an Authorization: Bearer header instead",
+                    "type": "string"
+                },
+                "tracking_numbers": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "api.getTrackingChanges": {
+            "type": "object",
+            "properties": {
+                "cursor": {
+                    "description": "Cursor returned by the previous call, or 0 to get every change",
+                    "type": "integer"
+                },
+                "limit": {
+                    "type": "integer"
+                },
+                "token": {
+                    "description": "Deprecated: send an Authorization: Bearer header instead",
+                    "type": "string"
+                }
+            }
+        },
+        "api.getWebhookDeliveries": {
+            "type": "object",
+            "properties": {
+                "limit": {
+                    "type": "integer"
+                },
+                "token": {
+                    "description": "Deprecated: send an Authorization: Bearer header instead",
+                    "type": "string"
+                },
+                "webhook_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "api.getWebhooks": {
+            "type": "object",
+            "properties": {
+                "token": {
+                    "description": "Deprecated: send an Authorization: Bearer header instead",
+                    "type": "string"
+                }
+            }
+        },
+        "api.groupEmailRecipientsResp": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "recipients": {
+                    "description": "Group name to the emails on its list",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "api.groupsResp": {
+            "type": "object",
+            "properties": {
+                "groups": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/trackings.Group"
+                    }
+                }
+            }
+        },
+        "api.invitationsResp": {
+            "type": "object",
+            "properties": {
+                "invitations": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/orgs.Invitation"
+                    }
+                }
+            }
+        },
+        "api.loginInfo": {
+            "type": "object",
+            "properties": {
+                "password": {
+                    "type": "string"
+                },
+                "username": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.loginResponse": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "expires_at": {
+                    "description": "When the token expires, before which the refresh token should be swapped for new ones with /refresh",
+                    "type": "string"
+                },
+                "refresh_token": {
+                    "type": "string"
+                },
+                "token": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.logout": {
+            "type": "object",
+            "properties": {
+                "everywhere": {
+                    "description": "Log out every session rather than just this one",
+                    "type": "boolean"
+                },
+                "token": {
+                    "description": "Deprecated: send an Authorization: Bearer header instead",
+                    "type": "string"
+                }
+            }
+        },
+        "api.membersResp": {
+            "type": "object",
+            "properties": {
+                "members": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/orgs.Member"
+                    }
+                }
+            }
+        },
+        "api.moveToGroup": {
+            "type": "object",
+            "properties": {
+                "from_group_id": {
+                    "description": "Also moves every number in this group, which merges it into the group being moved to",
+                    "type": "integer"
+                },
+                "tracking_numbers": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "api.moveToGroupResp": {
+            "type": "object",
+            "properties": {
+                "moved": {
+                    "type": "integer"
+                }
+            }
+        },
+        "api.notificationLogResp": {
+            "type": "object",
+            "properties": {
+                "cursor": {
+                    "description": "Pass this as the cursor on the next call to only get newer entries",
+                    "type": "integer"
+                },
+                "entries": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/notify.LogEntry"
+                    }
+                },
+                "error": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.notificationRuleResp": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "rule": {
+                    "$ref": "#/definitions/notify.Rule"
+                }
+            }
+        },
+        "api.notificationRulesResp": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "rules": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/notify.Rule"
+                    }
+                }
+            }
+        },
+        "api.organizationsResp": {
+            "type": "object",
+            "properties": {
+                "organizations": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/orgs.Organization"
+                    }
+                }
+            }
+        },
+        "api.redeliverWebhook": {
+            "type": "object",
+            "properties": {
+                "delivery_id": {
+                    "type": "integer"
+                },
+                "token": {
+                    "description": "Deprecated: send an Authorization: Bearer header instead",
+                    "type": "string"
+                }
+            }
+        },
+        "api.refresh": {
+            "type": "object",
+            "properties": {
+                "refresh_token": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.registerResponse": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "expires_at": {
+                    "description": "When the token expires, before which the refresh token should be swapped for new ones with /refresh",
+                    "type": "string"
+                },
+                "refresh_token": {
+                    "type": "string"
+                },
+                "token": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.registrationInfo": {
+            "type": "object",
+            "properties": {
+                "company": {
+                    "description": "Names the organization the user gets, defaulting to their username",
+                    "type": "string"
+                },
+                "invite_token": {
+                    "description": "Joins the organization that emailed this invite token instead",
+                    "type": "string"
+                },
+                "password": {
+                    "type": "string"
+                },
+                "username": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.resetPassword": {
+            "type": "object",
+            "properties": {
+                "new_password": {
+                    "type": "string"
+                },
+                "reset_token": {
+                    "description": "The token from the email",
+                    "type": "string"
+                }
+            }
+        },
+        "api.setEmail": {
+            "type": "object",
+            "properties": {
+                "email": {
+                    "type": "string"
+                },
+                "token": {
+                    "description": "Deprecated: send an Authorization: Bearer header instead",
+                    "type": "string"
+                }
+            }
+        },
+        "api.setGroupEmailRecipients": {
+            "type": "object",
+            "properties": {
+                "emails": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "group_name": {
+                    "type": "string"
+                },
+                "token": {
+                    "description": "Deprecated: send an Authorization: Bearer header instead",
+                    "type": "string"
+                }
+            }
+        },
+        "api.setPhone": {
+            "type": "object",
+            "properties": {
+                "phone": {
+                    "description": "E.164 format, like +15555550123. Empty removes the user's phone number",
+                    "type": "string"
+                },
+                "sms_opt_in": {
+                    "type": "boolean"
+                },
+                "token": {
+                    "description": "Deprecated: send an Authorization: Bearer header instead",
+                    "type": "string"
+                }
+            }
+        },
+        "api.startTracking": {
+            "type": "object",
+            "properties": {
+                "token": {
+                    "description": "Deprecated: send an Authorization: Bearer header instead",
+                    "type": "string"
+                },
+                "tracking_number_groups": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/api.trackingNumberGroup"
+                    }
+                }
+            }
+        },
+        "api.startTrackingResp": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "results": {
+                    "description": "What happened to each number, in the order they were sent",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/trackings.StartResult"
+                    }
+                }
+            }
+        },
+        "api.trackingChangesResp": {
+            "type": "object",
+            "properties": {
+                "changes": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/history.Change"
+                    }
+                },
+                "cursor": {
+                    "description": "Pass this as the cursor on the next call to only get newer changes",
+                    "type": "integer"
+                },
+                "error": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.trackingInfo": {
+            "type": "object",
+            "properties": {
+                "tracking_info": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "api.trackingNumberGroup": {
+            "type": "object",
+            "properties": {
+                "group_name": {
+                    "type": "string"
+                },
+                "tracking_numbers": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "api.trackingsResp": {
+            "type": "object",
+            "properties": {
+                "next_cursor": {
+                    "description": "Pass this as the cursor to get the next page, empty on the last page",
+                    "type": "string"
+                },
+                "trackings": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/trackings.Tracking"
+                    }
+                }
+            }
+        },
+        "api.unarchiveResp": {
+            "type": "object",
+            "properties": {
+                "unarchived": {
+                    "type": "integer"
+                }
+            }
+        },
+        "api.updateGroup": {
+            "type": "object",
+            "properties": {
+                "archived": {
+                    "description": "Archived groups are left out of the group list by default",
+                    "type": "boolean"
+                },
+                "name": {
+                    "description": "Renames the group everywhere it's used, like in notification rules and webhook filters",
+                    "type": "string"
+                }
+            }
+        },
+        "api.updateMember": {
+            "type": "object",
+            "properties": {
+                "role": {
+                    "description": "owner, admin, member or viewer",
+                    "type": "string"
+                }
+            }
+        },
+        "api.verifyPhone": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string"
+                },
                 "token": {
+                    "description": "Deprecated: send an Authorization: Bearer header instead",
+                    "type": "string"
+                }
+            }
+        },
+        "api.webhookDeliveriesResp": {
+            "type": "object",
+            "properties": {
+                "deliveries": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/webhooks.Delivery"
+                    }
+                },
+                "error": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.webhookResp": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "webhook": {
+                    "$ref": "#/definitions/webhooks.Endpoint"
+                }
+            }
+        },
+        "api.webhooksResp": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "webhooks": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/webhooks.Endpoint"
+                    }
+                }
+            }
+        },
+        "api.wsMessage": {
+            "type": "object",
+            "properties": {
+                "change": {
+                    "$ref": "#/definitions/history.Change"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "group_names": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "tracking_numbers": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "type": {
+                    "type": "string"
+                }
+            }
+        },
+        "auth.APIKey": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "group_id": {
+                    "description": "Restricts the key to the numbers in this group",
+                    "type": "integer"
+                },
+                "group_name": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "last_used_at": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "org_id": {
+                    "description": "The organization the key acts in",
+                    "type": "integer"
+                },
+                "prefix": {
+                    "description": "The start of the key, to tell keys apart",
+                    "type": "string"
+                },
+                "scopes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "auth.NewAPIKey": {
+            "type": "object",
+            "properties": {
+                "expires_at": {
+                    "type": "string"
+                },
+                "group_id": {
+                    "description": "Restricts the key to the numbers in this group",
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "scopes": {
+                    "description": "read, write:trackings and/or manage:webhooks",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "digest.Digest": {
+            "type": "object",
+            "properties": {
+                "frequency": {
+                    "type": "string"
+                },
+                "groups": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/digest.Group"
+                    }
+                },
+                "period_end": {
+                    "type": "string"
+                },
+                "period_start": {
+                    "type": "string"
+                },
+                "totals": {
+                    "$ref": "#/definitions/digest.Totals"
+                }
+            }
+        },
+        "digest.Group": {
+            "type": "object",
+            "properties": {
+                "delivered": {
+                    "description": "Delivered during the period",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/digest.Package"
+                    }
+                },
+                "exceptions": {
+                    "description": "Hit an exception during the period",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/digest.Package"
+                    }
+                },
+                "group_name": {
+                    "description": "Empty for numbers that aren't in a group",
+                    "type": "string"
+                },
+                "in_transit": {
+                    "description": "Still on the way at the end of the period",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/digest.Package"
+                    }
+                },
+                "late": {
+                    "description": "Still on the way at the end of the period, but past their ETA. These are in in_transit too",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/digest.Package"
+                    }
+                }
+            }
+        },
+        "digest.Package": {
+            "type": "object",
+            "properties": {
+                "eta": {
+                    "type": "string"
+                },
+                "observed_at": {
+                    "description": "When it was delivered or hit the exception",
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "tracking_number": {
+                    "type": "string"
+                }
+            }
+        },
+        "digest.Subscription": {
+            "type": "object",
+            "properties": {
+                "channel": {
+                    "description": "Email goes to the user's verified email, webhook goes to every one of the user's webhooks as a digest event",
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "frequency": {
+                    "type": "string"
+                },
+                "hour": {
+                    "description": "Hour of the day, 0-23 in the subscription's timezone, the digest is sent at",
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "last_sent_at": {
+                    "type": "string"
+                },
+                "org_id": {
+                    "description": "The organization whose packages the digest summarizes",
+                    "type": "integer"
+                },
+                "timezone": {
+                    "description": "IANA timezone, like America/Chicago. Defaults to UTC",
+                    "type": "string"
+                },
+                "weekday": {
+                    "description": "Day of the week weekly digests are sent on, 0 is Sunday",
+                    "type": "integer"
+                }
+            }
+        },
+        "digest.Totals": {
+            "type": "object",
+            "properties": {
+                "delivered": {
+                    "type": "integer"
+                },
+                "exceptions": {
+                    "type": "integer"
+                },
+                "in_transit": {
+                    "type": "integer"
+                },
+                "late": {
+                    "type": "integer"
+                }
+            }
+        },
+        "history.Change": {
+            "type": "object",
+            "properties": {
+                "canonical_status": {
+                    "type": "string"
+                },
+                "eta": {
+                    "description": "The package's current estimated delivery, if FedEx has given one",
+                    "type": "string"
+                },
+                "group_name": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "new_status": {
                     "type": "string"
                 },
-                "tracking_number_groups": {
+                "observed_at": {
+                    "type": "string"
+                },
+                "old_canonical_status": {
+                    "description": "The canonical status before the change, null if the number was just added",
+                    "type": "string"
+                },
+                "old_status": {
+                    "type": "string"
+                },
+                "source": {
+                    "type": "string"
+                },
+                "tracking_number": {
+                    "type": "string"
+                }
+            }
+        },
+        "notify.ChatWebhook": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "group_name": {
+                    "description": "Only post about this group, empty means every group",
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "kind": {
+                    "type": "string"
+                },
+                "statuses": {
+                    "description": "Canonical statuses to post about, empty means every status",
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/api.trackingNumberGroup"
+                        "type": "string"
                     }
+                },
+                "url": {
+                    "type": "string"
                 }
             }
         },
-        "api.startTrackingResp": {
+        "notify.LogEntry": {
             "type": "object",
             "properties": {
+                "change_id": {
+                    "type": "integer"
+                },
+                "channel": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
                 "error": {
                     "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "outcome": {
+                    "type": "string"
+                },
+                "rule_id": {
+                    "description": "Null when the user didn't have any rules and the channel's defaults were used",
+                    "type": "integer"
+                },
+                "tracking_number": {
+                    "type": "string"
                 }
             }
         },
-        "api.trackingInfo": {
+        "notify.Rule": {
             "type": "object",
             "properties": {
-                "tracking_info": {
+                "channels": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "debounce_seconds": {
+                    "description": "A tracking number isn't sent to the same channel by this rule more than once in this many seconds",
+                    "type": "integer"
+                },
+                "group_names": {
+                    "description": "Empty matches every group",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "quiet_end": {
+                    "type": "string"
+                },
+                "quiet_start": {
+                    "description": "Nothing is sent between quiet_start and quiet_end, both HH:MM in the rule's timezone",
+                    "type": "string"
+                },
+                "timezone": {
+                    "description": "IANA timezone, like America/Chicago. Defaults to UTC",
+                    "type": "string"
+                },
+                "transitions": {
+                    "description": "Empty matches every status change",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/notify.Transition"
+                    }
+                }
+            }
+        },
+        "notify.Transition": {
+            "type": "object",
+            "properties": {
+                "from": {
+                    "description": "Canonical status before the change, empty matches any status, including a number that was just added",
+                    "type": "string"
+                },
+                "to": {
+                    "description": "Canonical status after the change, empty matches any status",
+                    "type": "string"
+                }
+            }
+        },
+        "orgs.Invitation": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "email": {
+                    "type": "string"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "invited_by": {
+                    "description": "The username of whoever sent it",
+                    "type": "string"
+                },
+                "org_id": {
+                    "type": "integer"
+                },
+                "org_name": {
+                    "type": "string"
+                },
+                "role": {
+                    "description": "The role the invited user joins with",
+                    "type": "string"
+                }
+            }
+        },
+        "orgs.Member": {
+            "type": "object",
+            "properties": {
+                "joined_at": {
+                    "type": "string"
+                },
+                "role": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "integer"
+                },
+                "username": {
+                    "type": "string"
+                }
+            }
+        },
+        "orgs.Organization": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "role": {
+                    "description": "The user's role in it",
+                    "type": "string"
+                }
+            }
+        },
+        "trackings.Group": {
+            "type": "object",
+            "properties": {
+                "archived_at": {
+                    "type": "string"
+                },
+                "counts": {
+                    "description": "How many of the group's tracking numbers that aren't archived are in each canonical status",
                     "type": "object",
                     "additionalProperties": {
-                        "type": "string"
+                        "type": "integer"
+                    }
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "trackings.ImportReport": {
+            "type": "object",
+            "properties": {
+                "counts": {
+                    "description": "How many rows had each outcome",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "integer"
+                    }
+                },
+                "results": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/trackings.ImportResult"
                     }
                 }
             }
         },
-        "api.trackingNumberGroup": {
+        "trackings.ImportResult": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "description": "Why the number is invalid, forbidden or couldn't be looked up",
+                    "type": "string"
+                },
+                "outcome": {
+                    "type": "string"
+                },
+                "row": {
+                    "type": "integer"
+                },
+                "tracking_number": {
+                    "type": "string"
+                }
+            }
+        },
+        "trackings.Patch": {
             "type": "object",
             "properties": {
+                "archived": {
+                    "type": "boolean"
+                },
                 "group_name": {
                     "type": "string"
                 },
+                "notes": {
+                    "type": "string"
+                }
+            }
+        },
+        "trackings.Selection": {
+            "type": "object",
+            "properties": {
+                "group_id": {
+                    "description": "Every number in this group",
+                    "type": "integer"
+                },
                 "tracking_numbers": {
                     "type": "array",
                     "items": {
@@ -296,6 +5589,151 @@ const docTemplate = `{
                     }
                 }
             }
+        },
+        "trackings.StartResult": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "description": "Why the number is invalid, forbidden or couldn't be looked up",
+                    "type": "string"
+                },
+                "outcome": {
+                    "type": "string"
+                },
+                "tracking_number": {
+                    "type": "string"
+                }
+            }
+        },
+        "trackings.Tracking": {
+            "type": "object",
+            "properties": {
+                "archived_at": {
+                    "description": "Archived numbers aren't polled, and are left out of lists by default",
+                    "type": "string"
+                },
+                "canonical_status": {
+                    "type": "string"
+                },
+                "carrier": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "eta": {
+                    "type": "string"
+                },
+                "group_name": {
+                    "type": "string"
+                },
+                "notes": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "status_last_updated": {
+                    "description": "When the status was last checked with the carrier",
+                    "type": "string"
+                },
+                "tracking_number": {
+                    "type": "string"
+                }
+            }
+        },
+        "webhooks.Attempt": {
+            "type": "object",
+            "properties": {
+                "attempted_at": {
+                    "type": "string"
+                },
+                "duration_ms": {
+                    "type": "integer"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "status_code": {
+                    "type": "integer"
+                }
+            }
+        },
+        "webhooks.Delivery": {
+            "type": "object",
+            "properties": {
+                "attempt_log": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/webhooks.Attempt"
+                    }
+                },
+                "attempts": {
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "delivered_at": {
+                    "type": "string"
+                },
+                "event_id": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "last_error": {
+                    "type": "string"
+                },
+                "last_status_code": {
+                    "type": "integer"
+                },
+                "next_attempt_at": {
+                    "type": "string"
+                },
+                "payload": {
+                    "type": "object"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "webhook_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "webhooks.Endpoint": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "group_names": {
+                    "description": "Groups to send events for, empty means every group",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "secret": {
+                    "description": "Only returned when the endpoint is created",
+                    "type": "string"
+                },
+                "statuses": {
+                    "description": "Canonical statuses to send events for, empty means every status",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
         }
     }
 }`
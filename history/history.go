@@ -0,0 +1,85 @@
+package history
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Where a status change was observed
+const (
+	SourcePoll    = "poll"
+	SourceWebhook = "webhook"
+	SourceManual  = "manual"
+)
+
+// Change is a shipment's status change, as seen by one of the users tracking it through one of their organizations.
+// Everyone tracking the shipment shares the same change id.
+type Change struct {
+	ID             int64   `json:"id"`
+	UserID         int32   `json:"-"`
+	OrgID          int64   `json:"-"`
+	TrackingNumber string  `json:"tracking_number"`
+	GroupName      string  `json:"group_name,omitempty"`
+	OldStatus      *string `json:"old_status"`
+	NewStatus      string  `json:"new_status"`
+	// The canonical status before the change, null if the number was just added
+	OldCanonicalStatus *string `json:"old_canonical_status"`
+	CanonicalStatus    string  `json:"canonical_status"`
+	// The package's current estimated delivery, if FedEx has given one
+	ETA        *time.Time `json:"eta,omitempty"`
+	ObservedAt time.Time  `json:"observed_at"`
+	Source     string     `json:"source"`
+}
+
+// Record stores a status transition for the shipment with the given id, filling in the change's id and observed_at
+func Record(tx *sql.Tx, shipmentID int64, change *Change) error {
+	row := tx.QueryRow(
+		"insert into tracking_status_history (shipment_id, old_status, new_status, old_canonical_status, canonical_status, observed_at, source) values (?, ?, ?, ?, ?, datetime('now'), ?) returning id, observed_at",
+		shipmentID, change.OldStatus, change.NewStatus, change.OldCanonicalStatus, change.CanonicalStatus, change.Source,
+	)
+
+	return row.Scan(&change.ID, &change.ObservedAt)
+}
+
+// Since returns up to limit of the status changes in the organization that happened after the given cursor, oldest first.
+// The cursor is the id of the last change the caller has seen, or 0 for everything. Users who joined the organization
+// after it was created only get the changes since they joined.
+func Since(db *sql.DB, userID int32, orgID int64, cursor int64, limit int) ([]Change, error) {
+	rows, err := db.Query(
+		`select h.id, sh.tracking_number, coalesce(s.group_name, ''), h.old_status, h.new_status, h.old_canonical_status, h.canonical_status, sh.eta, h.observed_at, h.source
+		from tracking_status_history h
+		join shipments sh on sh.id = h.shipment_id
+		join subscriptions s on s.shipment_id = h.shipment_id and s.org_id = ?
+		join organization_members m on m.org_id = s.org_id and m.user_id = ?
+		where h.id > ?
+		and (
+			unixepoch(h.observed_at) >= unixepoch(m.created_at)
+			or unixepoch(m.created_at) = (select min(unixepoch(created_at)) from organization_members where org_id = m.org_id)
+		)
+		order by h.id
+		limit ?`,
+		orgID, userID, cursor, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	changes := []Change{}
+	for rows.Next() {
+		change := Change{UserID: userID, OrgID: orgID}
+		if err := rows.Scan(&change.ID, &change.TrackingNumber, &change.GroupName, &change.OldStatus, &change.NewStatus, &change.OldCanonicalStatus, &change.CanonicalStatus, &change.ETA, &change.ObservedAt, &change.Source); err != nil {
+			return nil, err
+		}
+		changes = append(changes, change)
+	}
+
+	return changes, rows.Err()
+}
+
+// LatestID returns the id of the newest change of any user's, or 0 if nothing's changed yet
+func LatestID(db *sql.DB) (int64, error) {
+	var id int64
+	err := db.QueryRow("select coalesce(max(id), 0) from tracking_status_history").Scan(&id)
+	return id, err
+}
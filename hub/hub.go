@@ -0,0 +1,210 @@
+package hub
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/billyb2/tracking_server/history"
+	"github.com/gin-gonic/gin"
+)
+
+const hubContextKey = "hubContextKey"
+
+const DefaultMaxConnsPerUser = 5
+
+// How many changes can be waiting to be sent to a subscriber before it's cut off for being too slow
+const subscriberBuffer = 64
+
+// MaxWatches is how many tracking numbers and groups, together, one subscriber can watch
+const MaxWatches = 1000
+
+var TooManyConnections error = fmt.Errorf("too many live connections for this user")
+var TooManyWatches error = fmt.Errorf("subscribers can watch at most %d tracking numbers and groups", MaxWatches)
+
+// Hub passes status changes to the subscribers watching their tracking numbers or groups, as they happen
+type Hub struct {
+	MaxConnsPerUser int
+
+	mu          sync.Mutex
+	subscribers map[int32]map[*Subscriber]struct{}
+}
+
+func New(maxConnsPerUser int) *Hub {
+	return &Hub{
+		MaxConnsPerUser: maxConnsPerUser,
+		subscribers:     map[int32]map[*Subscriber]struct{}{},
+	}
+}
+
+// Subscriber gets the changes it's watching on C, in the organization it subscribed in. If it falls too far behind, C
+// is closed and Slow returns true.
+type Subscriber struct {
+	C <-chan history.Change
+
+	userID  int32
+	orgID   int64
+	changes chan history.Change
+	// guarded by the hub's lock
+	numbers map[string]bool
+	groups  map[string]bool
+	closed  bool
+	slow    bool
+}
+
+// Subscribe adds a subscriber for the user in the organization that isn't watching anything yet
+func (h *Hub) Subscribe(userID int32, orgID int64) (*Subscriber, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if len(h.subscribers[userID]) >= h.MaxConnsPerUser {
+		return nil, TooManyConnections
+	}
+
+	changes := make(chan history.Change, subscriberBuffer)
+	s := &Subscriber{
+		C:       changes,
+		userID:  userID,
+		orgID:   orgID,
+		changes: changes,
+		numbers: map[string]bool{},
+		groups:  map[string]bool{},
+	}
+	if h.subscribers[userID] == nil {
+		h.subscribers[userID] = map[*Subscriber]struct{}{}
+	}
+	h.subscribers[userID][s] = struct{}{}
+
+	return s, nil
+}
+
+// Unsubscribe removes the subscriber, closing its channel if the hub hasn't already
+func (h *Hub) Unsubscribe(s *Subscriber) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.remove(s)
+}
+
+func (h *Hub) remove(s *Subscriber) {
+	delete(h.subscribers[s.userID], s)
+	if len(h.subscribers[s.userID]) == 0 {
+		delete(h.subscribers, s.userID)
+	}
+	if !s.closed {
+		s.closed = true
+		close(s.changes)
+	}
+}
+
+// Watch starts sending the subscriber changes to the tracking numbers and groups, returning everything it's watching.
+// Nothing is added if it would take the subscriber over MaxWatches.
+func (h *Hub) Watch(s *Subscriber, trackingNumbers, groupNames []string) (watchedNumbers, watchedGroups []string, err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	newNumbers := map[string]bool{}
+	for _, trackingNumber := range trackingNumbers {
+		if !s.numbers[trackingNumber] {
+			newNumbers[trackingNumber] = true
+		}
+	}
+	newGroups := map[string]bool{}
+	for _, groupName := range groupNames {
+		if !s.groups[groupName] {
+			newGroups[groupName] = true
+		}
+	}
+	if len(s.numbers)+len(s.groups)+len(newNumbers)+len(newGroups) > MaxWatches {
+		watchedNumbers, watchedGroups = s.watching()
+		return watchedNumbers, watchedGroups, TooManyWatches
+	}
+
+	for _, trackingNumber := range trackingNumbers {
+		s.numbers[trackingNumber] = true
+	}
+	for _, groupName := range groupNames {
+		s.groups[groupName] = true
+	}
+
+	watchedNumbers, watchedGroups = s.watching()
+	return watchedNumbers, watchedGroups, nil
+}
+
+// Unwatch stops sending the subscriber changes to the tracking numbers and groups, returning everything it's still watching
+func (h *Hub) Unwatch(s *Subscriber, trackingNumbers, groupNames []string) (watchedNumbers, watchedGroups []string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, trackingNumber := range trackingNumbers {
+		delete(s.numbers, trackingNumber)
+	}
+	for _, groupName := range groupNames {
+		delete(s.groups, groupName)
+	}
+
+	return s.watching()
+}
+
+func (s *Subscriber) watching() ([]string, []string) {
+	numbers := make([]string, 0, len(s.numbers))
+	for trackingNumber := range s.numbers {
+		numbers = append(numbers, trackingNumber)
+	}
+	groups := make([]string, 0, len(s.groups))
+	for groupName := range s.groups {
+		groups = append(groups, groupName)
+	}
+
+	sort.Strings(numbers)
+	sort.Strings(groups)
+
+	return numbers, groups
+}
+
+// Slow is true if the hub cut the subscriber off because it wasn't keeping up
+func (h *Hub) Slow(s *Subscriber) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return s.slow
+}
+
+// Publish never blocks on a subscriber. Ones whose buffer is full are dropped, so one slow client can't hold up the
+// poller or anyone else's updates.
+func (h *Hub) Publish(change *history.Change) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for s := range h.subscribers[change.UserID] {
+		if s.orgID != change.OrgID {
+			continue
+		}
+		if !s.numbers[change.TrackingNumber] && !s.groups[change.GroupName] {
+			continue
+		}
+
+		select {
+		case s.changes <- *change:
+		default:
+			s.slow = true
+			h.remove(s)
+		}
+	}
+}
+
+// Notify lets the hub be used as one of the poller's notifiers
+func (h *Hub) Notify(change *history.Change) error {
+	h.Publish(change)
+	return nil
+}
+
+func WithGinContext(c *gin.Context, hub *Hub) {
+	c.Set(hubContextKey, hub)
+	c.Next()
+}
+
+func FromGinContext(c *gin.Context) *Hub {
+	hub, _ := c.Get(hubContextKey)
+	return hub.(*Hub)
+}
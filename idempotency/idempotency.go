@@ -0,0 +1,109 @@
+package idempotency
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Header is the request header clients send their key in
+const Header = "Idempotency-Key"
+
+const maxKeyLength = 255
+
+// Responses are kept this long, after which the key can be used again
+const ttl = 24 * time.Hour
+
+var InvalidKey error = fmt.Errorf("idempotency keys can be at most 255 characters long")
+var KeyReused error = fmt.Errorf("this idempotency key was already used for a different request")
+var InProgress error = fmt.Errorf("a request with this idempotency key is still being handled")
+
+// Response is what was sent back for a key the first time it was used
+type Response struct {
+	Status int
+	Body   []byte
+}
+
+// Begin claims the user's key in the organization for the request. It returns nil if the request should be handled,
+// after which Finish or Release has to be called, or the response to send again if a request with the key has already
+// been handled.
+func Begin(db *sql.DB, userID int32, orgID int64, key string, request any) (*Response, error) {
+	if len(key) > maxKeyLength {
+		return nil, InvalidKey
+	}
+
+	requestJSON, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+	requestHash := sha256.Sum256(requestJSON)
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(
+		"delete from idempotency_keys where org_id = ? and user_id = ? and key = ? and created_at < datetime('now', ?)",
+		orgID, userID, key, fmt.Sprintf("-%d seconds", int(ttl.Seconds())),
+	); err != nil {
+		return nil, err
+	}
+
+	var id int64
+	err = tx.QueryRow(
+		"insert into idempotency_keys (org_id, user_id, key, request_hash, created_at) values (?, ?, ?, ?, datetime('now')) on conflict do nothing returning id",
+		orgID, userID, key, requestHash[:],
+	).Scan(&id)
+	if err == nil {
+		return nil, tx.Commit()
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	var savedHash []byte
+	var status *int
+	response := Response{}
+	if err := tx.QueryRow("select request_hash, status, response from idempotency_keys where org_id = ? and user_id = ? and key = ?", orgID, userID, key).Scan(&savedHash, &status, &response.Body); err != nil {
+		return nil, err
+	}
+	switch {
+	case !bytes.Equal(savedHash, requestHash[:]):
+		return nil, KeyReused
+	case status == nil:
+		return nil, InProgress
+	}
+	response.Status = *status
+
+	return &response, nil
+}
+
+// Finish saves the response to the request that claimed the key
+func Finish(db *sql.DB, userID int32, orgID int64, key string, response *Response) error {
+	_, err := db.Exec("update idempotency_keys set status = ?, response = ? where org_id = ? and user_id = ? and key = ?", response.Status, response.Body, orgID, userID, key)
+	return err
+}
+
+// Release lets the key be used again, like when handling the request failed in a way that's worth retrying
+func Release(db *sql.DB, userID int32, orgID int64, key string) error {
+	_, err := db.Exec("delete from idempotency_keys where org_id = ? and user_id = ? and key = ?", orgID, userID, key)
+	return err
+}
+
+// Run deletes expired keys forever, once an hour
+func Run(db *sql.DB) {
+	for {
+		if _, err := db.Exec("delete from idempotency_keys where created_at < datetime('now', ?)", fmt.Sprintf("-%d seconds", int(ttl.Seconds()))); err != nil {
+			fmt.Fprintln(os.Stderr, "error deleting expired idempotency keys", err)
+		}
+
+		time.Sleep(time.Hour)
+	}
+}
@@ -0,0 +1,156 @@
+package mailer
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const mailerContextKey = "mailerContextKey"
+
+var NotConfigured error = fmt.Errorf("email is not configured on this server")
+
+type Message struct {
+	To      []string
+	Subject string
+	Text    string
+	HTML    string
+}
+
+type Mailer interface {
+	Send(msg *Message) error
+}
+
+type SMTPMailer struct {
+	// host:port of the SMTP server
+	Addr string
+	From mail.Address
+	// nil to send without authenticating, like when testing against a local SMTP sink
+	Auth smtp.Auth
+}
+
+// NewSMTPMailerFromEnv configures an SMTP mailer from the SMTP_* env vars, returning nil if SMTP_HOST isn't set
+func NewSMTPMailerFromEnv() (*SMTPMailer, error) {
+	host := os.Getenv("SMTP_HOST")
+	if host == "" {
+		return nil, nil
+	}
+
+	port := os.Getenv("SMTP_PORT")
+	if port == "" {
+		port = "587"
+	}
+
+	from, err := mail.ParseAddress(os.Getenv("SMTP_FROM"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid SMTP_FROM: %w", err)
+	}
+
+	var auth smtp.Auth
+	if username := os.Getenv("SMTP_USERNAME"); username != "" {
+		auth = smtp.PlainAuth("", username, os.Getenv("SMTP_PASSWORD"), host)
+	}
+
+	return &SMTPMailer{
+		Addr: net.JoinHostPort(host, port),
+		From: *from,
+		Auth: auth,
+	}, nil
+}
+
+func (m *SMTPMailer) Send(msg *Message) error {
+	body, err := m.buildMessage(msg)
+	if err != nil {
+		return err
+	}
+
+	return smtp.SendMail(m.Addr, m.Auth, m.From.Address, msg.To, body)
+}
+
+// buildMessage renders the message as multipart/alternative, so that clients that can't show HTML get the text body
+func (m *SMTPMailer) buildMessage(msg *Message) ([]byte, error) {
+	buf := bytes.Buffer{}
+	parts := multipart.NewWriter(&buf)
+
+	messageID := make([]byte, 16)
+	if _, err := rand.Read(messageID); err != nil {
+		return nil, err
+	}
+	domain := "localhost"
+	if at := strings.LastIndex(m.From.Address, "@"); at != -1 {
+		domain = m.From.Address[at+1:]
+	}
+
+	headers := []string{
+		"From: " + m.From.String(),
+		"To: " + strings.Join(msg.To, ", "),
+		"Subject: " + mime.QEncoding.Encode("utf-8", msg.Subject),
+		"Date: " + time.Now().Format(time.RFC1123Z),
+		fmt.Sprintf("Message-ID: <%s@%s>", hex.EncodeToString(messageID), domain),
+		"MIME-Version: 1.0",
+		"Content-Type: multipart/alternative; boundary=" + parts.Boundary(),
+	}
+	header := strings.Join(headers, "\r\n") + "\r\n\r\n"
+
+	bodies := []struct {
+		contentType string
+		body        string
+	}{
+		{"text/plain; charset=utf-8", msg.Text},
+		{"text/html; charset=utf-8", msg.HTML},
+	}
+	for _, body := range bodies {
+		if body.body == "" {
+			continue
+		}
+
+		part, err := parts.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {body.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		qp := quotedprintable.NewWriter(part)
+		if _, err := qp.Write([]byte(body.body)); err != nil {
+			return nil, err
+		}
+		if err := qp.Close(); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := parts.Close(); err != nil {
+		return nil, err
+	}
+
+	return append([]byte(header), buf.Bytes()...), nil
+}
+
+func WithGinContext(c *gin.Context, mailer Mailer) {
+	c.Set(mailerContextKey, mailer)
+	c.Next()
+}
+
+// FromGinContext returns the server's mailer, or nil if email isn't configured
+func FromGinContext(c *gin.Context) Mailer {
+	// the mailer is stored even when it's nil, so check the type instead of whether it exists
+	mailer, _ := c.Get(mailerContextKey)
+	m, _ := mailer.(Mailer)
+
+	return m
+}
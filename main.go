@@ -2,13 +2,21 @@ package main
 
 import (
 	"fmt"
-	"os"
-	"time"
 
 	"github.com/billyb2/tracking_server/api"
+	"github.com/billyb2/tracking_server/auth"
 	dblib "github.com/billyb2/tracking_server/db"
+	"github.com/billyb2/tracking_server/digest"
 	_ "github.com/billyb2/tracking_server/docs"
-	fedex "github.com/billyb2/tracking_server/tracking"
+	"github.com/billyb2/tracking_server/hub"
+	"github.com/billyb2/tracking_server/idempotency"
+	"github.com/billyb2/tracking_server/mailer"
+	"github.com/billyb2/tracking_server/notify"
+	"github.com/billyb2/tracking_server/orgs"
+	"github.com/billyb2/tracking_server/outbound"
+	"github.com/billyb2/tracking_server/poller"
+	"github.com/billyb2/tracking_server/trackings"
+	"github.com/billyb2/tracking_server/webhooks"
 	"github.com/gin-gonic/gin"
 	swaggerfiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
@@ -25,68 +33,163 @@ func main() {
 	}
 	defer db.Close()
 
-	go func() {
-		for {
-			rows, err := db.Query("select tracking_number from tracking where unixepoch('now', 'auto') - unixepoch(status_last_updated, 'auto') > 1800")
-			if err != nil {
-				fmt.Fprintln(os.Stderr, "error querying tracking numbers", err)
-				continue
-			}
-			defer rows.Close()
-
-			trackingNumbers := []string{}
-			for rows.Next() {
-				var trackingNumber string
-				if err := rows.Scan(&trackingNumber); err != nil {
-					fmt.Fprintln(os.Stderr, "error scanning fedex api", err)
-					continue
-				}
-				trackingNumbers = append(trackingNumbers, trackingNumber)
-			}
-
-			trackingStatuses, err := fedex.TrackByTrackingNumber(trackingNumbers)
-			if err != nil {
-				fmt.Fprintln(os.Stderr, "error calling fedex api", err)
-				continue
-			}
-			tx, err := db.Begin()
-			if err != nil {
-				fmt.Fprintln(os.Stderr, "error creating tx", err)
-				continue
-			}
-
-			for trackingNumber, trackingStatus := range trackingStatuses {
-				_, err := tx.Exec(
-					"update tracking set status = ? where tracking_number = ?",
-					trackingStatus.StatusDescription, trackingNumber,
-				)
-				if err != nil {
-					tx.Rollback()
-					fmt.Fprintln(os.Stderr, "error updating tracking number status in DB", err)
-					continue
-				}
-			}
-
-			if err := tx.Commit(); err != nil {
-				fmt.Fprintln(os.Stderr, "error commiting tx", err)
-				continue
-			}
-
-			time.Sleep(60 * time.Second)
-		}
-
-	}()
-
-	r := gin.Default()
+	if err := auth.HashPlaintextTokens(db); err != nil {
+		fmt.Println(err)
+		return
+	}
+	if err := auth.UpgradeLegacyPasswordHashes(db); err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	smtpMailer, err := mailer.NewSMTPMailerFromEnv()
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	smsClient, err := notify.NewSMSClientFromEnv()
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	inviteSigner, err := orgs.NewSignerFromEnv()
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	autoArchiveDays, err := trackings.AutoArchiveDaysFromEnv()
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	argon2Params, err := auth.Argon2ParamsFromEnv()
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	auth.SetArgon2Params(argon2Params)
+
+	outboundAllowlist, err := outbound.AllowlistFromEnv()
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	outbound.SetAllowlist(outboundAllowlist)
+
+	var mail mailer.Mailer
+	channels := []notify.Channel{&notify.ChatNotifier{DB: db}}
+	if smtpMailer != nil {
+		mail = smtpMailer
+		channels = append(channels, &notify.EmailNotifier{DB: db, Mailer: mail})
+	}
+	if smsClient != nil {
+		channels = append(channels, &notify.SMSNotifier{DB: db, Client: smsClient})
+	}
+
+	liveUpdates := hub.New(hub.DefaultMaxConnsPerUser)
+
+	// live updates go first, since Fanout notifies in order and the rule engine waits on email, SMS and chat services
+	go poller.Run(db, notify.Fanout{liveUpdates, &notify.RuleEngine{DB: db, Channels: channels}})
+	go webhooks.RunDispatcher(db)
+	go digest.Run(db, mail)
+	go idempotency.Run(db)
+	go auth.Run(db)
+	go orgs.Run(db)
+	if autoArchiveDays > 0 {
+		go trackings.RunAutoArchiver(db, autoArchiveDays)
+	}
+
+	r := gin.New()
+	r.Use(api.Logger(), gin.Recovery())
 	r.Use(func(c *gin.Context) {
 		dblib.WithGinContext(c, db)
 	})
+	r.Use(func(c *gin.Context) {
+		mailer.WithGinContext(c, mail)
+	})
+	r.Use(func(c *gin.Context) {
+		notify.SMSClientWithGinContext(c, smsClient)
+	})
+	r.Use(func(c *gin.Context) {
+		hub.WithGinContext(c, liveUpdates)
+	})
+	r.Use(func(c *gin.Context) {
+		orgs.SignerWithGinContext(c, inviteSigner)
+	})
 
 	v1 := r.Group("/api")
 	v1.POST("/register", api.Register)
 	v1.POST("/login", api.Login)
-	v1.POST("/start_tracking", api.StartTrackingGroups)
-	v1.POST("/get_tracking", api.GetTrackingNumbers)
+	v1.POST("/refresh", api.Refresh)
+	v1.GET("/verify_email", api.VerifyEmail)
+	v1.POST("/sms/inbound", api.SMSInbound)
+	v1.POST("/v1/invitations/decline", api.DeclineInvitation)
+	v1.POST("/v1/password/forgot", api.ForgotPassword)
+	v1.POST("/v1/password/reset", api.ResetPassword)
+
+	// the older endpoints also take the token in the body, until clients have moved to the Authorization header
+	legacy := v1.Group("", api.LegacyAuth)
+	legacy.POST("/logout", api.Logout)
+	legacy.POST("/start_tracking", api.StartTrackingGroups)
+	legacy.POST("/get_tracking", api.GetTrackingNumbers)
+	legacy.POST("/get_tracking_changes", api.GetTrackingChanges)
+	legacy.GET("/trackings/stream", api.StreamTrackingChanges)
+	legacy.GET("/trackings/ws", api.TrackingWebSocket)
+	legacy.POST("/create_webhook", api.CreateWebhook)
+	legacy.POST("/get_webhooks", api.GetWebhooks)
+	legacy.POST("/delete_webhook", api.DeleteWebhook)
+	legacy.POST("/get_webhook_deliveries", api.GetWebhookDeliveries)
+	legacy.POST("/redeliver_webhook", api.RedeliverWebhook)
+	legacy.POST("/set_email", api.SetEmail)
+	legacy.POST("/set_group_email_recipients", api.SetGroupEmailRecipients)
+	legacy.POST("/get_group_email_recipients", api.GetGroupEmailRecipients)
+	legacy.POST("/create_chat_webhook", api.CreateChatWebhook)
+	legacy.POST("/get_chat_webhooks", api.GetChatWebhooks)
+	legacy.POST("/delete_chat_webhook", api.DeleteChatWebhook)
+	legacy.POST("/set_phone", api.SetPhone)
+	legacy.POST("/verify_phone", api.VerifyPhone)
+	legacy.POST("/create_notification_rule", api.CreateNotificationRule)
+	legacy.POST("/get_notification_rules", api.GetNotificationRules)
+	legacy.POST("/delete_notification_rule", api.DeleteNotificationRule)
+	legacy.POST("/get_notification_log", api.GetNotificationLog)
+	legacy.POST("/create_digest_subscription", api.CreateDigestSubscription)
+	legacy.POST("/get_digest_subscriptions", api.GetDigestSubscriptions)
+	legacy.POST("/delete_digest_subscription", api.DeleteDigestSubscription)
+	legacy.POST("/get_digest", api.GetDigest)
+
+	apiV1 := v1.Group("/v1", api.RequireAuth)
+	apiV1.GET("/trackings", api.ListTrackings)
+	apiV1.POST("/trackings", api.CreateTracking)
+	apiV1.GET("/trackings/export", api.ExportTrackings)
+	apiV1.POST("/trackings/import", api.ImportTrackings)
+	apiV1.POST("/trackings/archive", api.ArchiveTrackings)
+	apiV1.POST("/trackings/unarchive", api.UnarchiveTrackings)
+	apiV1.POST("/trackings/delete", api.DeleteTrackings)
+	apiV1.GET("/trackings/:number", api.GetTracking)
+	apiV1.PATCH("/trackings/:number", api.UpdateTracking)
+	apiV1.DELETE("/trackings/:number", api.DeleteTracking)
+	apiV1.GET("/groups", api.ListGroups)
+	apiV1.POST("/groups", api.CreateGroup)
+	apiV1.GET("/groups/:id", api.GetGroup)
+	apiV1.PATCH("/groups/:id", api.UpdateGroup)
+	apiV1.POST("/groups/:id/trackings", api.MoveToGroup)
+	apiV1.GET("/api_keys", api.ListAPIKeys)
+	apiV1.POST("/api_keys", api.CreateAPIKey)
+	apiV1.DELETE("/api_keys/:id", api.RevokeAPIKey)
+	apiV1.GET("/organizations", api.ListOrganizations)
+	apiV1.GET("/organization/members", api.ListMembers)
+	apiV1.PATCH("/organization/members/:user_id", api.UpdateMember)
+	apiV1.DELETE("/organization/members/:user_id", api.RemoveMember)
+	apiV1.GET("/organization/invitations", api.ListInvitations)
+	apiV1.POST("/organization/invitations", api.CreateInvitation)
+	apiV1.DELETE("/organization/invitations/:id", api.RevokeInvitation)
+	apiV1.POST("/invitations/accept", api.AcceptInvitation)
+	apiV1.PUT("/password", api.ChangePassword)
+
 	r.GET("/docs/*any", ginSwagger.WrapHandler(swaggerfiles.Handler))
 
 	fmt.Println("Starting server!")
@@ -0,0 +1,305 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"slices"
+	"time"
+
+	"github.com/billyb2/tracking_server/history"
+	"github.com/billyb2/tracking_server/outbound"
+	fedex "github.com/billyb2/tracking_server/tracking"
+)
+
+const (
+	ChatSlack = "slack"
+	ChatTeams = "teams"
+)
+
+var ChatWebhookNotFound error = fmt.Errorf("chat webhook not found")
+var InvalidChatWebhook error = fmt.Errorf("invalid chat webhook")
+
+var chatClient = outbound.NewClient(10 * time.Second)
+
+// ChatWebhook is a Slack or Microsoft Teams incoming webhook URL that status changes are posted to
+type ChatWebhook struct {
+	ID   int64  `json:"id"`
+	Kind string `json:"kind"`
+	URL  string `json:"url"`
+	// Only post about this group, empty means every group
+	GroupName string `json:"group_name,omitempty"`
+	// Canonical statuses to post about, empty means every status
+	Statuses  []string  `json:"statuses,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func CreateChatWebhook(db *sql.DB, userID int32, orgID int64, webhook *ChatWebhook) error {
+	u, err := url.Parse(webhook.URL)
+	if err != nil || !u.IsAbs() || (u.Scheme != "http" && u.Scheme != "https") {
+		return fmt.Errorf("%w: it needs an absolute http or https url", InvalidChatWebhook)
+	}
+	if webhook.Kind != ChatSlack && webhook.Kind != ChatTeams {
+		return fmt.Errorf("%w: its kind has to be slack or teams", InvalidChatWebhook)
+	}
+	if err := outbound.CheckURL(context.Background(), webhook.URL); err != nil {
+		return fmt.Errorf("%w: %w", InvalidChatWebhook, err)
+	}
+
+	var groupName *string
+	if webhook.GroupName != "" {
+		groupName = &webhook.GroupName
+	}
+	var statuses *string
+	if len(webhook.Statuses) > 0 {
+		statusesJSON, err := json.Marshal(webhook.Statuses)
+		if err != nil {
+			return err
+		}
+		statuses = new(string)
+		*statuses = string(statusesJSON)
+	}
+
+	row := db.QueryRow(
+		"insert into chat_webhooks (user_id, org_id, kind, url, group_name, statuses, created_at) values (?, ?, ?, ?, ?, ?, datetime('now')) returning id, created_at",
+		userID, orgID, webhook.Kind, webhook.URL, groupName, statuses,
+	)
+
+	return row.Scan(&webhook.ID, &webhook.CreatedAt)
+}
+
+func ListChatWebhooks(db *sql.DB, userID int32, orgID int64) ([]ChatWebhook, error) {
+	return chatWebhooks(db, "select id, kind, url, coalesce(group_name, ''), statuses, created_at from chat_webhooks where org_id = ? and user_id = ? order by id", orgID, userID)
+}
+
+func DeleteChatWebhook(db *sql.DB, userID int32, orgID int64, webhookID int64) error {
+	result, err := db.Exec("delete from chat_webhooks where id = ? and org_id = ? and user_id = ?", webhookID, orgID, userID)
+	if err != nil {
+		return err
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return ChatWebhookNotFound
+	}
+
+	return nil
+}
+
+func chatWebhooks(db *sql.DB, query string, args ...any) ([]ChatWebhook, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	webhooks := []ChatWebhook{}
+	for rows.Next() {
+		webhook := ChatWebhook{}
+		var statuses *string
+		if err := rows.Scan(&webhook.ID, &webhook.Kind, &webhook.URL, &webhook.GroupName, &statuses, &webhook.CreatedAt); err != nil {
+			return nil, err
+		}
+		if statuses != nil {
+			if err := json.Unmarshal([]byte(*statuses), &webhook.Statuses); err != nil {
+				return nil, err
+			}
+		}
+
+		webhooks = append(webhooks, webhook)
+	}
+
+	return webhooks, rows.Err()
+}
+
+// ChatNotifier posts a card to each of the user's chat webhooks in the change's organization that cover its group and
+// status
+type ChatNotifier struct {
+	DB *sql.DB
+}
+
+func (n *ChatNotifier) Name() string {
+	return ChannelChat
+}
+
+// Default is always true, since each chat webhook has its own status filter
+func (n *ChatNotifier) Default(change *history.Change) bool {
+	return true
+}
+
+func (n *ChatNotifier) Send(change *history.Change) error {
+	webhooks, err := chatWebhooks(
+		n.DB,
+		"select id, kind, url, coalesce(group_name, ''), statuses, created_at from chat_webhooks where org_id = ? and user_id = ? and (group_name is null or group_name = ?)",
+		change.OrgID, change.UserID, change.GroupName,
+	)
+	if err != nil {
+		return err
+	}
+
+	errs := []error{}
+	posted := 0
+	for _, webhook := range webhooks {
+		if len(webhook.Statuses) > 0 && !slices.Contains(webhook.Statuses, change.CanonicalStatus) {
+			continue
+		}
+
+		posted++
+		if err := postChatCard(&webhook, change); err != nil {
+			errs = append(errs, fmt.Errorf("error posting to %s webhook %d: %w", webhook.Kind, webhook.ID, err))
+		}
+	}
+	if posted == 0 {
+		return fmt.Errorf("%w: no chat webhooks for this group and status", Skipped)
+	}
+
+	return errors.Join(errs...)
+}
+
+func postChatCard(webhook *ChatWebhook, change *history.Change) error {
+	var card any
+	switch webhook.Kind {
+	case ChatSlack:
+		card = slackCard(change)
+	case ChatTeams:
+		card = teamsCard(change)
+	default:
+		return InvalidChatWebhook
+	}
+
+	body, err := json.Marshal(card)
+	if err != nil {
+		return err
+	}
+
+	resp, err := chatClient.Post(webhook.URL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("non-2xx status code %d: %s", resp.StatusCode, string(respBody))
+	}
+
+	return nil
+}
+
+type chatFact struct {
+	title string
+	value string
+}
+
+func chatFacts(change *history.Change) []chatFact {
+	oldStatus := "none"
+	if change.OldStatus != nil {
+		oldStatus = *change.OldStatus
+	}
+	eta := "unknown"
+	if change.ETA != nil {
+		eta = change.ETA.Format("Mon Jan 2, 3:04 PM MST")
+	}
+	groupName := change.GroupName
+	if groupName == "" {
+		groupName = "none"
+	}
+
+	return []chatFact{
+		{"Tracking number", change.TrackingNumber},
+		{"Group", groupName},
+		{"Status", fmt.Sprintf("%s → %s", oldStatus, change.NewStatus)},
+		{"ETA", eta},
+	}
+}
+
+func chatTitle(change *history.Change) string {
+	return fmt.Sprintf("%s is now %s", change.TrackingNumber, change.NewStatus)
+}
+
+func slackCard(change *history.Change) map[string]any {
+	fields := []map[string]any{}
+	for _, fact := range chatFacts(change) {
+		fields = append(fields, map[string]any{
+			"type": "mrkdwn",
+			"text": fmt.Sprintf("*%s*\n%s", fact.title, fact.value),
+		})
+	}
+
+	return map[string]any{
+		// shown in notifications and by clients that can't render blocks
+		"text": chatTitle(change),
+		"blocks": []map[string]any{
+			{
+				"type": "header",
+				"text": map[string]any{"type": "plain_text", "text": chatTitle(change)},
+			},
+			{
+				"type":   "section",
+				"fields": fields,
+			},
+			{
+				"type": "actions",
+				"elements": []map[string]any{
+					{
+						"type": "button",
+						"text": map[string]any{"type": "plain_text", "text": "Track package"},
+						"url":  fedex.TrackingURL(change.TrackingNumber),
+					},
+				},
+			},
+		},
+	}
+}
+
+func teamsCard(change *history.Change) map[string]any {
+	facts := []map[string]any{}
+	for _, fact := range chatFacts(change) {
+		facts = append(facts, map[string]any{
+			"title": fact.title,
+			"value": fact.value,
+		})
+	}
+
+	return map[string]any{
+		"type": "message",
+		"attachments": []map[string]any{
+			{
+				"contentType": "application/vnd.microsoft.card.adaptive",
+				"content": map[string]any{
+					"$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+					"type":    "AdaptiveCard",
+					"version": "1.4",
+					"body": []map[string]any{
+						{
+							"type":   "TextBlock",
+							"text":   chatTitle(change),
+							"weight": "Bolder",
+							"size":   "Medium",
+							"wrap":   true,
+						},
+						{
+							"type":  "FactSet",
+							"facts": facts,
+						},
+					},
+					"actions": []map[string]any{
+						{
+							"type":  "Action.OpenUrl",
+							"title": "Track package",
+							"url":   fedex.TrackingURL(change.TrackingNumber),
+						},
+					},
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,137 @@
+package notify
+
+import (
+	"bytes"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"slices"
+	texttemplate "text/template"
+
+	"github.com/billyb2/tracking_server/history"
+	"github.com/billyb2/tracking_server/mailer"
+	fedex "github.com/billyb2/tracking_server/tracking"
+)
+
+//go:embed templates
+var templates embed.FS
+
+var statusChangeText = texttemplate.Must(texttemplate.ParseFS(templates, "templates/status_change.txt"))
+var statusChangeHTML = htmltemplate.Must(htmltemplate.ParseFS(templates, "templates/status_change.html"))
+
+var emailHeadlines = map[string]string{
+	fedex.StatusUnknown:        "Your package's status changed",
+	fedex.StatusLabelCreated:   "Your package's label was created",
+	fedex.StatusInTransit:      "Your package is in transit",
+	fedex.StatusOutForDelivery: "Your package is out for delivery",
+	fedex.StatusDelivered:      "Your package was delivered",
+	fedex.StatusException:      "There's a problem with your package",
+}
+
+var emailStatuses = []string{fedex.StatusOutForDelivery, fedex.StatusDelivered, fedex.StatusException}
+
+type statusChangeData struct {
+	Headline       string
+	TrackingNumber string
+	GroupName      string
+	OldStatus      string
+	NewStatus      string
+	TrackingURL    string
+}
+
+func newStatusChangeData(change *history.Change, headline string) statusChangeData {
+	oldStatus := ""
+	if change.OldStatus != nil {
+		oldStatus = *change.OldStatus
+	}
+
+	return statusChangeData{
+		Headline:       headline,
+		TrackingNumber: change.TrackingNumber,
+		GroupName:      change.GroupName,
+		OldStatus:      oldStatus,
+		NewStatus:      change.NewStatus,
+		TrackingURL:    fedex.TrackingURL(change.TrackingNumber),
+	}
+}
+
+// EmailNotifier emails the user, if they've verified their email, and everyone on the group's recipient list.
+// By default, it only emails when a package is out for delivery, delivered, or hits an exception.
+type EmailNotifier struct {
+	DB     *sql.DB
+	Mailer mailer.Mailer
+}
+
+func (n *EmailNotifier) Name() string {
+	return ChannelEmail
+}
+
+func (n *EmailNotifier) Default(change *history.Change) bool {
+	return slices.Contains(emailStatuses, change.CanonicalStatus)
+}
+
+func (n *EmailNotifier) Send(change *history.Change) error {
+	recipients, err := n.recipients(change)
+	if err != nil {
+		return err
+	}
+	if len(recipients) == 0 {
+		return fmt.Errorf("%w: no verified email or group recipients", Skipped)
+	}
+
+	headline := emailHeadlines[change.CanonicalStatus]
+	if headline == "" {
+		headline = emailHeadlines[fedex.StatusUnknown]
+	}
+	data := newStatusChangeData(change, headline)
+	text := bytes.Buffer{}
+	if err := statusChangeText.Execute(&text, data); err != nil {
+		return err
+	}
+	html := bytes.Buffer{}
+	if err := statusChangeHTML.Execute(&html, data); err != nil {
+		return err
+	}
+
+	// send everyone their own email so that recipients on a group's list can't see each other
+	errs := []error{}
+	for _, recipient := range recipients {
+		err := n.Mailer.Send(&mailer.Message{
+			To:      []string{recipient},
+			Subject: fmt.Sprintf("%s: %s", headline, change.TrackingNumber),
+			Text:    text.String(),
+			HTML:    html.String(),
+		})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("error emailing %s: %w", recipient, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (n *EmailNotifier) recipients(change *history.Change) ([]string, error) {
+	rows, err := n.DB.Query(
+		`select email from users where id = ? and email is not null and email_verified_at is not null
+		union
+		select email from group_email_recipients where org_id = ? and user_id = ? and group_name = ?`,
+		change.UserID, change.OrgID, change.UserID, change.GroupName,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	recipients := []string{}
+	for rows.Next() {
+		var recipient string
+		if err := rows.Scan(&recipient); err != nil {
+			return nil, err
+		}
+		recipients = append(recipients, recipient)
+	}
+
+	return recipients, rows.Err()
+}
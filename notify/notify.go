@@ -0,0 +1,49 @@
+package notify
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/billyb2/tracking_server/history"
+)
+
+// Channel names, as used in notification rules
+const (
+	ChannelEmail = "email"
+	ChannelChat  = "chat"
+	ChannelSMS   = "sms"
+)
+
+var ChannelNames = []string{ChannelEmail, ChannelChat, ChannelSMS}
+
+// Skipped is wrapped by channels when there's nobody to send a change to, like when a user hasn't verified their email
+var Skipped error = fmt.Errorf("skipped")
+
+// A Notifier tells people about a tracking number's status change.
+// Notifiers decide for themselves who should hear about which changes.
+type Notifier interface {
+	Notify(change *history.Change) error
+}
+
+// A Channel is one way of telling a user about a status change, like email or SMS
+type Channel interface {
+	Name() string
+	// Default is whether the channel sends the change when the user hasn't set up any notification rules
+	Default(change *history.Change) bool
+	// Send tells the user about the change, no matter what its status is
+	Send(change *history.Change) error
+}
+
+// Fanout sends every change to each of its notifiers, one after the other in order
+type Fanout []Notifier
+
+func (f Fanout) Notify(change *history.Change) error {
+	errs := []error{}
+	for _, notifier := range f {
+		if err := notifier.Notify(change); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,397 @@
+package notify
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+	// rules have timezones, which the docker image doesn't have a database of
+	_ "time/tzdata"
+
+	"github.com/billyb2/tracking_server/history"
+	fedex "github.com/billyb2/tracking_server/tracking"
+)
+
+// What happened when a rule tried to send a change to a channel
+const (
+	OutcomeSent       = "sent"
+	OutcomeSkipped    = "skipped"
+	OutcomeQuietHours = "quiet_hours"
+	OutcomeDebounced  = "debounced"
+	OutcomeFailed     = "failed"
+)
+
+var InvalidRule error = fmt.Errorf("invalid notification rule")
+var RuleNotFound error = fmt.Errorf("notification rule not found")
+
+type Transition struct {
+	// Canonical status before the change, empty matches any status, including a number that was just added
+	From string `json:"from,omitempty"`
+	// Canonical status after the change, empty matches any status
+	To string `json:"to,omitempty"`
+}
+
+func (t *Transition) matches(change *history.Change) bool {
+	if t.From != "" && (change.OldCanonicalStatus == nil || *change.OldCanonicalStatus != t.From) {
+		return false
+	}
+
+	return t.To == "" || t.To == change.CanonicalStatus
+}
+
+type Rule struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+	// Empty matches every group
+	GroupNames []string `json:"group_names,omitempty"`
+	// Empty matches every status change
+	Transitions []Transition `json:"transitions,omitempty"`
+	Channels    []string     `json:"channels"`
+	// Nothing is sent between quiet_start and quiet_end, both HH:MM in the rule's timezone
+	QuietStart string `json:"quiet_start,omitempty"`
+	QuietEnd   string `json:"quiet_end,omitempty"`
+	// IANA timezone, like America/Chicago. Defaults to UTC
+	Timezone string `json:"timezone,omitempty"`
+	// A tracking number isn't sent to the same channel by this rule more than once in this many seconds
+	DebounceSeconds int       `json:"debounce_seconds,omitempty"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+func (r *Rule) validate() error {
+	if r.Name == "" {
+		return fmt.Errorf("%w: name is required", InvalidRule)
+	}
+
+	if len(r.Channels) == 0 {
+		return fmt.Errorf("%w: at least one channel is required", InvalidRule)
+	}
+	for _, channel := range r.Channels {
+		if !slices.Contains(ChannelNames, channel) {
+			return fmt.Errorf("%w: unknown channel %q", InvalidRule, channel)
+		}
+	}
+
+	for _, transition := range r.Transitions {
+		for _, status := range []string{transition.From, transition.To} {
+			if status != "" && !slices.Contains(fedex.CanonicalStatuses, status) {
+				return fmt.Errorf("%w: unknown status %q", InvalidRule, status)
+			}
+		}
+	}
+
+	if (r.QuietStart == "") != (r.QuietEnd == "") {
+		return fmt.Errorf("%w: quiet hours need both a start and an end", InvalidRule)
+	}
+	for _, quietTime := range []string{r.QuietStart, r.QuietEnd} {
+		if _, err := time.Parse("15:04", quietTime); quietTime != "" && err != nil {
+			return fmt.Errorf("%w: quiet hours must be formatted as HH:MM", InvalidRule)
+		}
+	}
+
+	if r.Timezone == "" {
+		r.Timezone = "UTC"
+	}
+	if _, err := time.LoadLocation(r.Timezone); err != nil {
+		return fmt.Errorf("%w: unknown timezone %q", InvalidRule, r.Timezone)
+	}
+
+	if r.DebounceSeconds < 0 {
+		return fmt.Errorf("%w: debounce_seconds can't be negative", InvalidRule)
+	}
+
+	return nil
+}
+
+func (r *Rule) matches(change *history.Change) bool {
+	if len(r.GroupNames) > 0 && !slices.Contains(r.GroupNames, change.GroupName) {
+		return false
+	}
+	if len(r.Transitions) == 0 {
+		return true
+	}
+
+	return slices.ContainsFunc(r.Transitions, func(transition Transition) bool {
+		return transition.matches(change)
+	})
+}
+
+// inQuietHours handles quiet hours that wrap around midnight, like 22:00 to 07:00
+func (r *Rule) inQuietHours(now time.Time) bool {
+	if r.QuietStart == "" {
+		return false
+	}
+
+	location, err := time.LoadLocation(r.Timezone)
+	if err != nil {
+		location = time.UTC
+	}
+	now = now.In(location)
+	start, _ := time.Parse("15:04", r.QuietStart)
+	end, _ := time.Parse("15:04", r.QuietEnd)
+
+	minute := now.Hour()*60 + now.Minute()
+	startMinute := start.Hour()*60 + start.Minute()
+	endMinute := end.Hour()*60 + end.Minute()
+
+	if startMinute <= endMinute {
+		return minute >= startMinute && minute < endMinute
+	}
+	return minute >= startMinute || minute < endMinute
+}
+
+func CreateRule(db *sql.DB, userID int32, orgID int64, rule *Rule) error {
+	if err := rule.validate(); err != nil {
+		return err
+	}
+
+	groupNames, err := marshalNullableJSON(rule.GroupNames)
+	if err != nil {
+		return err
+	}
+	transitions, err := marshalNullableJSON(rule.Transitions)
+	if err != nil {
+		return err
+	}
+	channels, err := json.Marshal(rule.Channels)
+	if err != nil {
+		return err
+	}
+
+	var quietStart, quietEnd *string
+	if rule.QuietStart != "" {
+		quietStart, quietEnd = &rule.QuietStart, &rule.QuietEnd
+	}
+
+	row := db.QueryRow(
+		`insert into notification_rules (user_id, org_id, name, group_names, transitions, channels, quiet_start, quiet_end, timezone, debounce_seconds, created_at)
+		values (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, datetime('now')) returning id, created_at`,
+		userID, orgID, rule.Name, groupNames, transitions, string(channels), quietStart, quietEnd, rule.Timezone, rule.DebounceSeconds,
+	)
+
+	return row.Scan(&rule.ID, &rule.CreatedAt)
+}
+
+func ListRules(db *sql.DB, userID int32, orgID int64) ([]Rule, error) {
+	rows, err := db.Query(
+		`select id, name, group_names, transitions, channels, coalesce(quiet_start, ''), coalesce(quiet_end, ''), timezone, debounce_seconds, created_at
+		from notification_rules where org_id = ? and user_id = ? order by id`,
+		orgID, userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rules := []Rule{}
+	for rows.Next() {
+		rule := Rule{}
+		var groupNames, transitions *string
+		var channels string
+		if err := rows.Scan(&rule.ID, &rule.Name, &groupNames, &transitions, &channels, &rule.QuietStart, &rule.QuietEnd, &rule.Timezone, &rule.DebounceSeconds, &rule.CreatedAt); err != nil {
+			return nil, err
+		}
+		if groupNames != nil {
+			if err := json.Unmarshal([]byte(*groupNames), &rule.GroupNames); err != nil {
+				return nil, err
+			}
+		}
+		if transitions != nil {
+			if err := json.Unmarshal([]byte(*transitions), &rule.Transitions); err != nil {
+				return nil, err
+			}
+		}
+		if err := json.Unmarshal([]byte(channels), &rule.Channels); err != nil {
+			return nil, err
+		}
+
+		rules = append(rules, rule)
+	}
+
+	return rules, rows.Err()
+}
+
+func DeleteRule(db *sql.DB, userID int32, orgID int64, ruleID int64) error {
+	result, err := db.Exec("delete from notification_rules where id = ? and org_id = ? and user_id = ?", ruleID, orgID, userID)
+	if err != nil {
+		return err
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return RuleNotFound
+	}
+
+	return nil
+}
+
+type LogEntry struct {
+	ID int64 `json:"id"`
+	// Null when the user didn't have any rules and the channel's defaults were used
+	RuleID         *int64    `json:"rule_id"`
+	ChangeID       int64     `json:"change_id"`
+	TrackingNumber string    `json:"tracking_number"`
+	Channel        string    `json:"channel"`
+	Outcome        string    `json:"outcome"`
+	Error          *string   `json:"error,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// Log returns up to limit of the user's notification log entries after the given cursor, oldest first
+func Log(db *sql.DB, userID int32, orgID int64, cursor int64, limit int) ([]LogEntry, error) {
+	rows, err := db.Query(
+		`select id, rule_id, history_id, tracking_number, channel, outcome, error, created_at
+		from notification_log where org_id = ? and user_id = ? and id > ? order by id limit ?`,
+		orgID, userID, cursor, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []LogEntry{}
+	for rows.Next() {
+		entry := LogEntry{}
+		if err := rows.Scan(&entry.ID, &entry.RuleID, &entry.ChangeID, &entry.TrackingNumber, &entry.Channel, &entry.Outcome, &entry.Error, &entry.CreatedAt); err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, rows.Err()
+}
+
+// RuleEngine sends each change to the channels picked by the user's matching rules in the change's organization, or to
+// each channel that would send it by default if the user doesn't have any rules there. Everything it does is recorded in the notification log.
+type RuleEngine struct {
+	DB       *sql.DB
+	Channels []Channel
+}
+
+func (e *RuleEngine) Notify(change *history.Change) error {
+	rules, err := ListRules(e.DB, change.UserID, change.OrgID)
+	if err != nil {
+		return err
+	}
+
+	errs := []error{}
+	if len(rules) == 0 {
+		for _, channel := range e.Channels {
+			if channel.Default(change) {
+				errs = append(errs, e.send(nil, channel, change))
+			}
+		}
+
+		return errors.Join(errs...)
+	}
+
+	now := time.Now()
+	sent := map[string]bool{}
+	for _, rule := range rules {
+		if !rule.matches(change) {
+			continue
+		}
+
+		for _, channelName := range rule.Channels {
+			// more than one rule can match the same change, but nobody wants the same email twice
+			if sent[channelName] {
+				continue
+			}
+
+			channel := e.channel(channelName)
+			if channel == nil {
+				errs = append(errs, e.log(&rule.ID, channelName, change, OutcomeSkipped, "this channel isn't configured on the server"))
+				continue
+			}
+
+			if rule.inQuietHours(now) {
+				errs = append(errs, e.log(&rule.ID, channelName, change, OutcomeQuietHours, ""))
+				continue
+			}
+
+			debounced, err := e.debounced(&rule, channelName, change)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			if debounced {
+				errs = append(errs, e.log(&rule.ID, channelName, change, OutcomeDebounced, ""))
+				continue
+			}
+
+			sent[channelName] = true
+			errs = append(errs, e.send(&rule.ID, channel, change))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (e *RuleEngine) channel(name string) Channel {
+	for _, channel := range e.Channels {
+		if channel.Name() == name {
+			return channel
+		}
+	}
+
+	return nil
+}
+
+func (e *RuleEngine) debounced(rule *Rule, channelName string, change *history.Change) (bool, error) {
+	if rule.DebounceSeconds == 0 {
+		return false, nil
+	}
+
+	var sent int
+	err := e.DB.QueryRow(
+		`select count(*) from notification_log
+		where rule_id = ? and tracking_number = ? and channel = ? and outcome = ? and created_at > datetime('now', ?)`,
+		rule.ID, change.TrackingNumber, channelName, OutcomeSent, fmt.Sprintf("-%d seconds", rule.DebounceSeconds),
+	).Scan(&sent)
+
+	return sent > 0, err
+}
+
+// send only returns the channel's error if it actually failed, not if there was nobody to send the change to
+func (e *RuleEngine) send(ruleID *int64, channel Channel, change *history.Change) error {
+	sendErr := channel.Send(change)
+	switch {
+	case sendErr == nil:
+		return e.log(ruleID, channel.Name(), change, OutcomeSent, "")
+	case errors.Is(sendErr, Skipped):
+		return e.log(ruleID, channel.Name(), change, OutcomeSkipped, sendErr.Error())
+	default:
+		return errors.Join(sendErr, e.log(ruleID, channel.Name(), change, OutcomeFailed, sendErr.Error()))
+	}
+}
+
+func (e *RuleEngine) log(ruleID *int64, channelName string, change *history.Change, outcome, errStr string) error {
+	var errPtr *string
+	if errStr != "" {
+		errPtr = &errStr
+	}
+
+	_, err := e.DB.Exec(
+		"insert into notification_log (user_id, org_id, rule_id, history_id, tracking_number, channel, outcome, error, created_at) values (?, ?, ?, ?, ?, ?, ?, ?, datetime('now'))",
+		change.UserID, change.OrgID, ruleID, change.ID, change.TrackingNumber, channelName, outcome, errPtr,
+	)
+	return err
+}
+
+func marshalNullableJSON[T any](values []T) (*string, error) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+
+	valuesJSON, err := json.Marshal(values)
+	if err != nil {
+		return nil, err
+	}
+	valuesStr := string(valuesJSON)
+
+	return &valuesStr, nil
+}
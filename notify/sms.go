@@ -0,0 +1,303 @@
+package notify
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/billyb2/tracking_server/history"
+	fedex "github.com/billyb2/tracking_server/tracking"
+	"github.com/gin-gonic/gin"
+)
+
+const smsClientContextKey = "smsClientContextKey"
+
+const defaultSMSDailyCap = 10
+
+// E.164, like +15555550123
+var phoneNumberRegex = regexp.MustCompile(`^\+[1-9][0-9]{1,14}$`)
+
+var InvalidPhoneNumber error = fmt.Errorf("phone numbers must be in E.164 format, like +15555550123")
+var PhoneOptedOut error = fmt.Errorf("this number replied STOP, and has to reply START before it can be opted back in")
+var SMSNotConfigured error = fmt.Errorf("texting is not configured on this server")
+var DailyCapReached error = fmt.Errorf("reached the daily limit of texts")
+
+var smsStatuses = []string{fedex.StatusOutForDelivery}
+
+// Keywords carriers expect to opt people out of and back into texts
+var smsOptOutKeywords = []string{"STOP", "STOPALL", "UNSUBSCRIBE", "CANCEL", "END", "QUIT", "OPTOUT", "REVOKE"}
+var smsOptInKeywords = []string{"START", "UNSTOP", "YES", "OPTIN"}
+
+func ValidPhoneNumber(phone string) bool {
+	return phoneNumberRegex.MatchString(phone)
+}
+
+// SMSClient sends texts through Twilio's REST API, or anything compatible with it
+type SMSClient struct {
+	BaseURL    string
+	AccountSID string
+	AuthToken  string
+	From       string
+	// The most texts any one user can be sent each (UTC) day
+	DailyCap   int
+	HTTPClient *http.Client
+}
+
+// NewSMSClientFromEnv configures an SMS client from the TWILIO_* env vars, returning nil if TWILIO_ACCOUNT_SID isn't set
+func NewSMSClientFromEnv() (*SMSClient, error) {
+	accountSID := os.Getenv("TWILIO_ACCOUNT_SID")
+	if accountSID == "" {
+		return nil, nil
+	}
+
+	baseURL := "https://api.twilio.com"
+	if envURL := os.Getenv("TWILIO_BASE_URL"); envURL != "" {
+		baseURL = strings.TrimSuffix(envURL, "/")
+	}
+
+	dailyCap := defaultSMSDailyCap
+	if envCap := os.Getenv("SMS_DAILY_CAP"); envCap != "" {
+		var err error
+		if dailyCap, err = strconv.Atoi(envCap); err != nil {
+			return nil, fmt.Errorf("invalid SMS_DAILY_CAP: %w", err)
+		}
+	}
+
+	return &SMSClient{
+		BaseURL:    baseURL,
+		AccountSID: accountSID,
+		AuthToken:  os.Getenv("TWILIO_AUTH_TOKEN"),
+		From:       os.Getenv("TWILIO_FROM_NUMBER"),
+		DailyCap:   dailyCap,
+		HTTPClient: &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+type twilioMessageResp struct {
+	SID     string `json:"sid"`
+	Message string `json:"message"`
+}
+
+// Send texts the body to the phone number, returning the provider's message sid
+func (s *SMSClient) Send(to, body string) (string, error) {
+	form := url.Values{}
+	form.Set("To", to)
+	form.Set("From", s.From)
+	form.Set("Body", body)
+
+	messagesURL := fmt.Sprintf("%s/2010-04-01/Accounts/%s/Messages.json", s.BaseURL, url.PathEscape(s.AccountSID))
+	req, err := http.NewRequest(http.MethodPost, messagesURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(s.AccountSID, s.AuthToken)
+
+	resp, err := s.HTTPClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if err != nil {
+		return "", err
+	}
+
+	messageResp := twilioMessageResp{}
+	json.Unmarshal(respBody, &messageResp)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		if messageResp.Message != "" {
+			return "", fmt.Errorf("non-2xx status code %d: %s", resp.StatusCode, messageResp.Message)
+		}
+		return "", fmt.Errorf("non-2xx status code %d: %s", resp.StatusCode, string(respBody))
+	}
+
+	return messageResp.SID, nil
+}
+
+// ValidSignature checks the X-Twilio-Signature header of an inbound webhook, which is the base64 encoded HMAC-SHA1 of
+// the full webhook URL followed by every POST parameter's name and value, sorted by name
+func (s *SMSClient) ValidSignature(webhookURL string, params url.Values, signature string) bool {
+	keys := make([]string, 0, len(params))
+	for key := range params {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	mac := hmac.New(sha1.New, []byte(s.AuthToken))
+	mac.Write([]byte(webhookURL))
+	for _, key := range keys {
+		for _, value := range params[key] {
+			mac.Write([]byte(key + value))
+		}
+	}
+	expected := base64.StdEncoding.EncodeToString(mac.Sum(nil))
+
+	return hmac.Equal([]byte(expected), []byte(signature))
+}
+
+// SMSKeywordOptIn returns whether an inbound text opts the sender in or out of texts, and false for ok if it does neither
+func SMSKeywordOptIn(body string) (optIn bool, ok bool) {
+	keyword := strings.ToUpper(strings.TrimSpace(body))
+	switch {
+	case slices.Contains(smsOptOutKeywords, keyword):
+		return false, true
+	case slices.Contains(smsOptInKeywords, keyword):
+		return true, true
+	default:
+		return false, false
+	}
+}
+
+// SetSMSOptIn opts every user with the phone number in or out of texts. Opting out is remembered, so that users can't opt
+// the number back in until it opts in itself.
+func SetSMSOptIn(db *sql.DB, phone string, optIn bool) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("update users set sms_opt_in = ? where phone = ?", optIn, phone); err != nil {
+		return err
+	}
+	if optIn {
+		_, err = tx.Exec("delete from sms_opt_outs where phone = ?", phone)
+	} else {
+		_, err = tx.Exec("insert into sms_opt_outs (phone, created_at) values (?, datetime('now')) on conflict do nothing", phone)
+	}
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// SMSOptedOut is whether the phone number replied STOP, and hasn't replied START since
+func SMSOptedOut(db *sql.DB, phone string) (bool, error) {
+	var optedOut bool
+	err := db.QueryRow("select exists(select 1 from sms_opt_outs where phone = ?)", phone).Scan(&optedOut)
+	return optedOut, err
+}
+
+// SendVerificationCode texts the user a code to verify their phone number with, which counts towards their daily cap
+func SendVerificationCode(db *sql.DB, client *SMSClient, userID int32, phone, code string) error {
+	sentToday, err := sentToday(db, userID)
+	if err != nil {
+		return err
+	}
+	if sentToday >= client.DailyCap {
+		return fmt.Errorf("%w: try again tomorrow", DailyCapReached)
+	}
+
+	body := fmt.Sprintf("Your verification code is %s. It expires in 10 minutes.", code)
+	sid, err := client.Send(phone, body)
+	if err != nil {
+		return fmt.Errorf("error texting verification code: %w", err)
+	}
+
+	return recordSend(db, userID, phone, body, sid)
+}
+
+// sentToday is how many texts the user has been sent since midnight UTC
+func sentToday(db *sql.DB, userID int32) (int, error) {
+	var sent int
+	err := db.QueryRow("select count(*) from sms_sends where user_id = ? and sent_at >= date('now')", userID).Scan(&sent)
+	return sent, err
+}
+
+func recordSend(db *sql.DB, userID int32, phone, body, sid string) error {
+	_, err := db.Exec(
+		"insert into sms_sends (user_id, phone, body, sid, sent_at) values (?, ?, ?, ?, datetime('now'))",
+		userID, phone, body, sid,
+	)
+	return err
+}
+
+func SMSClientWithGinContext(c *gin.Context, client *SMSClient) {
+	c.Set(smsClientContextKey, client)
+	c.Next()
+}
+
+// SMSClientFromGinContext returns the server's SMS client, or nil if SMS isn't configured
+func SMSClientFromGinContext(c *gin.Context) *SMSClient {
+	client, _ := c.Get(smsClientContextKey)
+	smsClient, _ := client.(*SMSClient)
+
+	return smsClient
+}
+
+// SMSNotifier texts users who've verified their number and opted in, up to the client's daily cap.
+// By default, it only texts when a package is out for delivery.
+type SMSNotifier struct {
+	DB     *sql.DB
+	Client *SMSClient
+}
+
+func (n *SMSNotifier) Name() string {
+	return ChannelSMS
+}
+
+func (n *SMSNotifier) Default(change *history.Change) bool {
+	return slices.Contains(smsStatuses, change.CanonicalStatus)
+}
+
+func (n *SMSNotifier) Send(change *history.Change) error {
+	row := n.DB.QueryRow("select phone from users where id = ? and phone is not null and phone_verified_at is not null and sms_opt_in", change.UserID)
+
+	var phone string
+	if err := row.Scan(&phone); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return fmt.Errorf("%w: no verified phone number or not opted in", Skipped)
+		default:
+			return err
+		}
+	}
+
+	sentToday, err := sentToday(n.DB, change.UserID)
+	if err != nil {
+		return err
+	}
+	if sentToday >= n.Client.DailyCap {
+		return fmt.Errorf("%w: reached the daily limit of %d texts", Skipped, n.Client.DailyCap)
+	}
+
+	body := smsBody(change)
+	sid, err := n.Client.Send(phone, body)
+	if err != nil {
+		return fmt.Errorf("error texting user %d: %w", change.UserID, err)
+	}
+
+	return recordSend(n.DB, change.UserID, phone, body, sid)
+}
+
+// smsBody keeps texts short enough to fit in as few segments as possible
+func smsBody(change *history.Change) string {
+	body := fmt.Sprintf("Package %s", change.TrackingNumber)
+	if change.GroupName != "" {
+		body += fmt.Sprintf(" (%s)", change.GroupName)
+	}
+	body += ": " + change.NewStatus
+	if change.ETA != nil && change.CanonicalStatus != fedex.StatusDelivered {
+		body += ", ETA " + change.ETA.Format("Jan 2 3:04 PM")
+	}
+
+	return body + ". Reply STOP to opt out."
+}
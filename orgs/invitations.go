@@ -0,0 +1,303 @@
+package orgs
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/mail"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// InvitationPrefix starts every invite token
+const InvitationPrefix = "tsi_"
+
+// InvitationTTL is how long invitations can be accepted for
+const InvitationTTL = 7 * 24 * time.Hour
+
+const signerContextKey = "signerContextKey"
+
+// The shortest INVITE_SIGNING_KEY allowed
+const minSigningKeyLength = 32
+
+var InvalidNewInvitation error = fmt.Errorf("invalid invitation")
+var InvalidInvitation error = fmt.Errorf("invalid or expired invitation")
+var InvitationAnswered error = fmt.Errorf("%w: it was already accepted or declined", InvalidInvitation)
+var InvitationNotFound error = fmt.Errorf("invitation not found")
+
+// Signer signs invite tokens, so they can't be guessed or changed
+type Signer struct {
+	key []byte
+}
+
+// NewSignerFromEnv signs invite tokens with INVITE_SIGNING_KEY. Without it, a random key is used, so invitations stop
+// working when the server restarts.
+func NewSignerFromEnv() (*Signer, error) {
+	if key := os.Getenv("INVITE_SIGNING_KEY"); key != "" {
+		if len(key) < minSigningKeyLength {
+			return nil, fmt.Errorf("INVITE_SIGNING_KEY has to be at least %d characters", minSigningKeyLength)
+		}
+		return &Signer{key: []byte(key)}, nil
+	}
+
+	key := make([]byte, minSigningKeyLength)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	fmt.Fprintln(os.Stderr, "INVITE_SIGNING_KEY isn't set, so invitations will stop working when the server restarts")
+
+	return &Signer{key: key}, nil
+}
+
+func (s *Signer) sign(payload string) string {
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// token makes an invite token, which is tsi_<invitation id>.<expiry as a unix timestamp>.<signature of the rest>
+func (s *Signer) token(id int64, expiresAt time.Time) string {
+	payload := fmt.Sprintf("%s%d.%d", InvitationPrefix, id, expiresAt.Unix())
+	return payload + "." + s.sign(payload)
+}
+
+// invitationID checks the invite token's signature and expiry, returning the invitation it's for
+func (s *Signer) invitationID(token string) (int64, error) {
+	payload, signature, ok := cutLast(token, ".")
+	if !ok || !hmac.Equal([]byte(signature), []byte(s.sign(payload))) {
+		return 0, InvalidInvitation
+	}
+
+	idStr, expiresStr, ok := strings.Cut(strings.TrimPrefix(payload, InvitationPrefix), ".")
+	if !ok {
+		return 0, InvalidInvitation
+	}
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		return 0, InvalidInvitation
+	}
+	expires, err := strconv.ParseInt(expiresStr, 10, 64)
+	if err != nil || time.Now().Unix() >= expires {
+		return 0, InvalidInvitation
+	}
+
+	return id, nil
+}
+
+func cutLast(s, sep string) (string, string, bool) {
+	i := strings.LastIndex(s, sep)
+	if i == -1 {
+		return s, "", false
+	}
+
+	return s[:i], s[i+len(sep):], true
+}
+
+type Invitation struct {
+	ID      int64  `json:"id"`
+	OrgID   int64  `json:"org_id"`
+	OrgName string `json:"org_name"`
+	Email   string `json:"email"`
+	// The role the invited user joins with
+	Role string `json:"role"`
+	// The username of whoever sent it
+	InvitedBy string    `json:"invited_by"`
+	ExpiresAt time.Time `json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+const invitationColumns = "i.id, i.org_id, o.name, i.email, i.role, u.username, i.expires_at, i.created_at"
+
+// invitationTables is what invitationColumns are selected from
+const invitationTables = "invitations i join organizations o on o.id = i.org_id join users u on u.id = i.invited_by"
+
+func scanInvitation(row interface{ Scan(...any) error }, invitation *Invitation, extra ...any) error {
+	return row.Scan(append([]any{&invitation.ID, &invitation.OrgID, &invitation.OrgName, &invitation.Email, &invitation.Role, &invitation.InvitedBy, &invitation.ExpiresAt, &invitation.CreatedAt}, extra...)...)
+}
+
+// Invite makes an invitation to the organization for the email, returning it along with the invite token to send them.
+// Inviting an email again replaces its pending invitation. Only owners can invite other owners.
+func Invite(db *sql.DB, signer *Signer, actor *Membership, email, role string) (*Invitation, string, error) {
+	address, err := mail.ParseAddress(email)
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: invalid email: %s", InvalidNewInvitation, err)
+	}
+	if !validRole(role) {
+		return nil, "", InvalidRole
+	}
+	if role == RoleOwner && actor.Role != RoleOwner {
+		return nil, "", InsufficientRole
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, "", err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(
+		"delete from invitations where org_id = ? and email = ? and accepted_at is null and declined_at is null",
+		actor.OrgID, address.Address,
+	); err != nil {
+		return nil, "", err
+	}
+
+	expiresAt := time.Now().Add(InvitationTTL).Truncate(time.Second)
+	var id int64
+	if err := tx.QueryRow(
+		"insert into invitations (org_id, email, role, invited_by, expires_at, created_at) values (?, ?, ?, ?, ?, datetime('now')) returning id",
+		actor.OrgID, address.Address, role, actor.UserID, expiresAt.UTC().Format(time.DateTime),
+	).Scan(&id); err != nil {
+		return nil, "", err
+	}
+
+	invitation := Invitation{}
+	if err := scanInvitation(tx.QueryRow("select "+invitationColumns+" from "+invitationTables+" where i.id = ?", id), &invitation); err != nil {
+		return nil, "", err
+	}
+
+	return &invitation, signer.token(id, expiresAt), tx.Commit()
+}
+
+// ListInvitations returns the organization's invitations that haven't been answered or expired yet
+func ListInvitations(db *sql.DB, orgID int64) ([]Invitation, error) {
+	rows, err := db.Query(
+		`select `+invitationColumns+` from `+invitationTables+`
+		where i.org_id = ? and i.accepted_at is null and i.declined_at is null and unixepoch(i.expires_at) > unixepoch('now')
+		order by i.id`,
+		orgID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	invitations := []Invitation{}
+	for rows.Next() {
+		invitation := Invitation{}
+		if err := scanInvitation(rows, &invitation); err != nil {
+			return nil, err
+		}
+		invitations = append(invitations, invitation)
+	}
+
+	return invitations, rows.Err()
+}
+
+// RevokeInvitation deletes an invitation, so its token stops working
+func RevokeInvitation(db *sql.DB, orgID, id int64) error {
+	result, err := db.Exec("delete from invitations where id = ? and org_id = ? and accepted_at is null and declined_at is null", id, orgID)
+	if err != nil {
+		return err
+	}
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return InvitationNotFound
+	}
+
+	return nil
+}
+
+// pendingInvitation looks up the invitation an invite token is for, checking it can still be answered
+func pendingInvitation(tx *sql.Tx, signer *Signer, token string) (*Invitation, error) {
+	id, err := signer.invitationID(token)
+	if err != nil {
+		return nil, err
+	}
+
+	invitation := Invitation{}
+	var answered bool
+	row := tx.QueryRow(
+		"select "+invitationColumns+", i.accepted_at is not null or i.declined_at is not null from "+invitationTables+" where i.id = ?",
+		id,
+	)
+	if err := scanInvitation(row, &invitation, &answered); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, InvalidInvitation
+		default:
+			return nil, err
+		}
+	}
+	if answered {
+		return nil, InvitationAnswered
+	}
+
+	return &invitation, nil
+}
+
+// AcceptInvitation adds the user to the organization with the role they were invited with. Anyone with the invite token
+// can accept it, and only once.
+func AcceptInvitation(tx *sql.Tx, signer *Signer, token string, userID int32) (*Organization, error) {
+	invitation, err := pendingInvitation(tx, signer, token)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := addMember(tx, invitation.OrgID, userID, invitation.Role); err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec("update invitations set accepted_at = datetime('now') where id = ?", invitation.ID); err != nil {
+		return nil, err
+	}
+
+	organization := Organization{ID: invitation.OrgID, Name: invitation.OrgName, Role: invitation.Role}
+	if err := tx.QueryRow("select created_at from organizations where id = ?", invitation.OrgID).Scan(&organization.CreatedAt); err != nil {
+		return nil, err
+	}
+
+	return &organization, nil
+}
+
+// DeclineInvitation turns an invitation down, so its token stops working
+func DeclineInvitation(db *sql.DB, signer *Signer, token string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	invitation, err := pendingInvitation(tx, signer, token)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec("update invitations set declined_at = datetime('now') where id = ?", invitation.ID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Run deletes expired invitations forever, once an hour
+func Run(db *sql.DB) {
+	for {
+		if _, err := db.Exec("delete from invitations where unixepoch(expires_at) < unixepoch('now')"); err != nil {
+			fmt.Fprintln(os.Stderr, "error deleting expired invitations", err)
+		}
+
+		time.Sleep(time.Hour)
+	}
+}
+
+func SignerWithGinContext(c *gin.Context, signer *Signer) {
+	c.Set(signerContextKey, signer)
+	c.Next()
+}
+
+func SignerFromGinContext(c *gin.Context) *Signer {
+	signer, _ := c.Get(signerContextKey)
+	s, _ := signer.(*Signer)
+
+	return s
+}
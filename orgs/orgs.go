@@ -0,0 +1,297 @@
+package orgs
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/billyb2/tracking_server/auth"
+	"github.com/gin-gonic/gin"
+)
+
+// Header picks which of the user's organizations a request acts in. Without it, requests act in the organization the
+// user joined first.
+const Header = "X-Organization-ID"
+
+const membershipContextKey = "membershipContextKey"
+
+const maxNameLength = 100
+
+const (
+	// Viewers can see the organization's numbers
+	RoleViewer = "viewer"
+	// Members can also start, change and stop tracking numbers
+	RoleMember = "member"
+	// Admins can also manage members
+	RoleAdmin = "admin"
+	// Owners can also make other owners
+	RoleOwner = "owner"
+)
+
+// Roles from the fewest permissions to the most
+var Roles = []string{RoleViewer, RoleMember, RoleAdmin, RoleOwner}
+
+var InvalidRole error = fmt.Errorf("roles are %s", strings.Join(Roles, ", "))
+var InvalidName error = fmt.Errorf("organization names can't be empty or longer than %d characters", maxNameLength)
+var AlreadyMember error = fmt.Errorf("the user is already a member")
+var MemberNotFound error = fmt.Errorf("member not found")
+var LastOwner error = fmt.Errorf("organizations need at least one owner")
+var NotAMember error = fmt.Errorf("%w: not a member of that organization", auth.Forbidden)
+var NoOrganization error = fmt.Errorf("%w: not a member of any organization", auth.Forbidden)
+var InsufficientRole error = fmt.Errorf("%w: your role in the organization doesn't allow this", auth.Forbidden)
+
+type Organization struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+	// The user's role in it
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Membership is the organization a request acts in, and the user's role in it
+type Membership struct {
+	OrgID  int64
+	UserID int32
+	Role   string
+}
+
+type Member struct {
+	UserID   int32     `json:"user_id"`
+	Username string    `json:"username"`
+	Role     string    `json:"role"`
+	JoinedAt time.Time `json:"joined_at"`
+}
+
+func validRole(role string) bool {
+	return slices.Contains(Roles, role)
+}
+
+// RoleAtLeast is whether role has every permission required does
+func RoleAtLeast(role, required string) bool {
+	return slices.Index(Roles, role) >= slices.Index(Roles, required)
+}
+
+// Create makes an organization owned by the user
+func Create(tx *sql.Tx, name string, ownerID int32) (int64, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || len(name) > maxNameLength {
+		return 0, InvalidName
+	}
+
+	var orgID int64
+	if err := tx.QueryRow("insert into organizations (name, created_at) values (?, datetime('now')) returning id", name).Scan(&orgID); err != nil {
+		return 0, err
+	}
+	if _, err := tx.Exec(
+		"insert into organization_members (org_id, user_id, role, created_at) values (?, ?, ?, datetime('now'))",
+		orgID, ownerID, RoleOwner,
+	); err != nil {
+		return 0, err
+	}
+
+	return orgID, nil
+}
+
+// MembershipFor looks up the user's role in the organization, or in the one they joined first if orgID is 0
+func MembershipFor(db *sql.DB, userID int32, orgID int64) (*Membership, error) {
+	membership := Membership{UserID: userID}
+	var row *sql.Row
+	if orgID == 0 {
+		row = db.QueryRow("select org_id, role from organization_members where user_id = ? order by unixepoch(created_at), rowid limit 1", userID)
+	} else {
+		row = db.QueryRow("select org_id, role from organization_members where user_id = ? and org_id = ?", userID, orgID)
+	}
+	if err := row.Scan(&membership.OrgID, &membership.Role); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows) && orgID == 0:
+			return nil, NoOrganization
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, NotAMember
+		default:
+			return nil, err
+		}
+	}
+
+	return &membership, nil
+}
+
+// List returns the organizations the user is in, in the order they joined them
+func List(db *sql.DB, userID int32) ([]Organization, error) {
+	rows, err := db.Query(
+		`select o.id, o.name, m.role, o.created_at from organizations o join organization_members m on m.org_id = o.id
+		where m.user_id = ? order by unixepoch(m.created_at), m.rowid`,
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	organizations := []Organization{}
+	for rows.Next() {
+		organization := Organization{}
+		if err := rows.Scan(&organization.ID, &organization.Name, &organization.Role, &organization.CreatedAt); err != nil {
+			return nil, err
+		}
+		organizations = append(organizations, organization)
+	}
+
+	return organizations, rows.Err()
+}
+
+func Members(db *sql.DB, orgID int64) ([]Member, error) {
+	rows, err := db.Query(
+		`select u.id, u.username, m.role, m.created_at from organization_members m join users u on u.id = m.user_id
+		where m.org_id = ? order by u.username`,
+		orgID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	members := []Member{}
+	for rows.Next() {
+		member := Member{}
+		if err := rows.Scan(&member.UserID, &member.Username, &member.Role, &member.JoinedAt); err != nil {
+			return nil, err
+		}
+		members = append(members, member)
+	}
+
+	return members, rows.Err()
+}
+
+func addMember(tx *sql.Tx, orgID int64, userID int32, role string) (time.Time, error) {
+	var joinedAt time.Time
+	err := tx.QueryRow(
+		"insert into organization_members (org_id, user_id, role, created_at) values (?, ?, ?, datetime('now')) on conflict do nothing returning created_at",
+		orgID, userID, role,
+	).Scan(&joinedAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return joinedAt, AlreadyMember
+		default:
+			return joinedAt, err
+		}
+	}
+
+	return joinedAt, nil
+}
+
+// SetRole changes a member's role. Only owners can change owners or make new ones, and the last owner can't stop being
+// one.
+func SetRole(db *sql.DB, actor *Membership, userID int32, role string) (*Member, error) {
+	if !validRole(role) {
+		return nil, InvalidRole
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	current, err := memberRole(tx, actor.OrgID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if (current == RoleOwner || role == RoleOwner) && actor.Role != RoleOwner {
+		return nil, InsufficientRole
+	}
+	if current == RoleOwner && role != RoleOwner {
+		if err := checkOtherOwners(tx, actor.OrgID); err != nil {
+			return nil, err
+		}
+	}
+
+	member := Member{UserID: userID, Role: role}
+	if err := tx.QueryRow(
+		`update organization_members set role = ? where org_id = ? and user_id = ?
+		returning (select username from users where id = user_id), created_at`,
+		role, actor.OrgID, userID,
+	).Scan(&member.Username, &member.JoinedAt); err != nil {
+		return nil, err
+	}
+
+	return &member, tx.Commit()
+}
+
+// RemoveMember takes the user out of the organization, deleting the API keys they made in it and logging them out
+// everywhere, since their sessions could have been used to act in it. Only owners can remove owners, and the last owner
+// can't be removed.
+func RemoveMember(db *sql.DB, actor *Membership, userID int32) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	current, err := memberRole(tx, actor.OrgID, userID)
+	if err != nil {
+		return err
+	}
+	if current == RoleOwner {
+		if actor.Role != RoleOwner {
+			return InsufficientRole
+		}
+		if err := checkOtherOwners(tx, actor.OrgID); err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.Exec("delete from organization_members where org_id = ? and user_id = ?", actor.OrgID, userID); err != nil {
+		return err
+	}
+	if err := auth.RevokeOrgAPIKeys(tx, userID, actor.OrgID); err != nil {
+		return err
+	}
+	if err := auth.RevokeUserTokens(tx, userID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func memberRole(tx *sql.Tx, orgID int64, userID int32) (string, error) {
+	var role string
+	if err := tx.QueryRow("select role from organization_members where org_id = ? and user_id = ?", orgID, userID).Scan(&role); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return "", MemberNotFound
+		default:
+			return "", err
+		}
+	}
+
+	return role, nil
+}
+
+// checkOtherOwners returns LastOwner unless the organization has more than one owner
+func checkOtherOwners(tx *sql.Tx, orgID int64) error {
+	var owners int
+	if err := tx.QueryRow("select count(*) from organization_members where org_id = ? and role = ?", orgID, RoleOwner).Scan(&owners); err != nil {
+		return err
+	}
+	if owners <= 1 {
+		return LastOwner
+	}
+
+	return nil
+}
+
+func MembershipWithGinContext(c *gin.Context, membership *Membership) {
+	c.Set(membershipContextKey, membership)
+}
+
+// MembershipFromGinContext returns the organization the request acts in, or nil if it wasn't authenticated
+func MembershipFromGinContext(c *gin.Context) *Membership {
+	membership, _ := c.Get(membershipContextKey)
+	m, _ := membership.(*Membership)
+
+	return m
+}
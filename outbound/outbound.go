@@ -0,0 +1,151 @@
+package outbound
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"syscall"
+	"time"
+)
+
+var DisallowedAddress error = fmt.Errorf("urls can't point at loopback, link-local or private addresses")
+
+// Allowlist is hosts and networks urls can point at even though they'd otherwise be disallowed, like stand-ins for
+// chat services and webhook receivers running next to the server
+type Allowlist struct {
+	hosts    map[string]bool
+	networks []*net.IPNet
+}
+
+// allowlist is empty unless SetAllowlist is called
+var allowlist = &Allowlist{}
+
+// AllowlistFromEnv reads OUTBOUND_ALLOW_HOSTS, a comma separated list of hostnames, IP addresses and CIDR ranges.
+// Nothing is allowed when it isn't set.
+func AllowlistFromEnv() (*Allowlist, error) {
+	list := &Allowlist{hosts: map[string]bool{}}
+	for _, entry := range strings.Split(os.Getenv("OUTBOUND_ALLOW_HOSTS"), ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		if strings.Contains(entry, "/") {
+			_, network, err := net.ParseCIDR(entry)
+			if err != nil {
+				return nil, fmt.Errorf("invalid OUTBOUND_ALLOW_HOSTS entry: %q", entry)
+			}
+			list.networks = append(list.networks, network)
+		} else if ip := net.ParseIP(entry); ip != nil {
+			list.networks = append(list.networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(len(ip)*8, len(ip)*8)})
+		} else {
+			list.hosts[normalizeHost(entry)] = true
+		}
+	}
+
+	return list, nil
+}
+
+// SetAllowlist changes which otherwise disallowed hosts and networks urls can point at
+func SetAllowlist(list *Allowlist) {
+	allowlist = list
+}
+
+func normalizeHost(host string) string {
+	return strings.TrimSuffix(strings.ToLower(host), ".")
+}
+
+func (list *Allowlist) hasHost(host string) bool {
+	return list.hosts[normalizeHost(host)]
+}
+
+func (list *Allowlist) hasIP(ip net.IP) bool {
+	for _, network := range list.networks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// allowed is false for addresses that reach the server itself or the network it's on rather than the internet, unless
+// they've been allowlisted
+func allowed(ip net.IP) bool {
+	if allowlist.hasIP(ip) {
+		return true
+	}
+	return !ip.IsLoopback() && !ip.IsPrivate() && !ip.IsLinkLocalUnicast() && !ip.IsLinkLocalMulticast() &&
+		!ip.IsInterfaceLocalMulticast() && !ip.IsUnspecified()
+}
+
+// CheckURL resolves the url's host and makes sure none of its addresses are disallowed, so that users can't have the
+// server send requests to itself or its network
+func CheckURL(ctx context.Context, rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+
+	host := u.Hostname()
+	if allowlist.hasHost(host) {
+		return nil
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		if !allowed(ip) {
+			return DisallowedAddress
+		}
+		return nil
+	}
+
+	ips, err := net.DefaultResolver.LookupIP(ctx, "ip", host)
+	if err != nil {
+		return fmt.Errorf("couldn't resolve %q: %w", host, err)
+	}
+	for _, ip := range ips {
+		if !allowed(ip) {
+			return DisallowedAddress
+		}
+	}
+
+	return nil
+}
+
+// NewClient makes an http client for urls users give, which refuses to connect to disallowed addresses. The check is
+// made on the address actually dialed, so a host that resolved to somewhere allowed when the url was checked can't
+// switch to a disallowed address later, and redirects can't lead to one either. Allowlisted hostnames are dialed
+// without the check.
+func NewClient(timeout time.Duration) *http.Client {
+	allowlistedDialer := &net.Dialer{Timeout: 10 * time.Second}
+	dialer := &net.Dialer{
+		Timeout: 10 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !allowed(ip) {
+				return fmt.Errorf("%w: %s", DisallowedAddress, address)
+			}
+			return nil
+		},
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// a proxy would be what's dialed, rather than the url's host
+	transport.Proxy = nil
+	transport.DialContext = func(ctx context.Context, network, address string) (net.Conn, error) {
+		if host, _, err := net.SplitHostPort(address); err == nil && allowlist.hasHost(host) {
+			return allowlistedDialer.DialContext(ctx, network, address)
+		}
+		return dialer.DialContext(ctx, network, address)
+	}
+
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+	}
+}
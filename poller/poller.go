@@ -0,0 +1,204 @@
+package poller
+
+import (
+	"database/sql"
+	"fmt"
+	"maps"
+	"os"
+	"time"
+
+	"github.com/billyb2/tracking_server/history"
+	"github.com/billyb2/tracking_server/notify"
+	fedex "github.com/billyb2/tracking_server/tracking"
+	"github.com/billyb2/tracking_server/webhooks"
+)
+
+// Shipments that couldn't be refreshed are retried after firstRetryDelay, doubling each time up to maxRetryDelay
+const (
+	firstRetryDelay = 5 * time.Minute
+	maxRetryDelay   = 24 * time.Hour
+)
+
+type shipment struct {
+	id     int64
+	status *string
+	// canonical status before this poll
+	canonicalStatus *string
+	failedPolls     int
+}
+
+// Run polls the FedEx API forever, once a minute
+func Run(db *sql.DB, notifier notify.Notifier) {
+	for {
+		if err := Poll(db, notifier); err != nil {
+			fmt.Fprintln(os.Stderr, "error polling tracking numbers", err)
+		}
+
+		time.Sleep(60 * time.Second)
+	}
+}
+
+// Poll refreshes every shipment that hasn't been updated in the last 30 minutes and that somebody is tracking without
+// having archived it, recording any status changes and telling the notifier about them once they're committed. Each
+// shipment is only polled once no matter how many users are tracking it, and every one of them is told about changes.
+// Shipments FedEx doesn't return a status for are backed off with retryDelay rather than asked about every minute.
+func Poll(db *sql.DB, notifier notify.Notifier) error {
+	rows, err := db.Query(
+		`select id, tracking_number, status, canonical_status, failed_polls from shipments
+		where exists(select 1 from subscriptions where shipment_id = shipments.id and archived_at is null)
+		and unixepoch('now', 'auto') - unixepoch(status_last_updated, 'auto') > 1800
+		and (next_poll_at is null or unixepoch(next_poll_at) <= unixepoch('now'))`,
+	)
+	if err != nil {
+		return fmt.Errorf("error querying tracking numbers: %w", err)
+	}
+	defer rows.Close()
+
+	shipments := map[string]shipment{}
+	trackingNumbers := []string{}
+	for rows.Next() {
+		var trackingNumber string
+		shipment := shipment{}
+		if err := rows.Scan(&shipment.id, &trackingNumber, &shipment.status, &shipment.canonicalStatus, &shipment.failedPolls); err != nil {
+			return fmt.Errorf("error scanning tracking numbers: %w", err)
+		}
+		shipments[trackingNumber] = shipment
+		trackingNumbers = append(trackingNumbers, trackingNumber)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error scanning tracking numbers: %w", err)
+	}
+	rows.Close()
+
+	if len(trackingNumbers) == 0 {
+		return nil
+	}
+
+	// a batch that fails is backed off along with numbers FedEx returns errors for, rather than holding up the rest
+	trackingStatuses := map[string]fedex.TrackingNumberStatus{}
+	for len(trackingNumbers) > 0 {
+		batch := trackingNumbers[:min(len(trackingNumbers), fedex.BatchSize)]
+		trackingNumbers = trackingNumbers[len(batch):]
+
+		batchStatuses, err := fedex.TrackByTrackingNumber(batch)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "error calling fedex api", err)
+			continue
+		}
+		maps.Copy(trackingStatuses, batchStatuses)
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("error creating tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	for trackingNumber, shipment := range shipments {
+		if _, ok := trackingStatuses[trackingNumber]; ok {
+			continue
+		}
+
+		retryIn := fmt.Sprintf("+%d seconds", int(retryDelay(shipment.failedPolls+1).Seconds()))
+		_, err := tx.Exec("update shipments set failed_polls = failed_polls + 1, next_poll_at = datetime('now', ?) where id = ?", retryIn, shipment.id)
+		if err != nil {
+			return fmt.Errorf("error backing off tracking number in DB: %w", err)
+		}
+	}
+
+	changes := []*history.Change{}
+	for trackingNumber, trackingStatus := range trackingStatuses {
+		shipment, ok := shipments[trackingNumber]
+		if !ok {
+			continue
+		}
+
+		canonicalStatus := trackingStatus.CanonicalStatus()
+		_, err := tx.Exec(
+			`update shipments set status = ?, canonical_status = ?, eta = ?, status_last_updated = datetime('now'), failed_polls = 0, next_poll_at = null
+			where id = ?`,
+			trackingStatus.StatusDescription, canonicalStatus, trackingStatus.EstimatedDelivery, shipment.id,
+		)
+		if err != nil {
+			return fmt.Errorf("error updating tracking number status in DB: %w", err)
+		}
+
+		if shipment.status != nil && *shipment.status == trackingStatus.StatusDescription {
+			continue
+		}
+
+		change := history.Change{
+			TrackingNumber:     trackingNumber,
+			OldStatus:          shipment.status,
+			NewStatus:          trackingStatus.StatusDescription,
+			OldCanonicalStatus: shipment.canonicalStatus,
+			CanonicalStatus:    canonicalStatus,
+			ETA:                trackingStatus.EstimatedDelivery,
+			Source:             history.SourcePoll,
+		}
+		if err := history.Record(tx, shipment.id, &change); err != nil {
+			return fmt.Errorf("error recording status change: %w", err)
+		}
+
+		subscriberChanges, err := forSubscribers(tx, shipment.id, &change)
+		if err != nil {
+			return fmt.Errorf("error querying who's tracking %s: %w", trackingNumber, err)
+		}
+		for _, change := range subscriberChanges {
+			if err := webhooks.Enqueue(tx, change); err != nil {
+				return fmt.Errorf("error queueing webhooks: %w", err)
+			}
+		}
+
+		changes = append(changes, subscriberChanges...)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error commiting tx: %w", err)
+	}
+
+	go func() {
+		for _, change := range changes {
+			if err := notifier.Notify(change); err != nil {
+				fmt.Fprintln(os.Stderr, "error sending notifications", err)
+			}
+		}
+	}()
+
+	return nil
+}
+
+// retryDelay doubles the wait after every failed poll, starting at firstRetryDelay and topping out at maxRetryDelay
+func retryDelay(failedPolls int) time.Duration {
+	delay := firstRetryDelay
+	for i := 1; i < failedPolls && delay < maxRetryDelay; i++ {
+		delay *= 2
+	}
+
+	return min(delay, maxRetryDelay)
+}
+
+// forSubscribers copies the change for each member of the organizations tracking the shipment that haven't archived it,
+// with their group
+func forSubscribers(tx *sql.Tx, shipmentID int64, change *history.Change) ([]*history.Change, error) {
+	rows, err := tx.Query(
+		`select m.user_id, m.org_id, coalesce(s.group_name, '') from subscriptions s join organization_members m on m.org_id = s.org_id
+		where s.shipment_id = ? and s.archived_at is null`,
+		shipmentID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	changes := []*history.Change{}
+	for rows.Next() {
+		subscriberChange := *change
+		if err := rows.Scan(&subscriberChange.UserID, &subscriberChange.OrgID, &subscriberChange.GroupName); err != nil {
+			return nil, err
+		}
+		changes = append(changes, &subscriberChange)
+	}
+
+	return changes, rows.Err()
+}
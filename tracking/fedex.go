@@ -9,6 +9,7 @@ import (
 	"net/url"
 	"os"
 	"strings"
+	"time"
 )
 
 type trackingNumberInfo struct {
@@ -20,13 +21,58 @@ type trackBody struct {
 	TrackingInfo         []trackingNumberInfo `json:"trackingInfo"`
 }
 
+type latestStatusDetail struct {
+	Code        string `json:"code"`
+	Description string `json:"description"`
+}
+
+type dateAndTime struct {
+	Type     string `json:"type"`
+	DateTime string `json:"dateTime"`
+}
+
+type trackError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+type trackResult struct {
+	// Set instead of everything else when FedEx doesn't know about the number
+	Error                 *trackError           `json:"error"`
+	LatestStatusDetail    latestStatusDetail    `json:"latestStatusDetail"`
+	DistanceToDestination DistanceToDestination `json:"distanceToDestination"`
+	DateAndTimes          []dateAndTime         `json:"dateAndTimes"`
+}
+
+// estimatedDelivery returns nil if FedEx hasn't given an estimate
+func (r *trackResult) estimatedDelivery() *time.Time {
+	for _, dateAndTime := range r.DateAndTimes {
+		if dateAndTime.Type != "ESTIMATED_DELIVERY" {
+			continue
+		}
+
+		// fedex only sometimes includes the timezone
+		for _, layout := range []string{time.RFC3339, "2006-01-02T15:04:05"} {
+			if eta, err := time.Parse(layout, dateAndTime.DateTime); err == nil {
+				return &eta
+			}
+		}
+	}
+
+	return nil
+}
+
 type completeTrackResult struct {
-	TrackingNumber string           `json:"trackingNumber"`
-	TrackResults   []map[string]any `json:"trackResults"`
+	TrackingNumber string        `json:"trackingNumber"`
+	TrackResults   []trackResult `json:"trackResults"`
+}
+
+type trackOutput struct {
+	CompleteTrackResults []completeTrackResult `json:"completeTrackResults"`
 }
 
 type trackResp struct {
-	Output map[string][]any `json:"output"`
+	Output trackOutput `json:"output"`
 }
 
 type DistanceToDestination struct {
@@ -35,8 +81,10 @@ type DistanceToDestination struct {
 }
 
 type TrackingNumberStatus struct {
+	StatusCode            string                `json:"status_code"`
 	StatusDescription     string                `json:"status_description"`
 	DistanceToDestination DistanceToDestination `json:"distance_to_destination"`
+	EstimatedDelivery     *time.Time            `json:"estimated_delivery,omitempty"`
 }
 
 type authResp struct {
@@ -93,6 +141,10 @@ func getAuth() (string, error) {
 	return authResp.AccessToken, nil
 }
 
+// BatchSize is the most tracking numbers FedEx looks up in one request
+const BatchSize = 30
+
+// TrackByTrackingNumber leaves out numbers FedEx doesn't know about. It can look up at most BatchSize numbers at once.
 func TrackByTrackingNumber(trackingNumbers []string) (map[string]TrackingNumberStatus, error) {
 	if len(trackingNumbers) == 0 {
 		return nil, nil
@@ -132,15 +184,18 @@ func TrackByTrackingNumber(trackingNumbers []string) (map[string]TrackingNumberS
 
 	results := map[string]TrackingNumberStatus{}
 
-	reqResults := trackResp.Output["completeTrackResults"]
 	// the structure of fedex api responses makes me want to die.
-	for _, reqResult := range reqResults {
-		result := reqResult.(completeTrackResult)
+	for _, result := range trackResp.Output.CompleteTrackResults {
 		for _, trackResult := range result.TrackResults {
-			latestStatusDetail := trackResult["latestStatusDetail"].(map[string]any)
+			if trackResult.Error != nil {
+				continue
+			}
+
 			status := TrackingNumberStatus{
-				StatusDescription:     latestStatusDetail["description"].(string),
-				DistanceToDestination: trackResult["distanceToDestination"].(DistanceToDestination),
+				StatusCode:            trackResult.LatestStatusDetail.Code,
+				StatusDescription:     trackResult.LatestStatusDetail.Description,
+				DistanceToDestination: trackResult.DistanceToDestination,
+				EstimatedDelivery:     trackResult.estimatedDelivery(),
 			}
 
 			results[result.TrackingNumber] = status
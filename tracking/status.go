@@ -0,0 +1,69 @@
+package fedex
+
+import "net/url"
+
+// Canonical statuses are carrier independent, so that clients don't have to
+// know every FedEx scan code to figure out where a package is.
+const (
+	StatusUnknown        = "unknown"
+	StatusLabelCreated   = "label_created"
+	StatusInTransit      = "in_transit"
+	StatusOutForDelivery = "out_for_delivery"
+	StatusDelivered      = "delivered"
+	StatusException      = "exception"
+)
+
+var CanonicalStatuses = []string{
+	StatusUnknown,
+	StatusLabelCreated,
+	StatusInTransit,
+	StatusOutForDelivery,
+	StatusDelivered,
+	StatusException,
+}
+
+var canonicalStatuses = map[string]string{
+	"OC": StatusLabelCreated,
+	"IN": StatusLabelCreated,
+
+	"PU": StatusInTransit,
+	"IT": StatusInTransit,
+	"AR": StatusInTransit,
+	"AF": StatusInTransit,
+	"DP": StatusInTransit,
+	"FD": StatusInTransit,
+	"HL": StatusInTransit,
+	"CC": StatusInTransit,
+	"PL": StatusInTransit,
+	"PF": StatusInTransit,
+
+	"OD": StatusOutForDelivery,
+
+	"DL": StatusDelivered,
+
+	"DE": StatusException,
+	"SE": StatusException,
+	"CA": StatusException,
+	"RS": StatusException,
+	"DY": StatusException,
+	"DD": StatusException,
+	"CD": StatusException,
+}
+
+// CanonicalStatus maps a FedEx status code to one of the canonical statuses
+func CanonicalStatus(code string) string {
+	if status, ok := canonicalStatuses[code]; ok {
+		return status
+	}
+
+	return StatusUnknown
+}
+
+func (s TrackingNumberStatus) CanonicalStatus() string {
+	return CanonicalStatus(s.StatusCode)
+}
+
+// TrackingURL is the FedEx page customers can use to track the package themselves
+func TrackingURL(trackingNumber string) string {
+	return "https://www.fedex.com/fedextrack/?trknbr=" + url.QueryEscape(trackingNumber)
+}
@@ -0,0 +1,154 @@
+package trackings
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	fedex "github.com/billyb2/tracking_server/tracking"
+)
+
+const defaultAutoArchiveDays = 30
+
+var InvalidSelection error = fmt.Errorf("pick some tracking numbers or a group")
+
+// Selection picks tracking numbers by number, by group, or both
+type Selection struct {
+	TrackingNumbers []string `json:"tracking_numbers,omitempty"`
+	// Every number in this group
+	GroupID int64 `json:"group_id,omitempty"`
+}
+
+// where returns the conditions that match the selection's subscriptions
+func (s *Selection) where(q querier, orgID int64) (string, []any, error) {
+	if len(s.TrackingNumbers) == 0 && s.GroupID == 0 {
+		return "", nil, InvalidSelection
+	}
+
+	matches := []string{}
+	args := []any{orgID}
+	if len(s.TrackingNumbers) > 0 {
+		matches = append(matches, "shipment_id in (select id from shipments where tracking_number in ("+strings.TrimSuffix(strings.Repeat("?, ", len(s.TrackingNumbers)), ", ")+"))")
+		for _, trackingNumber := range s.TrackingNumbers {
+			args = append(args, trackingNumber)
+		}
+	}
+	if s.GroupID != 0 {
+		var groupName string
+		if err := q.QueryRow("select name from tracking_groups where id = ? and org_id = ?", s.GroupID, orgID).Scan(&groupName); err != nil {
+			switch {
+			case errors.Is(err, sql.ErrNoRows):
+				return "", nil, GroupNotFound
+			default:
+				return "", nil, err
+			}
+		}
+		matches = append(matches, "group_name = ?")
+		args = append(args, groupName)
+	}
+
+	return "org_id = ? and (" + strings.Join(matches, " or ") + ")", args, nil
+}
+
+func rowsAffected(result sql.Result, err error) (int64, error) {
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// Archive hides the selected numbers from lists and stops polling them once nobody else is tracking them, but keeps
+// their history. It returns how many numbers were archived, not counting ones that already were.
+func Archive(db *sql.DB, orgID int64, sel *Selection) (int64, error) {
+	where, args, err := sel.where(db, orgID)
+	if err != nil {
+		return 0, err
+	}
+
+	return rowsAffected(db.Exec("update subscriptions set archived_at = datetime('now') where archived_at is null and "+where, args...))
+}
+
+// Unarchive starts polling the selected numbers again, returning how many were unarchived
+func Unarchive(db *sql.DB, orgID int64, sel *Selection) (int64, error) {
+	where, args, err := sel.where(db, orgID)
+	if err != nil {
+		return 0, err
+	}
+
+	return rowsAffected(db.Exec("update subscriptions set archived_at = null where archived_at is not null and "+where, args...))
+}
+
+// DeleteMany stops tracking the selected numbers, returning how many were deleted. Shipments nobody is tracking anymore
+// are deleted along with their history.
+func DeleteMany(db *sql.DB, orgID int64, sel *Selection) (int64, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	where, args, err := sel.where(tx, orgID)
+	if err != nil {
+		return 0, err
+	}
+
+	deleted, err := rowsAffected(tx.Exec("delete from subscriptions where "+where, args...))
+	if err != nil {
+		return 0, err
+	}
+
+	orphaned := "select id from shipments where not exists (select 1 from subscriptions where shipment_id = shipments.id)"
+	if _, err := tx.Exec("delete from tracking_status_history where shipment_id in (" + orphaned + ")"); err != nil {
+		return 0, err
+	}
+	if _, err := tx.Exec("delete from shipments where id in (" + orphaned + ")"); err != nil {
+		return 0, err
+	}
+
+	return deleted, tx.Commit()
+}
+
+// ArchiveDelivered archives numbers that were delivered more than days days ago, returning how many were archived
+func ArchiveDelivered(db *sql.DB, days int) (int64, error) {
+	return rowsAffected(db.Exec(
+		`update subscriptions set archived_at = datetime('now')
+		where archived_at is null and shipment_id in (
+			select id from shipments where canonical_status = ? and (
+				select max(observed_at) from tracking_status_history
+				where shipment_id = shipments.id and canonical_status = ?
+			) < datetime('now', ?)
+		)`,
+		fedex.StatusDelivered, fedex.StatusDelivered, fmt.Sprintf("-%d days", days),
+	))
+}
+
+// AutoArchiveDaysFromEnv is how many days after delivery numbers are archived, from AUTO_ARCHIVE_DELIVERED_DAYS. 0 turns
+// auto archiving off.
+func AutoArchiveDaysFromEnv() (int, error) {
+	envDays := os.Getenv("AUTO_ARCHIVE_DELIVERED_DAYS")
+	if envDays == "" {
+		return defaultAutoArchiveDays, nil
+	}
+
+	days, err := strconv.Atoi(envDays)
+	if err != nil || days < 0 {
+		return 0, fmt.Errorf("invalid AUTO_ARCHIVE_DELIVERED_DAYS: %q", envDays)
+	}
+
+	return days, nil
+}
+
+// RunAutoArchiver archives delivered numbers once they've been delivered for days days, checking every hour
+func RunAutoArchiver(db *sql.DB, days int) {
+	for {
+		if _, err := ArchiveDelivered(db, days); err != nil {
+			fmt.Fprintln(os.Stderr, "error archiving delivered tracking numbers", err)
+		}
+
+		time.Sleep(time.Hour)
+	}
+}
@@ -0,0 +1,240 @@
+package trackings
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// Formats numbers can be exported in
+const (
+	ExportCSV    = "csv"
+	ExportNDJSON = "ndjson"
+	ExportXLSX   = "xlsx"
+)
+
+var ExportFormats = []string{ExportCSV, ExportNDJSON, ExportXLSX}
+
+// exportBatchSize is how many numbers are read at a time while exporting
+const exportBatchSize = 500
+
+var exportHeader = []string{"tracking_number", "carrier", "group_name", "notes", "status", "canonical_status", "eta", "delivered_at", "events", "created_at", "archived_at"}
+
+type ExportRow struct {
+	Tracking
+	DeliveredAt *time.Time `json:"delivered_at,omitempty"`
+	// How many status changes have been recorded for the number
+	Events int `json:"events"`
+}
+
+// Export calls write with each of the organization's numbers that match the options' filters, in the order they were added.
+// Archived numbers are exported unless the options say otherwise. Numbers are read a batch at a time, so that slowly
+// writing them somewhere doesn't hold up writes to the database.
+func Export(db *sql.DB, orgID int64, opts *ListOptions, write func(row *ExportRow) error) error {
+	if opts.Archived == "" {
+		opts.Archived = "any"
+	}
+	if err := opts.validate(); err != nil {
+		return err
+	}
+	where, args := opts.filters(orgID)
+
+	var lastID int64
+	for {
+		batch, err := exportBatch(db, where, args, lastID)
+		if err != nil {
+			return err
+		}
+		for i := range batch {
+			if err := write(&batch[i]); err != nil {
+				return err
+			}
+		}
+		if len(batch) < exportBatchSize {
+			return nil
+		}
+		lastID = batch[len(batch)-1].id
+	}
+}
+
+func exportBatch(db *sql.DB, where []string, args []any, afterID int64) ([]ExportRow, error) {
+	rows, err := db.Query(
+		fmt.Sprintf(
+			"select %s, unixepoch(%s), (select count(*) from tracking_status_history where shipment_id = sh.id) from %s where %s and s.id > ? order by s.id limit ?",
+			trackingColumns, deliveredAt, trackingTables, strings.Join(where, " and "),
+		),
+		append(args, afterID, exportBatchSize)...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	batch := []ExportRow{}
+	for rows.Next() {
+		row := ExportRow{}
+		var deliveredAt *int64
+		if err := scanTracking(rows, &row.Tracking, &deliveredAt, &row.Events); err != nil {
+			return nil, err
+		}
+		if deliveredAt != nil {
+			delivered := time.Unix(*deliveredAt, 0).UTC()
+			row.DeliveredAt = &delivered
+		}
+		batch = append(batch, row)
+	}
+
+	return batch, rows.Err()
+}
+
+// An ExportWriter writes exported numbers as a file
+type ExportWriter interface {
+	Write(row *ExportRow) error
+	// Close finishes the file. XLSX files aren't written at all until they're closed.
+	Close() error
+}
+
+func NewExportWriter(format string, w io.Writer) (ExportWriter, error) {
+	switch format {
+	case ExportCSV:
+		return &csvExportWriter{w: csv.NewWriter(w)}, nil
+	case ExportNDJSON:
+		return &ndjsonExportWriter{encoder: json.NewEncoder(w)}, nil
+	case ExportXLSX:
+		return newXLSXExportWriter(w)
+	default:
+		return nil, fmt.Errorf("%w: can't export as %q", InvalidListOptions, format)
+	}
+}
+
+// exportFields is the row's values in the order of exportHeader
+func exportFields(row *ExportRow) []string {
+	formatTime := func(t *time.Time) string {
+		if t == nil {
+			return ""
+		}
+		return t.Format(time.RFC3339)
+	}
+
+	return []string{
+		row.TrackingNumber, row.Carrier, row.GroupName, row.Notes, row.Status, row.CanonicalStatus, formatTime(row.ETA),
+		formatTime(row.DeliveredAt), strconv.Itoa(row.Events), formatTime(&row.CreatedAt), formatTime(row.ArchivedAt),
+	}
+}
+
+// escapeFormula stops spreadsheets from running a field as a formula when a CSV is opened, by starting fields that
+// would be one with a '
+func escapeFormula(field string) string {
+	if field != "" && strings.ContainsRune("=+-@\t\r", rune(field[0])) {
+		return "'" + field
+	}
+	return field
+}
+
+type csvExportWriter struct {
+	w           *csv.Writer
+	wroteHeader bool
+}
+
+func (e *csvExportWriter) writeHeader() error {
+	if e.wroteHeader {
+		return nil
+	}
+	e.wroteHeader = true
+	return e.w.Write(exportHeader)
+}
+
+func (e *csvExportWriter) Write(row *ExportRow) error {
+	if err := e.writeHeader(); err != nil {
+		return err
+	}
+
+	fields := exportFields(row)
+	for i, field := range fields {
+		fields[i] = escapeFormula(field)
+	}
+	return e.w.Write(fields)
+}
+
+func (e *csvExportWriter) Close() error {
+	if err := e.writeHeader(); err != nil {
+		return err
+	}
+	e.w.Flush()
+	return e.w.Error()
+}
+
+type ndjsonExportWriter struct {
+	encoder *json.Encoder
+}
+
+func (e *ndjsonExportWriter) Write(row *ExportRow) error {
+	return e.encoder.Encode(row)
+}
+
+func (e *ndjsonExportWriter) Close() error {
+	return nil
+}
+
+type xlsxExportWriter struct {
+	w      io.Writer
+	file   *excelize.File
+	stream *excelize.StreamWriter
+	// the next row to write, starting from 1
+	row int
+}
+
+func newXLSXExportWriter(w io.Writer) (*xlsxExportWriter, error) {
+	file := excelize.NewFile()
+	stream, err := file.NewStreamWriter("Sheet1")
+	if err != nil {
+		return nil, err
+	}
+
+	e := xlsxExportWriter{w: w, file: file, stream: stream, row: 1}
+	header := make([]any, len(exportHeader))
+	for i, name := range exportHeader {
+		header[i] = name
+	}
+	return &e, e.writeRow(header)
+}
+
+func (e *xlsxExportWriter) writeRow(values []any) error {
+	cell, err := excelize.CoordinatesToCellName(1, e.row)
+	if err != nil {
+		return err
+	}
+	e.row++
+	return e.stream.SetRow(cell, values)
+}
+
+func (e *xlsxExportWriter) Write(row *ExportRow) error {
+	fields := exportFields(row)
+	values := make([]any, len(fields))
+	for i, field := range fields {
+		if field == "" {
+			continue
+		}
+		// written as inline strings, which are never formulas
+		values[i] = []excelize.RichTextRun{{Text: field}}
+	}
+	// so that spreadsheets can add them up
+	values[8] = row.Events
+
+	return e.writeRow(values)
+}
+
+func (e *xlsxExportWriter) Close() error {
+	defer e.file.Close()
+	if err := e.stream.Flush(); err != nil {
+		return err
+	}
+	return e.file.Write(e.w)
+}
@@ -0,0 +1,298 @@
+package trackings
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	fedex "github.com/billyb2/tracking_server/tracking"
+)
+
+const maxGroupNameLength = 100
+
+var GroupNotFound error = fmt.Errorf("group not found")
+var GroupExists error = fmt.Errorf("there's already a group with that name")
+var InvalidGroupName error = fmt.Errorf("group names can't be empty or longer than 100 characters")
+
+type Group struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+	// How many of the group's tracking numbers that aren't archived are in each canonical status
+	Counts     map[string]int `json:"counts"`
+	Total      int            `json:"total"`
+	ArchivedAt *time.Time     `json:"archived_at,omitempty"`
+	CreatedAt  time.Time      `json:"created_at"`
+}
+
+type querier interface {
+	Exec(query string, args ...any) (sql.Result, error)
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+func validGroupName(name string) bool {
+	return strings.TrimSpace(name) != "" && len(name) <= maxGroupNameLength
+}
+
+// ensureGroup creates the group if the organization doesn't have one with that name yet, so numbers can be added to groups
+// just by naming them
+func ensureGroup(q querier, orgID int64, name string) error {
+	if name == "" {
+		return nil
+	}
+	if !validGroupName(name) {
+		return InvalidGroupName
+	}
+
+	_, err := q.Exec("insert into tracking_groups (org_id, name, created_at) values (?, ?, datetime('now')) on conflict do nothing", orgID, name)
+	return err
+}
+
+func newCounts() map[string]int {
+	counts := map[string]int{}
+	for _, status := range fedex.CanonicalStatuses {
+		counts[status] = 0
+	}
+	return counts
+}
+
+func CreateGroup(db *sql.DB, orgID int64, name string) (*Group, error) {
+	name = strings.TrimSpace(name)
+	if !validGroupName(name) {
+		return nil, InvalidGroupName
+	}
+
+	group := Group{
+		Name:   name,
+		Counts: newCounts(),
+	}
+	row := db.QueryRow("insert into tracking_groups (org_id, name, created_at) values (?, ?, datetime('now')) on conflict do nothing returning id, created_at", orgID, name)
+	if err := row.Scan(&group.ID, &group.CreatedAt); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, GroupExists
+		default:
+			return nil, err
+		}
+	}
+
+	return &group, nil
+}
+
+func GetGroup(db *sql.DB, orgID int64, groupID int64) (*Group, error) {
+	groups, err := listGroups(db, orgID, "and id = ?", groupID)
+	if err != nil {
+		return nil, err
+	}
+	if len(groups) == 0 {
+		return nil, GroupNotFound
+	}
+
+	return &groups[0], nil
+}
+
+// ListGroups leaves out archived groups unless includeArchived is true
+func ListGroups(db *sql.DB, orgID int64, includeArchived bool) ([]Group, error) {
+	if includeArchived {
+		return listGroups(db, orgID, "")
+	}
+	return listGroups(db, orgID, "and archived_at is null")
+}
+
+func listGroups(db *sql.DB, orgID int64, where string, args ...any) ([]Group, error) {
+	rows, err := db.Query(
+		"select id, name, archived_at, created_at from tracking_groups where org_id = ? "+where+" order by name",
+		append([]any{orgID}, args...)...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	groups := []Group{}
+	byName := map[string]*Group{}
+	for rows.Next() {
+		group := Group{
+			Counts: newCounts(),
+		}
+		if err := rows.Scan(&group.ID, &group.Name, &group.ArchivedAt, &group.CreatedAt); err != nil {
+			return nil, err
+		}
+		groups = append(groups, group)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	for i := range groups {
+		byName[groups[i].Name] = &groups[i]
+	}
+
+	rows, err = db.Query(
+		"select s.group_name, coalesce(sh.canonical_status, ?), count(*) from "+trackingTables+" where s.org_id = ? and s.group_name is not null and s.archived_at is null group by 1, 2",
+		fedex.StatusUnknown, orgID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var groupName, status string
+		var count int
+		if err := rows.Scan(&groupName, &status, &count); err != nil {
+			return nil, err
+		}
+		if group, ok := byName[groupName]; ok {
+			group.Counts[status] += count
+			group.Total += count
+		}
+	}
+
+	return groups, rows.Err()
+}
+
+// RenameGroup also renames the group everywhere else it's referred to by name, like notification rules and webhook filters
+func RenameGroup(db *sql.DB, orgID int64, groupID int64, name string) error {
+	name = strings.TrimSpace(name)
+	if !validGroupName(name) {
+		return InvalidGroupName
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var oldName string
+	if err := tx.QueryRow("select name from tracking_groups where id = ? and org_id = ?", groupID, orgID).Scan(&oldName); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return GroupNotFound
+		default:
+			return err
+		}
+	}
+	if oldName == name {
+		return nil
+	}
+
+	var exists bool
+	if err := tx.QueryRow("select exists(select 1 from tracking_groups where org_id = ? and name = ?)", orgID, name).Scan(&exists); err != nil {
+		return err
+	}
+	if exists {
+		return GroupExists
+	}
+
+	renames := []string{
+		"update tracking_groups set name = ?2 where org_id = ?3 and name = ?1",
+		"update subscriptions set group_name = ?2 where org_id = ?3 and group_name = ?1",
+		// members' own settings in the organization refer to groups by name too
+		"update group_email_recipients set group_name = ?2 where org_id = ?3 and group_name = ?1",
+		"update chat_webhooks set group_name = ?2 where org_id = ?3 and group_name = ?1",
+		// these have json arrays of group names
+		"update notification_rules set group_names = (select json_group_array(iif(value = ?1, ?2, value)) from json_each(group_names)) where org_id = ?3 and group_names is not null",
+		"update webhook_endpoints set group_names = (select json_group_array(iif(value = ?1, ?2, value)) from json_each(group_names)) where org_id = ?3 and group_names is not null",
+	}
+	for _, rename := range renames {
+		if _, err := tx.Exec(rename, oldName, name, orgID); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// SetGroupArchived also archives every number in the group when archiving it. Unarchiving a group leaves its numbers
+// archived, since some of them might have been archived on their own.
+func SetGroupArchived(db *sql.DB, orgID int64, groupID int64, archived bool) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := "update tracking_groups set archived_at = null where id = ? and org_id = ? returning name"
+	if archived {
+		query = "update tracking_groups set archived_at = coalesce(archived_at, datetime('now')) where id = ? and org_id = ? returning name"
+	}
+
+	var name string
+	if err := tx.QueryRow(query, groupID, orgID).Scan(&name); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return GroupNotFound
+		default:
+			return err
+		}
+	}
+
+	if archived {
+		if _, err := tx.Exec("update subscriptions set archived_at = datetime('now') where org_id = ? and group_name = ? and archived_at is null", orgID, name); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// MoveToGroup moves the tracking numbers, and every number in fromGroupID if it isn't 0, into the group, returning how
+// many were moved. Moving everything from one group into another merges them.
+func MoveToGroup(db *sql.DB, orgID int64, groupID int64, trackingNumbers []string, fromGroupID int64) (int64, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	groupName := func(id int64) (string, error) {
+		var name string
+		err := tx.QueryRow("select name from tracking_groups where id = ? and org_id = ?", id, orgID).Scan(&name)
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", GroupNotFound
+		}
+		return name, err
+	}
+
+	name, err := groupName(groupID)
+	if err != nil {
+		return 0, err
+	}
+
+	var moved int64
+	for _, trackingNumber := range trackingNumbers {
+		result, err := tx.Exec("update subscriptions set group_name = ? where org_id = ? and shipment_id in (select id from shipments where tracking_number = ?)", name, orgID, trackingNumber)
+		if err != nil {
+			return 0, err
+		}
+		updated, err := result.RowsAffected()
+		if err != nil {
+			return 0, err
+		}
+		if updated == 0 {
+			return 0, fmt.Errorf("%w: %s", NotFound, trackingNumber)
+		}
+		moved += updated
+	}
+
+	if fromGroupID != 0 {
+		fromName, err := groupName(fromGroupID)
+		if err != nil {
+			return 0, err
+		}
+
+		result, err := tx.Exec("update subscriptions set group_name = ? where org_id = ? and group_name = ?", name, orgID, fromName)
+		if err != nil {
+			return 0, err
+		}
+		updated, err := result.RowsAffected()
+		if err != nil {
+			return 0, err
+		}
+		moved += updated
+	}
+
+	return moved, tx.Commit()
+}
@@ -0,0 +1,173 @@
+package trackings
+
+import (
+	"bufio"
+	"bytes"
+	"database/sql"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+const MaxImportRows = 10000
+
+// How big an XLSX file's contents can get unzipped, and how much of a sheet is unzipped in memory rather than to a
+// temporary file. MaxImportRows rows of a dozen columns come to a few MB of XML, so these leave plenty of room while
+// refusing files that are mostly compressed padding.
+const (
+	maxXLSXUnzipSize    = 64 << 20
+	maxXLSXUnzipXMLSize = 16 << 20
+)
+
+var InvalidImport error = fmt.Errorf("invalid import")
+
+// The header names each column can go by, after lowercasing them and dropping spaces, underscores and dashes
+var importColumns = map[string][]string{
+	"tracking_number": {"trackingnumber", "tracking", "trackingno", "tracking#", "number"},
+	"carrier":         {"carrier"},
+	"group_name":      {"group", "groupname"},
+	"notes":           {"notes", "note", "reference", "ref"},
+}
+
+type ImportRow struct {
+	// Which row of the file it's from, counting the header as row 1
+	Row int
+	NewTracking
+}
+
+type ImportResult struct {
+	Row int `json:"row"`
+	StartResult
+}
+
+type ImportReport struct {
+	// How many rows had each outcome
+	Counts  map[string]int `json:"counts"`
+	Results []ImportResult `json:"results"`
+}
+
+// ParseImport reads the rows of a CSV or XLSX file, picking the format by the file's extension. The first row has to be
+// a header naming the columns, and only the tracking number column is required.
+func ParseImport(filename string, r io.Reader) ([]ImportRow, error) {
+	var records [][]string
+	var err error
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".csv":
+		records, err = readCSV(r)
+	case ".xlsx":
+		records, err = readXLSX(r)
+	default:
+		return nil, fmt.Errorf("%w: only .csv and .xlsx files can be imported", InvalidImport)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", InvalidImport, err)
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("%w: the file is empty", InvalidImport)
+	}
+
+	columns := map[string]int{}
+	for i, header := range records[0] {
+		name := strings.NewReplacer(" ", "", "_", "", "-", "").Replace(strings.ToLower(strings.TrimSpace(header)))
+		for column, names := range importColumns {
+			if _, ok := columns[column]; !ok && slices.Contains(names, name) {
+				columns[column] = i
+			}
+		}
+	}
+	if _, ok := columns["tracking_number"]; !ok {
+		return nil, fmt.Errorf("%w: the first row needs a tracking number column", InvalidImport)
+	}
+
+	rows := []ImportRow{}
+	for i, record := range records[1:] {
+		field := func(column string) string {
+			index, ok := columns[column]
+			if !ok || index >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[index])
+		}
+
+		row := ImportRow{
+			Row: i + 2,
+			NewTracking: NewTracking{
+				TrackingNumber: field("tracking_number"),
+				Carrier:        field("carrier"),
+				GroupName:      field("group_name"),
+				Notes:          field("notes"),
+			},
+		}
+		if row == (ImportRow{Row: row.Row}) {
+			continue
+		}
+		if len(rows) == MaxImportRows {
+			return nil, fmt.Errorf("%w: files can have at most %d rows", InvalidImport, MaxImportRows)
+		}
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
+
+func readCSV(r io.Reader) ([][]string, error) {
+	br := bufio.NewReader(r)
+	// Excel starts the CSVs it saves with a byte order mark
+	if bom, err := br.Peek(3); err == nil && bytes.Equal(bom, []byte("\xef\xbb\xbf")) {
+		br.Discard(3)
+	}
+
+	reader := csv.NewReader(br)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	return reader.ReadAll()
+}
+
+// readXLSX reads the first sheet. Cells are read raw, so long tracking numbers stored as numbers don't come out in
+// scientific notation.
+func readXLSX(r io.Reader) ([][]string, error) {
+	file, err := excelize.OpenReader(r, excelize.Options{
+		UnzipSizeLimit:    maxXLSXUnzipSize,
+		UnzipXMLSizeLimit: maxXLSXUnzipXMLSize,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return file.GetRows(file.GetSheetName(0), excelize.Options{RawCellValue: true})
+}
+
+// Import starts tracking the rows like StartEach, reporting what happened to each one. Since a file can take hundreds
+// of requests to the carrier, rows it can't be asked about are reported as carrier errors rather than failing the
+// whole import.
+func Import(db *sql.DB, orgID int64, rows []ImportRow) (*ImportReport, error) {
+	newTrackings := make([]NewTracking, len(rows))
+	for i, row := range rows {
+		newTrackings[i] = row.NewTracking
+	}
+
+	results, err := startEach(db, orgID, nil, newTrackings, true)
+	if err != nil {
+		return nil, err
+	}
+
+	report := ImportReport{
+		Counts:  map[string]int{},
+		Results: make([]ImportResult, len(rows)),
+	}
+	for i, result := range results {
+		report.Results[i] = ImportResult{
+			Row:         rows[i].Row,
+			StartResult: result,
+		}
+		report.Counts[result.Outcome]++
+	}
+
+	return &report, nil
+}
@@ -0,0 +1,255 @@
+package trackings
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	fedex "github.com/billyb2/tracking_server/tracking"
+)
+
+const DefaultListLimit = 50
+const MaxListLimit = 500
+
+var InvalidListOptions error = fmt.Errorf("invalid list options")
+
+// What lists can be sorted by, and the expression each one sorts on. Times are sorted as unix timestamps, since they're
+// stored with different offsets.
+var sortExprs = map[string]string{
+	"tracking_number":     "sh.tracking_number",
+	"created_at":          "unixepoch(s.created_at)",
+	"eta":                 "unixepoch(sh.eta)",
+	"status_last_updated": "unixepoch(sh.status_last_updated)",
+}
+
+type ListOptions struct {
+	GroupName string
+	// Canonical statuses, empty means every status
+	Statuses []string
+	Carrier  string
+	// Only numbers whose status was checked at or after this
+	UpdatedSince *time.Time
+	// Only numbers with an ETA in [ETAFrom, ETABefore)
+	ETAFrom   *time.Time
+	ETABefore *time.Time
+	// Only numbers delivered in [DeliveredFrom, DeliveredBefore)
+	DeliveredFrom   *time.Time
+	DeliveredBefore *time.Time
+	// Matched against the tracking number and notes
+	Search string
+	// false leaves out archived numbers, true only has archived numbers, and any has both. Defaults to false
+	Archived string
+	// One of the keys of sortExprs, with a - in front to sort descending. Defaults to tracking_number
+	Sort  string
+	Limit int
+	// From the previous page, or empty for the first page
+	Cursor string
+}
+
+// listCursor is where the last page ended. Value is nil when the last number didn't have a value for the sort, like
+// numbers without an ETA, which always come last.
+type listCursor struct {
+	Sort  string  `json:"sort"`
+	Value *string `json:"value"`
+	ID    int64   `json:"id"`
+}
+
+func (o *ListOptions) validate() error {
+	for _, status := range o.Statuses {
+		if !slices.Contains(fedex.CanonicalStatuses, status) {
+			return fmt.Errorf("%w: unknown status %q", InvalidListOptions, status)
+		}
+	}
+	if o.Carrier != "" && !slices.Contains(Carriers, o.Carrier) {
+		return fmt.Errorf("%w: unknown carrier %q", InvalidListOptions, o.Carrier)
+	}
+
+	switch o.Archived {
+	case "":
+		o.Archived = "false"
+	case "false", "true", "any":
+	default:
+		return fmt.Errorf("%w: archived must be false, true or any", InvalidListOptions)
+	}
+
+	if o.Sort == "" {
+		o.Sort = "tracking_number"
+	}
+	if _, ok := sortExprs[strings.TrimPrefix(o.Sort, "-")]; !ok {
+		return fmt.Errorf("%w: can't sort by %q", InvalidListOptions, o.Sort)
+	}
+
+	if o.Limit <= 0 {
+		o.Limit = DefaultListLimit
+	}
+	o.Limit = min(o.Limit, MaxListLimit)
+
+	return nil
+}
+
+// List returns a page of the organization's tracking numbers, and the cursor for the next page if there is one
+func List(db *sql.DB, orgID int64, opts *ListOptions) ([]Tracking, string, error) {
+	if err := opts.validate(); err != nil {
+		return nil, "", err
+	}
+
+	where, args := opts.filters(orgID)
+
+	sortKey := strings.TrimPrefix(opts.Sort, "-")
+	expr := sortExprs[sortKey]
+	direction, comparison := "asc", ">"
+	if strings.HasPrefix(opts.Sort, "-") {
+		direction, comparison = "desc", "<"
+	}
+
+	if opts.Cursor != "" {
+		cursor, err := decodeCursor(opts.Cursor)
+		if err != nil || cursor.Sort != opts.Sort {
+			return nil, "", fmt.Errorf("%w: invalid cursor, or it's from a list with a different sort", InvalidListOptions)
+		}
+
+		// numbers without a value for the sort come last either way
+		if cursor.Value == nil {
+			where = append(where, fmt.Sprintf("(%s is null and s.id %s ?)", expr, comparison))
+			args = append(args, cursor.ID)
+		} else {
+			var value any = *cursor.Value
+			if sortKey != "tracking_number" {
+				if value, err = strconv.ParseInt(*cursor.Value, 10, 64); err != nil {
+					return nil, "", fmt.Errorf("%w: invalid cursor", InvalidListOptions)
+				}
+			}
+			where = append(where, fmt.Sprintf("(%[1]s %[2]s ? or (%[1]s = ? and s.id %[2]s ?) or %[1]s is null)", expr, comparison))
+			args = append(args, value, value, cursor.ID)
+		}
+	}
+
+	// one extra row tells whether there's another page
+	args = append(args, opts.Limit+1)
+	list, err := query(
+		db,
+		fmt.Sprintf("select %s from %s where %s order by %s %s nulls last, s.id %s limit ?", trackingColumns, trackingTables, strings.Join(where, " and "), expr, direction, direction),
+		args...,
+	)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(list) <= opts.Limit {
+		return list, "", nil
+	}
+
+	list = list[:opts.Limit]
+	last := list[len(list)-1]
+	cursor := listCursor{
+		Sort: opts.Sort,
+		ID:   last.id,
+	}
+	switch sortKey {
+	case "tracking_number":
+		cursor.Value = &last.TrackingNumber
+	case "created_at":
+		cursor.Value = unixString(&last.CreatedAt)
+	case "eta":
+		cursor.Value = unixString(last.ETA)
+	case "status_last_updated":
+		cursor.Value = unixString(last.StatusLastUpdated)
+	}
+
+	next, err := encodeCursor(&cursor)
+	return list, next, err
+}
+
+// deliveredAt is when a delivered shipment was delivered, or null if it hasn't been
+const deliveredAt = `iif(sh.canonical_status = 'delivered', (
+	select max(observed_at) from tracking_status_history where shipment_id = sh.id and canonical_status = 'delivered'
+), null)`
+
+// filters returns the where conditions for everything but the cursor
+func (o *ListOptions) filters(orgID int64) ([]string, []any) {
+	where := []string{"s.org_id = ?"}
+	args := []any{orgID}
+
+	switch o.Archived {
+	case "false":
+		where = append(where, "s.archived_at is null")
+	case "true":
+		where = append(where, "s.archived_at is not null")
+	}
+	if o.GroupName != "" {
+		where = append(where, "s.group_name = ?")
+		args = append(args, o.GroupName)
+	}
+	if len(o.Statuses) > 0 {
+		where = append(where, "sh.canonical_status in ("+strings.TrimSuffix(strings.Repeat("?, ", len(o.Statuses)), ", ")+")")
+		for _, status := range o.Statuses {
+			args = append(args, status)
+		}
+	}
+	if o.Carrier != "" {
+		where = append(where, "sh.carrier = ?")
+		args = append(args, o.Carrier)
+	}
+	if o.UpdatedSince != nil {
+		where = append(where, "unixepoch(sh.status_last_updated) >= ?")
+		args = append(args, o.UpdatedSince.Unix())
+	}
+	if o.ETAFrom != nil {
+		where = append(where, "unixepoch(sh.eta) >= ?")
+		args = append(args, o.ETAFrom.Unix())
+	}
+	if o.ETABefore != nil {
+		where = append(where, "unixepoch(sh.eta) < ?")
+		args = append(args, o.ETABefore.Unix())
+	}
+	if o.Search != "" {
+		pattern := "%" + escapeLike(o.Search) + "%"
+		where = append(where, `(sh.tracking_number like ? escape '\' or s.notes like ? escape '\')`)
+		args = append(args, pattern, pattern)
+	}
+	if o.DeliveredFrom != nil {
+		where = append(where, "unixepoch("+deliveredAt+") >= ?")
+		args = append(args, o.DeliveredFrom.Unix())
+	}
+	if o.DeliveredBefore != nil {
+		where = append(where, "unixepoch("+deliveredAt+") < ?")
+		args = append(args, o.DeliveredBefore.Unix())
+	}
+
+	return where, args
+}
+
+func unixString(t *time.Time) *string {
+	if t == nil {
+		return nil
+	}
+	s := strconv.FormatInt(t.Unix(), 10)
+	return &s
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+func encodeCursor(cursor *listCursor) (string, error) {
+	cursorJSON, err := json.Marshal(cursor)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(cursorJSON), nil
+}
+
+func decodeCursor(s string) (*listCursor, error) {
+	cursorJSON, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+
+	cursor := listCursor{}
+	return &cursor, json.Unmarshal(cursorJSON, &cursor)
+}
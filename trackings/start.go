@@ -0,0 +1,147 @@
+package trackings
+
+import (
+	"database/sql"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// What StartEach did with each number
+const (
+	OutcomeCreated         = "created"
+	OutcomeAlreadyTracked  = "already_tracked"
+	OutcomeInvalid         = "invalid"
+	OutcomeCarrierNotFound = "carrier_not_found"
+	// The number is already tracked in a group the caller can't use
+	OutcomeForbidden = "forbidden"
+	// The number came up earlier in the same request or file
+	OutcomeDuplicate = "duplicate"
+	// The carrier couldn't be asked about the number, so it can be imported again later
+	OutcomeCarrierError = "carrier_error"
+)
+
+// NewTracking is a number to start tracking with StartEach
+type NewTracking struct {
+	TrackingNumber string
+	// Empty means FedEx
+	Carrier   string
+	GroupName string
+	Notes     string
+}
+
+type StartResult struct {
+	TrackingNumber string `json:"tracking_number"`
+	Outcome        string `json:"outcome"`
+	// Why the number is invalid, forbidden or couldn't be looked up
+	Error string `json:"error,omitempty"`
+}
+
+func (t *NewTracking) validate() error {
+	if !ValidTrackingNumber(t.TrackingNumber) {
+		return InvalidTrackingNumber
+	}
+	if t.Carrier != "" && !slices.Contains(Carriers, strings.ToLower(t.Carrier)) {
+		return fmt.Errorf("unknown carrier %q", t.Carrier)
+	}
+	if t.GroupName != "" && !validGroupName(t.GroupName) {
+		return InvalidGroupName
+	}
+
+	return nil
+}
+
+// StartEach starts tracking each valid number the same way Start does, reporting what happened to each one instead of
+// failing if any of them can't be added. Numbers the organization already tracks are moved into the group and get the notes
+// they're given, so adding the same numbers again is harmless. Nothing is changed if the carrier can't be reached.
+// If onlyGroup isn't nil, like for API keys restricted to a group, numbers already tracked in any other group are left
+// where they are.
+func StartEach(db *sql.DB, orgID int64, onlyGroup *string, newTrackings []NewTracking) ([]StartResult, error) {
+	return startEach(db, orgID, onlyGroup, newTrackings, false)
+}
+
+// startEach is StartEach, except that if skipCarrierErrors is set, numbers the carrier couldn't be asked about get
+// OutcomeCarrierError while the rest are still added
+func startEach(db *sql.DB, orgID int64, onlyGroup *string, newTrackings []NewTracking, skipCarrierErrors bool) ([]StartResult, error) {
+	results := make([]StartResult, len(newTrackings))
+
+	toStart := []NewTracking{}
+	// which result each of toStart is for
+	resultIndexes := []int{}
+	toUpdate := []NewTracking{}
+	seen := map[string]bool{}
+	for i, newTracking := range newTrackings {
+		result := &results[i]
+		result.TrackingNumber = newTracking.TrackingNumber
+
+		if err := newTracking.validate(); err != nil {
+			result.Outcome = OutcomeInvalid
+			result.Error = err.Error()
+			continue
+		}
+		if seen[newTracking.TrackingNumber] {
+			result.Outcome = OutcomeDuplicate
+			continue
+		}
+		seen[newTracking.TrackingNumber] = true
+
+		tracked, err := isTracked(db, orgID, newTracking.TrackingNumber)
+		if err != nil {
+			return nil, err
+		}
+		if tracked && onlyGroup != nil {
+			tracking, err := Get(db, orgID, newTracking.TrackingNumber)
+			if err != nil {
+				return nil, err
+			}
+			if tracking.GroupName != *onlyGroup {
+				result.Outcome = OutcomeForbidden
+				result.Error = fmt.Sprintf("it's tracked in a group other than %q", *onlyGroup)
+				continue
+			}
+		}
+		if tracked {
+			result.Outcome = OutcomeAlreadyTracked
+			toUpdate = append(toUpdate, newTracking)
+			continue
+		}
+
+		toStart = append(toStart, newTracking)
+		resultIndexes = append(resultIndexes, i)
+	}
+
+	started, carrierErrs, err := start(db, orgID, toStart, skipCarrierErrors)
+	if err != nil {
+		return nil, err
+	}
+	for i, tracking := range started {
+		result := &results[resultIndexes[i]]
+		switch {
+		case carrierErrs[toStart[i].TrackingNumber] != nil:
+			result.Outcome = OutcomeCarrierError
+			result.Error = carrierErrs[toStart[i].TrackingNumber].Error()
+		case tracking == nil:
+			result.Outcome = OutcomeCarrierNotFound
+		default:
+			result.Outcome = OutcomeCreated
+		}
+	}
+
+	for _, newTracking := range toUpdate {
+		patch := Patch{}
+		if newTracking.GroupName != "" {
+			patch.GroupName = &newTracking.GroupName
+		}
+		if newTracking.Notes != "" {
+			patch.Notes = &newTracking.Notes
+		}
+		if patch == (Patch{}) {
+			continue
+		}
+		if _, err := Update(db, orgID, newTracking.TrackingNumber, &patch); err != nil {
+			return nil, err
+		}
+	}
+
+	return results, nil
+}
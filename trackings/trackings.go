@@ -0,0 +1,357 @@
+package trackings
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"maps"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/billyb2/tracking_server/history"
+	fedex "github.com/billyb2/tracking_server/tracking"
+)
+
+var NotFound error = fmt.Errorf("tracking number not found")
+var AlreadyTracked error = fmt.Errorf("tracking number is already being tracked")
+var CarrierNotFound error = fmt.Errorf("the carrier doesn't know about this tracking number")
+var InvalidTrackingNumber error = fmt.Errorf("tracking numbers can only have letters and numbers, and be at most 40 characters long")
+
+const CarrierFedEx = "fedex"
+
+var Carriers = []string{CarrierFedEx}
+
+var trackingNumberRegex = regexp.MustCompile(`^[A-Za-z0-9]{1,40}$`)
+
+// CarrierError is wrapped around errors from the carrier's API, so they can be told apart from the server's own
+type CarrierError struct {
+	Err error
+}
+
+func (e *CarrierError) Error() string {
+	return fmt.Sprintf("error talking to the carrier: %s", e.Err)
+}
+
+func (e *CarrierError) Unwrap() error {
+	return e.Err
+}
+
+// Tracking is one organization's subscription to a shipment. The status is the shipment's, which is shared with
+// everyone tracking the same number, while the group, notes and archiving are the organization's own.
+type Tracking struct {
+	// the subscription's id
+	id              int64
+	TrackingNumber  string     `json:"tracking_number"`
+	Carrier         string     `json:"carrier"`
+	GroupName       string     `json:"group_name,omitempty"`
+	Notes           string     `json:"notes,omitempty"`
+	Status          string     `json:"status"`
+	CanonicalStatus string     `json:"canonical_status"`
+	ETA             *time.Time `json:"eta,omitempty"`
+	// When the status was last checked with the carrier
+	StatusLastUpdated *time.Time `json:"status_last_updated,omitempty"`
+	CreatedAt         time.Time  `json:"created_at"`
+	// Archived numbers aren't polled, and are left out of lists by default
+	ArchivedAt *time.Time `json:"archived_at,omitempty"`
+}
+
+const trackingColumns = "s.id, sh.tracking_number, sh.carrier, coalesce(s.group_name, ''), coalesce(s.notes, ''), coalesce(sh.status, ''), coalesce(sh.canonical_status, 'unknown'), sh.eta, sh.status_last_updated, s.created_at, s.archived_at"
+
+// trackingTables is what trackingColumns are selected from
+const trackingTables = "subscriptions s join shipments sh on sh.id = s.shipment_id"
+
+// scanTracking scans trackingColumns, followed by any extra columns into extra
+func scanTracking(row interface{ Scan(...any) error }, tracking *Tracking, extra ...any) error {
+	return row.Scan(append([]any{&tracking.id, &tracking.TrackingNumber, &tracking.Carrier, &tracking.GroupName, &tracking.Notes, &tracking.Status, &tracking.CanonicalStatus, &tracking.ETA, &tracking.StatusLastUpdated, &tracking.CreatedAt, &tracking.ArchivedAt}, extra...)...)
+}
+
+func ValidTrackingNumber(trackingNumber string) bool {
+	return trackingNumberRegex.MatchString(trackingNumber)
+}
+
+func nullable(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+// Start starts tracking the numbers in the group, returning them. Numbers nobody is tracking yet are looked up with
+// the carrier first, and the ones it doesn't know about are left out. Nothing is added if any of the numbers are
+// invalid or already tracked by the organization.
+func Start(db *sql.DB, orgID int64, groupName, notes string, trackingNumbers []string) ([]Tracking, error) {
+	for _, trackingNumber := range trackingNumbers {
+		if !ValidTrackingNumber(trackingNumber) {
+			return nil, fmt.Errorf("%w: %q", InvalidTrackingNumber, trackingNumber)
+		}
+	}
+	if groupName != "" && !validGroupName(groupName) {
+		return nil, InvalidGroupName
+	}
+
+	newTrackings := []NewTracking{}
+	for _, trackingNumber := range trackingNumbers {
+		tracked, err := isTracked(db, orgID, trackingNumber)
+		if err != nil {
+			return nil, err
+		}
+		if tracked {
+			return nil, fmt.Errorf("%w: %s", AlreadyTracked, trackingNumber)
+		}
+
+		newTrackings = append(newTrackings, NewTracking{
+			TrackingNumber: trackingNumber,
+			GroupName:      groupName,
+			Notes:          notes,
+		})
+	}
+
+	started, _, err := start(db, orgID, newTrackings, false)
+	if err != nil {
+		return nil, err
+	}
+
+	trackings := []Tracking{}
+	for _, tracking := range started {
+		if tracking != nil {
+			trackings = append(trackings, *tracking)
+		}
+	}
+
+	return trackings, nil
+}
+
+func isTracked(db *sql.DB, orgID int64, trackingNumber string) (bool, error) {
+	var tracked bool
+	err := db.QueryRow(
+		"select exists(select 1 from "+trackingTables+" where s.org_id = ? and sh.tracking_number = ?)",
+		orgID, trackingNumber,
+	).Scan(&tracked)
+	return tracked, err
+}
+
+// start adds the numbers, which have already been checked to be valid and not tracked by the organization, returning what each
+// one became, or nil for the ones the carrier doesn't know about. A failed request to the carrier fails everything,
+// unless skipCarrierErrors is set, in which case the numbers it was for are left out and returned with the error.
+func start(db *sql.DB, orgID int64, newTrackings []NewTracking, skipCarrierErrors bool) ([]*Tracking, map[string]error, error) {
+	lookUp := []string{}
+	for _, newTracking := range newTrackings {
+		var exists bool
+		if err := db.QueryRow("select exists(select 1 from shipments where carrier = ? and tracking_number = ?)", CarrierFedEx, newTracking.TrackingNumber).Scan(&exists); err != nil {
+			return nil, nil, err
+		}
+		if !exists {
+			lookUp = append(lookUp, newTracking.TrackingNumber)
+		}
+	}
+
+	// the carrier is only asked about shipments nobody is tracking yet, and before starting the transaction, so that a
+	// slow response doesn't hold up other writes
+	statuses := map[string]fedex.TrackingNumberStatus{}
+	carrierErrs := map[string]error{}
+	for len(lookUp) > 0 {
+		batch := lookUp[:min(len(lookUp), fedex.BatchSize)]
+		lookUp = lookUp[len(batch):]
+
+		batchStatuses, err := fedex.TrackByTrackingNumber(batch)
+		if err != nil {
+			if !skipCarrierErrors {
+				return nil, nil, &CarrierError{Err: err}
+			}
+			for _, trackingNumber := range batch {
+				carrierErrs[trackingNumber] = &CarrierError{Err: err}
+			}
+			continue
+		}
+		maps.Copy(statuses, batchStatuses)
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tx.Rollback()
+
+	started := make([]*Tracking, len(newTrackings))
+	for i, newTracking := range newTrackings {
+		if carrierErrs[newTracking.TrackingNumber] != nil {
+			continue
+		}
+
+		shipmentID, err := ensureShipment(tx, newTracking.TrackingNumber, statuses)
+		if err != nil {
+			return nil, nil, err
+		}
+		if shipmentID == 0 {
+			continue
+		}
+
+		if err := ensureGroup(tx, orgID, newTracking.GroupName); err != nil {
+			return nil, nil, err
+		}
+
+		var subscriptionID int64
+		row := tx.QueryRow(
+			`insert into subscriptions (org_id, shipment_id, group_name, notes, created_at) values (?, ?, ?, ?, datetime('now'))
+			on conflict do nothing returning id`,
+			orgID, shipmentID, nullable(newTracking.GroupName), nullable(newTracking.Notes),
+		)
+		if err := row.Scan(&subscriptionID); err != nil {
+			switch {
+			case errors.Is(err, sql.ErrNoRows):
+				return nil, nil, fmt.Errorf("%w: %s", AlreadyTracked, newTracking.TrackingNumber)
+			default:
+				return nil, nil, err
+			}
+		}
+
+		tracking := Tracking{}
+		if err := scanTracking(tx.QueryRow("select "+trackingColumns+" from "+trackingTables+" where s.id = ?", subscriptionID), &tracking); err != nil {
+			return nil, nil, err
+		}
+		started[i] = &tracking
+	}
+
+	return started, carrierErrs, tx.Commit()
+}
+
+// ensureShipment returns the id of the carrier's shipment with the tracking number, adding it with its status from
+// statuses if nobody's tracking it yet. It returns 0 if the shipment is new and the carrier doesn't know about it.
+func ensureShipment(tx *sql.Tx, trackingNumber string, statuses map[string]fedex.TrackingNumberStatus) (int64, error) {
+	var shipmentID int64
+	err := tx.QueryRow("select id from shipments where carrier = ? and tracking_number = ?", CarrierFedEx, trackingNumber).Scan(&shipmentID)
+	if err == nil || !errors.Is(err, sql.ErrNoRows) {
+		return shipmentID, err
+	}
+
+	trackingStatus, ok := statuses[trackingNumber]
+	if !ok {
+		return 0, nil
+	}
+
+	change := history.Change{
+		NewStatus:       trackingStatus.StatusDescription,
+		CanonicalStatus: trackingStatus.CanonicalStatus(),
+		Source:          history.SourceManual,
+	}
+	row := tx.QueryRow(
+		`insert into shipments (carrier, tracking_number, status, canonical_status, eta, status_last_updated, created_at)
+		values (?, ?, ?, ?, ?, datetime('now'), datetime('now')) returning id`,
+		CarrierFedEx, trackingNumber, change.NewStatus, change.CanonicalStatus, trackingStatus.EstimatedDelivery,
+	)
+	if err := row.Scan(&shipmentID); err != nil {
+		return 0, err
+	}
+
+	return shipmentID, history.Record(tx, shipmentID, &change)
+}
+
+func Get(db *sql.DB, orgID int64, trackingNumber string) (*Tracking, error) {
+	tracking := Tracking{}
+	row := db.QueryRow("select "+trackingColumns+" from "+trackingTables+" where s.org_id = ? and sh.tracking_number = ?", orgID, trackingNumber)
+	if err := scanTracking(row, &tracking); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, NotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &tracking, nil
+}
+
+// GetMany leaves out numbers the organization isn't tracking
+func GetMany(db *sql.DB, orgID int64, trackingNumbers []string) ([]Tracking, error) {
+	if len(trackingNumbers) == 0 {
+		return []Tracking{}, nil
+	}
+
+	args := []any{orgID}
+	for _, trackingNumber := range trackingNumbers {
+		args = append(args, trackingNumber)
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(trackingNumbers)), ", ")
+
+	return query(db, "select "+trackingColumns+" from "+trackingTables+" where s.org_id = ? and sh.tracking_number in ("+placeholders+") order by sh.tracking_number", args...)
+}
+
+func query(db *sql.DB, query string, args ...any) ([]Tracking, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	trackings := []Tracking{}
+	for rows.Next() {
+		tracking := Tracking{}
+		if err := scanTracking(rows, &tracking); err != nil {
+			return nil, err
+		}
+		trackings = append(trackings, tracking)
+	}
+
+	return trackings, rows.Err()
+}
+
+// Patch is the fields to change in Update. Nil fields are left alone, and empty strings clear them.
+type Patch struct {
+	GroupName *string `json:"group_name,omitempty"`
+	Notes     *string `json:"notes,omitempty"`
+	Archived  *bool   `json:"archived,omitempty"`
+}
+
+func Update(db *sql.DB, orgID int64, trackingNumber string, patch *Patch) (*Tracking, error) {
+	sets := []string{}
+	args := []any{}
+	if patch.GroupName != nil {
+		if err := ensureGroup(db, orgID, *patch.GroupName); err != nil {
+			return nil, err
+		}
+		sets = append(sets, "group_name = ?")
+		args = append(args, nullable(*patch.GroupName))
+	}
+	if patch.Notes != nil {
+		sets = append(sets, "notes = ?")
+		args = append(args, nullable(*patch.Notes))
+	}
+	if patch.Archived != nil {
+		if *patch.Archived {
+			sets = append(sets, "archived_at = coalesce(archived_at, datetime('now'))")
+		} else {
+			sets = append(sets, "archived_at = null")
+		}
+	}
+	if len(sets) == 0 {
+		return Get(db, orgID, trackingNumber)
+	}
+
+	result, err := db.Exec(
+		"update subscriptions set "+strings.Join(sets, ", ")+" where org_id = ? and shipment_id in (select id from shipments where tracking_number = ?)",
+		append(args, orgID, trackingNumber)...,
+	)
+	updated, err := rowsAffected(result, err)
+	if err != nil {
+		return nil, err
+	}
+	if updated == 0 {
+		return nil, NotFound
+	}
+
+	return Get(db, orgID, trackingNumber)
+}
+
+// Delete stops tracking the number, and throws away its history if nobody else is tracking it
+func Delete(db *sql.DB, orgID int64, trackingNumber string) error {
+	deleted, err := DeleteMany(db, orgID, &Selection{TrackingNumbers: []string{trackingNumber}})
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return NotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,294 @@
+package webhooks
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/billyb2/tracking_server/outbound"
+)
+
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryFailed    = "failed"
+)
+
+// Deliveries are given up on after this many failed attempts, roughly a day and a half after the first one
+const maxAttempts = 12
+const firstRetryDelay = 30 * time.Second
+const maxRetryDelay = 6 * time.Hour
+
+var DeliveryNotFound error = fmt.Errorf("webhook delivery not found")
+
+var httpClient = outbound.NewClient(10 * time.Second)
+
+type Attempt struct {
+	AttemptedAt time.Time `json:"attempted_at"`
+	StatusCode  *int      `json:"status_code,omitempty"`
+	Error       *string   `json:"error,omitempty"`
+	DurationMS  int64     `json:"duration_ms"`
+}
+
+type Delivery struct {
+	ID             int64           `json:"id"`
+	EndpointID     int64           `json:"webhook_id"`
+	EventID        string          `json:"event_id"`
+	Payload        json.RawMessage `json:"payload" swaggertype:"object"`
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	NextAttemptAt  *time.Time      `json:"next_attempt_at,omitempty"`
+	LastStatusCode *int            `json:"last_status_code,omitempty"`
+	LastError      *string         `json:"last_error,omitempty"`
+	CreatedAt      time.Time       `json:"created_at"`
+	DeliveredAt    *time.Time      `json:"delivered_at,omitempty"`
+	AttemptLog     []Attempt       `json:"attempt_log"`
+}
+
+// Deliveries returns the newest deliveries to one of the user's endpoints in the organization, along with every attempt
+// made for each
+func Deliveries(db *sql.DB, userID int32, orgID int64, endpointID int64, limit int) ([]Delivery, error) {
+	if err := checkOwner(db, userID, orgID, endpointID); err != nil {
+		return nil, err
+	}
+
+	rows, err := db.Query(
+		`select id, endpoint_id, event_id, payload, status, attempts, next_attempt_at, last_status_code, last_error, created_at, delivered_at
+		from webhook_deliveries where endpoint_id = ? order by id desc limit ?`,
+		endpointID, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := []Delivery{}
+	for rows.Next() {
+		delivery := Delivery{}
+		var payload string
+		if err := rows.Scan(
+			&delivery.ID, &delivery.EndpointID, &delivery.EventID, &payload, &delivery.Status, &delivery.Attempts,
+			&delivery.NextAttemptAt, &delivery.LastStatusCode, &delivery.LastError, &delivery.CreatedAt, &delivery.DeliveredAt,
+		); err != nil {
+			return nil, err
+		}
+		delivery.Payload = json.RawMessage(payload)
+		deliveries = append(deliveries, delivery)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	for i := range deliveries {
+		attempts, err := attemptLog(db, deliveries[i].ID)
+		if err != nil {
+			return nil, err
+		}
+		deliveries[i].AttemptLog = attempts
+	}
+
+	return deliveries, nil
+}
+
+func attemptLog(db *sql.DB, deliveryID int64) ([]Attempt, error) {
+	rows, err := db.Query("select attempted_at, status_code, error, duration_ms from webhook_delivery_attempts where delivery_id = ? order by id", deliveryID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	attempts := []Attempt{}
+	for rows.Next() {
+		attempt := Attempt{}
+		if err := rows.Scan(&attempt.AttemptedAt, &attempt.StatusCode, &attempt.Error, &attempt.DurationMS); err != nil {
+			return nil, err
+		}
+		attempts = append(attempts, attempt)
+	}
+
+	return attempts, rows.Err()
+}
+
+// Redeliver queues one of the user's deliveries in the organization to be sent again as soon as possible, no matter its
+// current status
+func Redeliver(db *sql.DB, userID int32, orgID int64, deliveryID int64) error {
+	result, err := db.Exec(
+		`update webhook_deliveries set status = ?, attempts = 0, next_attempt_at = datetime('now')
+		where id = ? and endpoint_id in (select id from webhook_endpoints where org_id = ? and user_id = ?)`,
+		DeliveryPending, deliveryID, orgID, userID,
+	)
+	if err != nil {
+		return err
+	}
+
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if updated == 0 {
+		return DeliveryNotFound
+	}
+
+	return nil
+}
+
+// RunDispatcher sends due webhook deliveries forever
+func RunDispatcher(db *sql.DB) {
+	for {
+		if err := Dispatch(db); err != nil {
+			fmt.Fprintln(os.Stderr, "error dispatching webhooks", err)
+		}
+
+		time.Sleep(5 * time.Second)
+	}
+}
+
+type dueDelivery struct {
+	id       int64
+	eventID  string
+	payload  []byte
+	attempts int
+	url      string
+	secret   string
+}
+
+// Dispatch attempts every pending delivery that is due, scheduling a retry with exponential backoff for the ones that fail
+func Dispatch(db *sql.DB) error {
+	rows, err := db.Query(
+		`select d.id, d.event_id, d.payload, d.attempts, e.url, e.secret
+		from webhook_deliveries d
+		join webhook_endpoints e on e.id = d.endpoint_id
+		where d.status = ? and d.next_attempt_at <= datetime('now')
+		order by d.next_attempt_at
+		limit 100`,
+		DeliveryPending,
+	)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	dueDeliveries := []dueDelivery{}
+	for rows.Next() {
+		delivery := dueDelivery{}
+		var payload string
+		if err := rows.Scan(&delivery.id, &delivery.eventID, &payload, &delivery.attempts, &delivery.url, &delivery.secret); err != nil {
+			return err
+		}
+		delivery.payload = []byte(payload)
+		dueDeliveries = append(dueDeliveries, delivery)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	rows.Close()
+
+	for _, delivery := range dueDeliveries {
+		if err := attempt(db, &delivery); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func attempt(db *sql.DB, delivery *dueDelivery) error {
+	start := time.Now()
+	statusCode, sendErr := send(delivery)
+	duration := time.Since(start)
+
+	var statusCodePtr *int
+	if statusCode != 0 {
+		statusCodePtr = &statusCode
+	}
+	var errStr *string
+	if sendErr != nil {
+		errStr = new(string)
+		*errStr = sendErr.Error()
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(
+		"insert into webhook_delivery_attempts (delivery_id, attempted_at, status_code, error, duration_ms) values (?, datetime('now'), ?, ?, ?)",
+		delivery.id, statusCodePtr, errStr, duration.Milliseconds(),
+	)
+	if err != nil {
+		return err
+	}
+
+	attempts := delivery.attempts + 1
+	switch {
+	case sendErr == nil:
+		_, err = tx.Exec(
+			"update webhook_deliveries set status = ?, attempts = ?, next_attempt_at = null, last_status_code = ?, last_error = null, delivered_at = datetime('now') where id = ?",
+			DeliveryDelivered, attempts, statusCodePtr, delivery.id,
+		)
+	case attempts >= maxAttempts:
+		_, err = tx.Exec(
+			"update webhook_deliveries set status = ?, attempts = ?, next_attempt_at = null, last_status_code = ?, last_error = ? where id = ?",
+			DeliveryFailed, attempts, statusCodePtr, errStr, delivery.id,
+		)
+	default:
+		retryIn := fmt.Sprintf("+%d seconds", int(retryDelay(attempts).Seconds()))
+		_, err = tx.Exec(
+			"update webhook_deliveries set attempts = ?, next_attempt_at = datetime('now', ?), last_status_code = ?, last_error = ? where id = ?",
+			attempts, retryIn, statusCodePtr, errStr, delivery.id,
+		)
+	}
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// retryDelay doubles the wait after every failed attempt, starting at 30 seconds and topping out at 6 hours
+func retryDelay(attempts int) time.Duration {
+	delay := firstRetryDelay
+	for i := 1; i < attempts && delay < maxRetryDelay; i++ {
+		delay *= 2
+	}
+
+	return min(delay, maxRetryDelay)
+}
+
+func send(delivery *dueDelivery) (int, error) {
+	req, err := http.NewRequest(http.MethodPost, delivery.url, bytes.NewReader(delivery.payload))
+	if err != nil {
+		return 0, err
+	}
+
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "tracking-server-webhooks")
+	req.Header.Set("X-Webhook-Id", delivery.eventID)
+	req.Header.Set("X-Webhook-Delivery", strconv.FormatInt(delivery.id, 10))
+	req.Header.Set("X-Webhook-Timestamp", strconv.FormatInt(timestamp, 10))
+	req.Header.Set("X-Webhook-Signature", fmt.Sprintf("t=%d,v1=%s", timestamp, Sign(delivery.secret, timestamp, delivery.payload)))
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, errors.New(resp.Status)
+	}
+
+	return resp.StatusCode, nil
+}
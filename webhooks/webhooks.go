@@ -0,0 +1,256 @@
+package webhooks
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"time"
+
+	"github.com/billyb2/tracking_server/history"
+	"github.com/billyb2/tracking_server/outbound"
+	"github.com/oklog/ulid/v2"
+)
+
+const (
+	EventStatusChanged = "tracking.status_changed"
+	EventDigest        = "digest"
+)
+
+var NotFound error = fmt.Errorf("webhook not found")
+var InvalidURL error = fmt.Errorf("invalid webhook url")
+
+type Event struct {
+	ID        string    `json:"id"`
+	Type      string    `json:"type"`
+	CreatedAt time.Time `json:"created_at"`
+	Data      any       `json:"data"`
+}
+
+type Endpoint struct {
+	ID  int64  `json:"id"`
+	URL string `json:"url"`
+	// Only returned when the endpoint is created
+	Secret string `json:"secret,omitempty"`
+	// Canonical statuses to send events for, empty means every status
+	Statuses []string `json:"statuses,omitempty"`
+	// Groups to send events for, empty means every group
+	GroupNames []string  `json:"group_names,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+func (e *Endpoint) matches(change *history.Change) bool {
+	if len(e.Statuses) > 0 && !slices.Contains(e.Statuses, change.CanonicalStatus) {
+		return false
+	}
+	if len(e.GroupNames) > 0 && !slices.Contains(e.GroupNames, change.GroupName) {
+		return false
+	}
+
+	return true
+}
+
+// Create registers a new webhook endpoint for the user in the organization, generating a signing secret if one isn't given
+func Create(db *sql.DB, userID int32, orgID int64, endpoint *Endpoint) error {
+	u, err := url.Parse(endpoint.URL)
+	if err != nil || !u.IsAbs() || (u.Scheme != "http" && u.Scheme != "https") {
+		return fmt.Errorf("%w: it has to be an absolute http or https url", InvalidURL)
+	}
+	if err := outbound.CheckURL(context.Background(), endpoint.URL); err != nil {
+		return fmt.Errorf("%w: %w", InvalidURL, err)
+	}
+
+	if endpoint.Secret == "" {
+		secret := make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			return err
+		}
+		endpoint.Secret = "whsec_" + hex.EncodeToString(secret)
+	}
+
+	statuses, err := marshalFilter(endpoint.Statuses)
+	if err != nil {
+		return err
+	}
+	groupNames, err := marshalFilter(endpoint.GroupNames)
+	if err != nil {
+		return err
+	}
+
+	row := db.QueryRow(
+		"insert into webhook_endpoints (user_id, org_id, url, secret, statuses, group_names, created_at) values (?, ?, ?, ?, ?, ?, datetime('now')) returning id, created_at",
+		userID, orgID, endpoint.URL, endpoint.Secret, statuses, groupNames,
+	)
+
+	return row.Scan(&endpoint.ID, &endpoint.CreatedAt)
+}
+
+// List returns every webhook endpoint the user has in the organization, without their secrets
+func List(db *sql.DB, userID int32, orgID int64) ([]Endpoint, error) {
+	endpoints, err := endpointsForUser(db, userID, orgID)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range endpoints {
+		endpoints[i].Secret = ""
+	}
+
+	return endpoints, nil
+}
+
+// Delete removes the user's webhook endpoint along with its delivery logs
+func Delete(db *sql.DB, userID int32, orgID int64, endpointID int64) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := checkOwner(tx, userID, orgID, endpointID); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec("delete from webhook_delivery_attempts where delivery_id in (select id from webhook_deliveries where endpoint_id = ?)", endpointID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("delete from webhook_deliveries where endpoint_id = ?", endpointID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("delete from webhook_endpoints where id = ?", endpointID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Enqueue queues a status change event for every one of the user's endpoints in the change's organization whose filter
+// matches it
+func Enqueue(tx *sql.Tx, change *history.Change) error {
+	return enqueue(tx, change.UserID, change.OrgID, EventStatusChanged, change, func(endpoint *Endpoint) bool {
+		return endpoint.matches(change)
+	})
+}
+
+// EnqueueEvent queues an event for every one of the user's endpoints in the organization, ignoring their status and group
+// filters
+func EnqueueEvent(tx *sql.Tx, userID int32, orgID int64, eventType string, data any) error {
+	return enqueue(tx, userID, orgID, eventType, data, func(*Endpoint) bool {
+		return true
+	})
+}
+
+func enqueue(tx *sql.Tx, userID int32, orgID int64, eventType string, data any, filter func(*Endpoint) bool) error {
+	endpoints, err := endpointsForUser(tx, userID, orgID)
+	if err != nil {
+		return err
+	}
+
+	event := Event{
+		ID:        "evt_" + ulid.Make().String(),
+		Type:      eventType,
+		CreatedAt: time.Now().UTC(),
+		Data:      data,
+	}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	for _, endpoint := range endpoints {
+		if !filter(&endpoint) {
+			continue
+		}
+
+		_, err := tx.Exec(
+			"insert into webhook_deliveries (endpoint_id, event_id, payload, status, next_attempt_at, created_at) values (?, ?, ?, ?, datetime('now'), datetime('now'))",
+			endpoint.ID, event.ID, string(payload), DeliveryPending,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Sign returns the hex encoded HMAC-SHA256 of "<timestamp>.<payload>", which is what receivers should compare against
+// the v1 value of the X-Webhook-Signature header
+func Sign(secret string, timestamp int64, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d.", timestamp)
+	mac.Write(payload)
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+type querier interface {
+	Query(query string, args ...any) (*sql.Rows, error)
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+func endpointsForUser(q querier, userID int32, orgID int64) ([]Endpoint, error) {
+	rows, err := q.Query("select id, url, secret, statuses, group_names, created_at from webhook_endpoints where org_id = ? and user_id = ? order by id", orgID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	endpoints := []Endpoint{}
+	for rows.Next() {
+		endpoint := Endpoint{}
+		var statuses, groupNames *string
+		if err := rows.Scan(&endpoint.ID, &endpoint.URL, &endpoint.Secret, &statuses, &groupNames, &endpoint.CreatedAt); err != nil {
+			return nil, err
+		}
+		if err := unmarshalFilter(statuses, &endpoint.Statuses); err != nil {
+			return nil, err
+		}
+		if err := unmarshalFilter(groupNames, &endpoint.GroupNames); err != nil {
+			return nil, err
+		}
+
+		endpoints = append(endpoints, endpoint)
+	}
+
+	return endpoints, rows.Err()
+}
+
+func checkOwner(q querier, userID int32, orgID int64, endpointID int64) error {
+	var id int64
+	err := q.QueryRow("select id from webhook_endpoints where id = ? and org_id = ? and user_id = ?", endpointID, orgID, userID).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return NotFound
+	}
+
+	return err
+}
+
+func marshalFilter(filter []string) (*string, error) {
+	if len(filter) == 0 {
+		return nil, nil
+	}
+
+	filterJSON, err := json.Marshal(filter)
+	if err != nil {
+		return nil, err
+	}
+	filterStr := string(filterJSON)
+
+	return &filterStr, nil
+}
+
+func unmarshalFilter(filterJSON *string, filter *[]string) error {
+	if filterJSON == nil {
+		return nil
+	}
+
+	return json.Unmarshal([]byte(*filterJSON), filter)
+}